@@ -1,18 +1,37 @@
 package api
 
 import (
+	"context"
+	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
 	httpSwagger "github.com/swaggo/http-swagger"
 
 	"github.com/cinema-booker/api/handler"
+	"github.com/cinema-booker/api/middleware"
+	"github.com/cinema-booker/internal/analytics"
 	"github.com/cinema-booker/internal/booking"
 	"github.com/cinema-booker/internal/cinema"
+	"github.com/cinema-booker/internal/constants"
+	"github.com/cinema-booker/internal/email"
 	"github.com/cinema-booker/internal/event"
+	"github.com/cinema-booker/internal/notification"
+	"github.com/cinema-booker/internal/outbox"
 	"github.com/cinema-booker/internal/room"
 	"github.com/cinema-booker/internal/session"
+	"github.com/cinema-booker/internal/staff"
 	"github.com/cinema-booker/internal/user"
+	"github.com/cinema-booker/pkg/jwt"
+	"github.com/cinema-booker/pkg/ticket"
+	"github.com/cinema-booker/third_party/mailer"
+	"github.com/cinema-booker/third_party/payment"
 	"github.com/gorilla/handlers"
 	"github.com/gorilla/mux"
 	"github.com/jmoiron/sqlx"
@@ -39,29 +58,113 @@ func (s *APIServer) Start() error {
 	}).Methods(http.MethodGet)
 
 	sessionStore := session.NewStore(s.db)
-	sessionService := session.NewService(sessionStore)
+
+	var mails mailer.Mailer
+	if os.Getenv("MAILER") == "memory" {
+		mails = mailer.NewMemory()
+	} else {
+		mails = mailer.NewResend(os.Getenv("RESEND_API_KEY"), os.Getenv("RESEND_FROM_EMAIL"))
+	}
+
+	var tracker analytics.Tracker
+	if os.Getenv("ANALYTICS") == "memory" {
+		tracker = analytics.NewMemory()
+	} else {
+		tracker = analytics.NewWebhook(os.Getenv("ANALYTICS_WEBHOOK_URL"))
+	}
+	analyticsService := analytics.NewService(tracker)
+
+	emails, err := email.NewRegistry(constants.DefaultLocale)
+	if err != nil {
+		return fmt.Errorf("invalid email templates: %w", err)
+	}
+
+	keys, err := jwt.LoadKeySet()
+	if err != nil {
+		return fmt.Errorf("invalid JWT keys: %w", err)
+	}
+	keyHandler := handler.NewKeyHandler(keys)
+	keyHandler.RegisterRoutes(router)
+
+	ticketSecret, err := ticket.LoadSecret()
+	if err != nil {
+		return fmt.Errorf("invalid ticket secret: %w", err)
+	}
 
 	userStore := user.NewStore(s.db)
-	userService := user.NewService(userStore)
-	userHandler := handler.NewUserHandler(userService, userStore, sessionService)
-	userHandler.RegisterRoutes(router)
+	userService := user.NewService(userStore, mails, emails, keys)
+	auth := middleware.NewAuth(keys, userStore)
+	emailHandler := handler.NewEmailHandler(emails, auth)
+	emailHandler.RegisterRoutes(router)
 
 	roomStore := room.NewStore(s.db)
 	roomService := room.NewService(roomStore)
 	cinemaStore := cinema.NewStore(s.db)
 	cinemaService := cinema.NewService(cinemaStore)
-	cinemaHandler := handler.NewCinemaHandler(cinemaService, roomService, userStore)
+	cinemaHandler := handler.NewCinemaHandler(cinemaService, roomService, auth)
 	cinemaHandler.RegisterRoutes(router)
+	staffStore := staff.NewStore(s.db)
+	staffService := staff.NewService(staffStore, mails, emails)
+	staffHandler := handler.NewStaffHandler(staffService, cinemaService, auth)
+	staffHandler.RegisterRoutes(router)
 
 	eventStore := event.NewStore(s.db)
 	eventService := event.NewService(eventStore)
-	eventHandler := handler.NewEventHandler(eventService, sessionService, userStore)
-	eventHandler.RegisterRoutes(router)
+
+	holdExpiresIn, err := strconv.Atoi(os.Getenv("BOOKING_HOLD_EXPIRES_IN"))
+	if err != nil {
+		return fmt.Errorf("invalid BOOKING_HOLD_EXPIRES_IN: %w", err)
+	}
+	reaperInterval, err := strconv.Atoi(os.Getenv("BOOKING_HOLD_REAPER_INTERVAL"))
+	if err != nil {
+		return fmt.Errorf("invalid BOOKING_HOLD_REAPER_INTERVAL: %w", err)
+	}
+
+	outboxInterval, err := strconv.Atoi(os.Getenv("OUTBOX_DISPATCH_INTERVAL"))
+	if err != nil {
+		return fmt.Errorf("invalid OUTBOX_DISPATCH_INTERVAL: %w", err)
+	}
+	outboxMaxAttempts, err := strconv.Atoi(os.Getenv("OUTBOX_MAX_ATTEMPTS"))
+	if err != nil {
+		return fmt.Errorf("invalid OUTBOX_MAX_ATTEMPTS: %w", err)
+	}
+
+	var payments payment.Provider
+	if os.Getenv("PAYMENT_PROVIDER") == "fake" {
+		payments = payment.NewFake(os.Getenv("STRIPE_WEBHOOK_SECRET"))
+	} else {
+		payments = payment.NewStripe(payment.StripeConfig{
+			APIKey:        os.Getenv("STRIPE_API_KEY"),
+			WebhookSecret: os.Getenv("STRIPE_WEBHOOK_SECRET"),
+			Currency:      os.Getenv("STRIPE_CURRENCY"),
+			SuccessURL:    os.Getenv("STRIPE_SUCCESS_URL"),
+			CancelURL:     os.Getenv("STRIPE_CANCEL_URL"),
+		})
+	}
+
+	hub := notification.NewHub(notification.DefaultHubConfig())
+	broker := notification.NewBroker(64)
+	tickets := notification.NewTickets(30 * time.Second)
 
 	bookingStore := booking.NewStore(s.db)
-	bookingService := booking.NewService(bookingStore, sessionStore)
-	bookingHandler := handler.NewBookingHandler(bookingService, userStore)
+	bookingService := booking.NewService(bookingStore, sessionStore, cinemaService, payments, mails, emails, broker, booking.Config{
+		HoldDuration: time.Duration(holdExpiresIn) * time.Second,
+		Currency:     os.Getenv("STRIPE_CURRENCY"),
+		TicketSecret: ticketSecret,
+	})
+	bookingHandler := handler.NewBookingHandler(bookingService, auth)
 	bookingHandler.RegisterRoutes(router)
+	orderHandler := handler.NewOrderHandler(bookingService, auth)
+	orderHandler.RegisterRoutes(router)
+	seatHandler := handler.NewSeatHandler(bookingService, broker, tickets, auth)
+	seatHandler.RegisterRoutes(router)
+
+	// Sessions cancel their orders through the booking service when deleted.
+	sessionService := session.NewService(sessionStore, bookingService)
+	userHandler := handler.NewUserHandler(userService, auth, sessionService)
+	userHandler.RegisterRoutes(router)
+	eventHandler := handler.NewEventHandler(eventService, sessionService, cinemaService, auth)
+	eventHandler.RegisterRoutes(router)
 
 	router.PathPrefix("/docs/swagger.json").Handler(http.StripPrefix("/docs", http.FileServer(http.Dir("./docs"))))
 
@@ -70,10 +173,28 @@ func (s *APIServer) Start() error {
 		httpSwagger.URL("http://localhost:3000/docs/swagger.json"),
 	))
 
-	router.HandleFunc("/webhook", handler.HandleWebhook(bookingService)).Methods(http.MethodPost)
-
-	websocketHandler := handler.NewWebSocketHandler()
-	router.HandleFunc("/ws", websocketHandler.HandleWebSocket).Methods(http.MethodGet)
+	outboxDispatcher := outbox.NewDispatcher(outbox.NewStore(s.db), outbox.DispatcherConfig{
+		BatchSize:   50,
+		MaxAttempts: outboxMaxAttempts,
+		Backoff:     5 * time.Second,
+		MaxBackoff:  time.Hour,
+		Lease:       time.Minute,
+	})
+	websocketHandler := handler.NewWebSocketHandler(hub, tickets, auth, strings.Split(os.Getenv("WS_ALLOWED_ORIGINS"), ","))
+	websocketHandler.RegisterRoutes(router)
+	notificationService := notification.NewService(notification.NewStore(s.db), hub, broker)
+	notificationHandler := handler.NewNotificationHandler(notificationService, broker, tickets, auth)
+	notificationHandler.RegisterRoutes(router)
+
+	outboxDispatcher.Handle(constants.OutboxTopicManagerNotification, notificationService.Deliver)
+	outboxDispatcher.Handle(constants.OutboxTopicConfirmationEmail, bookingService.SendConfirmationEmail)
+	outboxDispatcher.Handle(constants.OutboxTopicCancellationEmail, bookingService.SendCancellationEmail)
+	outboxDispatcher.Handle(constants.OutboxTopicSessionCanceledEmail, bookingService.SendSessionCanceledEmail)
+	outboxDispatcher.Handle(constants.OutboxTopicRefund, bookingService.SendRefund)
+	outboxDispatcher.Handle(constants.OutboxTopicAnalytics, analyticsService.Deliver)
+
+	webhookHandler := handler.NewWebhookHandler(bookingService, payments)
+	webhookHandler.RegisterRoutes(router)
 
 	// Configure CORS
 	cors := handlers.CORS(
@@ -82,10 +203,11 @@ func (s *APIServer) Start() error {
 			http.MethodGet,
 			http.MethodPost,
 			http.MethodPut,
+			http.MethodPatch,
 			http.MethodDelete,
 			http.MethodOptions,
 		}),
-		handlers.AllowedHeaders([]string{"Content-Type", "Authorization"}),
+		handlers.AllowedHeaders([]string{"Content-Type", "Authorization", "Last-Event-ID"}),
 	)
 
 	// Add OPTIONS handler for preflight requests
@@ -93,6 +215,34 @@ func (s *APIServer) Start() error {
 		w.WriteHeader(http.StatusOK)
 	})
 
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go bookingService.RunHoldReaper(ctx, time.Duration(reaperInterval)*time.Second)
+	go outboxDispatcher.Run(ctx, time.Duration(outboxInterval)*time.Second)
+
+	server := &http.Server{
+		Addr:    s.address,
+		Handler: cors(router),
+	}
+	// WebSocket connections are hijacked, so Shutdown does not wait for them, and
+	// event streams never become idle, so they have to be ended for Shutdown to return.
+	server.RegisterOnShutdown(hub.Close)
+	server.RegisterOnShutdown(broker.Close)
+
+	shutdown := make(chan error, 1)
+	go func() {
+		<-ctx.Done()
+		log.Printf("🛑 Shutting down the server")
+
+		timeout, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		shutdown <- server.Shutdown(timeout)
+	}()
+
 	log.Printf("🚀 Starting server on %s", s.address)
-	return http.ListenAndServe(s.address, cors(router))
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		return err
+	}
+
+	return <-shutdown
 }
@@ -1,35 +1,41 @@
 package handler
 
 import (
+	goErrors "errors"
+	"fmt"
 	"net/http"
 	"strconv"
 
 	"github.com/cinema-booker/api/middleware"
 	"github.com/cinema-booker/api/utils"
 	"github.com/cinema-booker/internal/booking"
-	"github.com/cinema-booker/internal/user"
 	"github.com/cinema-booker/pkg/errors"
 	"github.com/cinema-booker/pkg/json"
+	"github.com/cinema-booker/pkg/ticket"
 	"github.com/gorilla/mux"
 )
 
 type BookinHandler struct {
-	service   booking.BookingService
-	userStore user.UserStore
+	service booking.BookingService
+	auth    *middleware.Auth
 }
 
-func NewBookingHandler(service booking.BookingService, userStore user.UserStore) *BookinHandler {
+func NewBookingHandler(service booking.BookingService, auth *middleware.Auth) *BookinHandler {
 	return &BookinHandler{
-		service:   service,
-		userStore: userStore,
+		service: service,
+		auth:    auth,
 	}
 }
 
 func (h *BookinHandler) RegisterRoutes(mux *mux.Router) {
-	mux.Handle("/bookings", errors.ErrorHandler(middleware.IsAuth(h.GetAll, h.userStore))).Methods(http.MethodGet)
-	mux.Handle("/bookings/{id}", errors.ErrorHandler(middleware.IsAuth(h.Get, h.userStore))).Methods(http.MethodGet)
-	mux.Handle("/bookings", errors.ErrorHandler(middleware.IsAuth(h.Create, h.userStore))).Methods(http.MethodPost)
-	mux.Handle("/bookings/{id}", errors.ErrorHandler(middleware.IsAuth(h.Cancel, h.userStore))).Methods(http.MethodDelete)
+	mux.Handle("/bookings", errors.ErrorHandler(h.auth.IsAuth(h.GetAll))).Methods(http.MethodGet)
+	mux.Handle("/bookings/{id}", errors.ErrorHandler(h.auth.IsAuth(h.Get))).Methods(http.MethodGet)
+	mux.Handle("/bookings", errors.ErrorHandler(h.auth.IsAuth(h.Create))).Methods(http.MethodPost)
+	mux.Handle("/bookings/{id}", errors.ErrorHandler(h.auth.IsAuth(h.Cancel))).Methods(http.MethodDelete)
+	mux.Handle("/bookings/{id}/ticket.png", errors.ErrorHandler(h.auth.IsAuth(h.TicketPNG))).Methods(http.MethodGet)
+	mux.Handle("/bookings/{id}/ticket.svg", errors.ErrorHandler(h.auth.IsAuth(h.TicketSVG))).Methods(http.MethodGet)
+	mux.Handle("/bookings/{id}/ticket.pdf", errors.ErrorHandler(h.auth.IsAuth(h.TicketPDF))).Methods(http.MethodGet)
+	mux.Handle("/tickets/validate", errors.ErrorHandler(h.auth.IsAuth(h.ValidateTicket))).Methods(http.MethodPost)
 }
 
 func (h *BookinHandler) GetAll(w http.ResponseWriter, r *http.Request) error {
@@ -123,3 +129,121 @@ func (h *BookinHandler) Cancel(w http.ResponseWriter, r *http.Request) error {
 
 	return nil
 }
+
+func (h *BookinHandler) ticket(r *http.Request) (string, error) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		return "", errors.CustomError{
+			Key: errors.BadRequest,
+			Err: err,
+		}
+	}
+
+	return h.service.GetTicket(r.Context(), id)
+}
+
+func (h *BookinHandler) TicketPNG(w http.ResponseWriter, r *http.Request) error {
+	token, err := h.ticket(r)
+	if err != nil {
+		return err
+	}
+
+	image, err := ticket.PNG(token, 512)
+	if err != nil {
+		return errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(image)
+
+	return err
+}
+
+func (h *BookinHandler) TicketSVG(w http.ResponseWriter, r *http.Request) error {
+	token, err := h.ticket(r)
+	if err != nil {
+		return err
+	}
+
+	image, err := ticket.SVG(token)
+	if err != nil {
+		return errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+
+	w.Header().Set("Content-Type", "image/svg+xml")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(image)
+
+	return err
+}
+
+func (h *BookinHandler) TicketPDF(w http.ResponseWriter, r *http.Request) error {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		return errors.CustomError{
+			Key: errors.BadRequest,
+			Err: err,
+		}
+	}
+
+	content, err := h.service.GetTicketPDF(r.Context(), id)
+	if err != nil {
+		return err
+	}
+
+	return writePDF(w, fmt.Sprintf("ticket-%d.pdf", id), content)
+}
+
+func (h *BookinHandler) ValidateTicket(w http.ResponseWriter, r *http.Request) error {
+	var input map[string]interface{}
+	if err := json.Parse(r, &input); err != nil {
+		return errors.CustomError{
+			Key: errors.BadRequest,
+			Err: err,
+		}
+	}
+
+	token, ok := input["token"].(string)
+	if !ok {
+		return errors.CustomError{
+			Key: errors.BadRequest,
+			Err: goErrors.New("token is required"),
+		}
+	}
+
+	booking, err := h.service.ValidateTicket(r.Context(), token)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Write(w, http.StatusOK, booking); err != nil {
+		return errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+
+	return nil
+}
+
+// writePDF sends content as a PDF file download named filename.
+func writePDF(w http.ResponseWriter, filename string, content []byte) error {
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	_, err := w.Write(content)
+
+	return err
+}
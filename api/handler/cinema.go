@@ -7,8 +7,8 @@ import (
 	"github.com/cinema-booker/api/middleware"
 	"github.com/cinema-booker/api/utils"
 	"github.com/cinema-booker/internal/cinema"
+	"github.com/cinema-booker/internal/constants"
 	"github.com/cinema-booker/internal/room"
-	"github.com/cinema-booker/internal/user"
 	"github.com/cinema-booker/pkg/errors"
 	"github.com/cinema-booker/pkg/json"
 	"github.com/gorilla/mux"
@@ -17,27 +17,36 @@ import (
 type CinemaHandler struct {
 	service     cinema.CinemaService
 	roomService room.RoomService
-	userStore   user.UserStore
+	auth        *middleware.Auth
 }
 
-func NewCinemaHandler(service cinema.CinemaService, roomService room.RoomService, userStore user.UserStore) *CinemaHandler {
+func NewCinemaHandler(service cinema.CinemaService, roomService room.RoomService, auth *middleware.Auth) *CinemaHandler {
 	return &CinemaHandler{
 		service:     service,
 		roomService: roomService,
-		userStore:   userStore,
+		auth:        auth,
 	}
 }
 
 func (h *CinemaHandler) RegisterRoutes(mux *mux.Router) {
-	mux.Handle("/cinemas", errors.ErrorHandler(middleware.IsAuth(h.GetAll, h.userStore))).Methods(http.MethodGet)
-	mux.Handle("/cinemas/{id}", errors.ErrorHandler(middleware.IsAuth(h.Get, h.userStore))).Methods(http.MethodGet)
-	mux.Handle("/cinemas", errors.ErrorHandler(middleware.IsAuth(h.Create, h.userStore))).Methods(http.MethodPost)
-	mux.Handle("/cinemas/{id}", errors.ErrorHandler(middleware.IsAuth(h.Update, h.userStore))).Methods(http.MethodPatch)
-	mux.Handle("/cinemas/{id}", errors.ErrorHandler(middleware.IsAuth(h.Delete, h.userStore))).Methods(http.MethodDelete)
-	mux.Handle("/cinemas/{id}/restore", errors.ErrorHandler(middleware.IsAuth(h.Restore, h.userStore))).Methods(http.MethodPatch)
-
-	mux.Handle("/cinemas/{cinemaId}/rooms", errors.ErrorHandler(middleware.IsAuth(h.CreateRoom, h.userStore))).Methods(http.MethodPost)
-	mux.Handle("/cinemas/{cinemaId}/rooms/{roomId}", errors.ErrorHandler(middleware.IsAuth(h.DeleteRoom, h.userStore))).Methods(http.MethodDelete)
+	mux.Handle("/cinemas", errors.ErrorHandler(h.auth.IsAuth(h.GetAll))).Methods(http.MethodGet)
+	mux.Handle("/cinemas/{id}", errors.ErrorHandler(h.auth.IsAuth(h.Get))).Methods(http.MethodGet)
+	mux.Handle("/cinemas", errors.ErrorHandler(h.auth.IsAuth(middleware.RequireRole(h.Create, constants.UserRoleAdmin, constants.UserRoleManager)))).Methods(http.MethodPost)
+	mux.Handle("/cinemas/{id}", errors.ErrorHandler(h.auth.IsAuth(h.can(h.Update, "id", constants.PermissionCinemaManage)))).Methods(http.MethodPatch)
+	mux.Handle("/cinemas/{id}", errors.ErrorHandler(h.auth.IsAuth(h.can(h.Delete, "id", constants.PermissionCinemaManage)))).Methods(http.MethodDelete)
+	mux.Handle("/cinemas/{id}/restore", errors.ErrorHandler(h.auth.IsAuth(h.can(h.Restore, "id", constants.PermissionCinemaManage)))).Methods(http.MethodPatch)
+	mux.Handle("/cinemas/{id}/cancellation-policy", errors.ErrorHandler(h.auth.IsAuth(h.can(h.UpdateCancellationPolicy, "id", constants.PermissionCinemaManage)))).Methods(http.MethodPut)
+
+	mux.Handle("/cinemas/{cinemaId}/rooms", errors.ErrorHandler(h.auth.IsAuth(h.can(h.CreateRoom, "cinemaId", constants.PermissionRoomsManage)))).Methods(http.MethodPost)
+	mux.Handle("/cinemas/{cinemaId}/rooms/{roomId}", errors.ErrorHandler(h.auth.IsAuth(h.GetRoom))).Methods(http.MethodGet)
+	mux.Handle("/cinemas/{cinemaId}/rooms/{roomId}/layout", errors.ErrorHandler(h.auth.IsAuth(h.can(h.UpdateRoomLayout, "cinemaId", constants.PermissionRoomsManage)))).Methods(http.MethodPut)
+	mux.Handle("/cinemas/{cinemaId}/rooms/{roomId}", errors.ErrorHandler(h.auth.IsAuth(h.can(h.DeleteRoom, "cinemaId", constants.PermissionRoomsManage)))).Methods(http.MethodDelete)
+}
+
+// can restricts a route to the users having permission in the cinema in the path
+// variable name.
+func (h *CinemaHandler) can(handlerFunc errors.ErrorHandler, name string, permission string) errors.ErrorHandler {
+	return middleware.HasCinemaPermission(handlerFunc, h.service, middleware.CinemaFromPath(name), permission)
 }
 
 func (h *CinemaHandler) GetAll(w http.ResponseWriter, r *http.Request) error {
@@ -139,6 +148,38 @@ func (h *CinemaHandler) Update(w http.ResponseWriter, r *http.Request) error {
 	return nil
 }
 
+func (h *CinemaHandler) UpdateCancellationPolicy(w http.ResponseWriter, r *http.Request) error {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		return errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+
+	var input map[string]interface{}
+	if err := json.Parse(r, &input); err != nil {
+		return errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+
+	if err := h.service.UpdateCancellationPolicy(r.Context(), id, input); err != nil {
+		return err
+	}
+
+	if err := json.Write(w, http.StatusAccepted, nil); err != nil {
+		return errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+
+	return nil
+}
+
 func (h *CinemaHandler) Delete(w http.ResponseWriter, r *http.Request) error {
 	vars := mux.Vars(r)
 	id, err := strconv.Atoi(vars["id"])
@@ -187,6 +228,38 @@ func (h *CinemaHandler) Restore(w http.ResponseWriter, r *http.Request) error {
 	return nil
 }
 
+func (h *CinemaHandler) GetRoom(w http.ResponseWriter, r *http.Request) error {
+	vars := mux.Vars(r)
+	cinemaId, err := strconv.Atoi(vars["cinemaId"])
+	if err != nil {
+		return errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+	roomId, err := strconv.Atoi(vars["roomId"])
+	if err != nil {
+		return errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+
+	room, err := h.roomService.Get(r.Context(), cinemaId, roomId)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Write(w, http.StatusOK, room); err != nil {
+		return errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+
+	return nil
+}
+
 func (h *CinemaHandler) CreateRoom(w http.ResponseWriter, r *http.Request) error {
 	vars := mux.Vars(r)
 	cinemaId, err := strconv.Atoi(vars["cinemaId"])
@@ -219,6 +292,45 @@ func (h *CinemaHandler) CreateRoom(w http.ResponseWriter, r *http.Request) error
 	return nil
 }
 
+func (h *CinemaHandler) UpdateRoomLayout(w http.ResponseWriter, r *http.Request) error {
+	vars := mux.Vars(r)
+	cinemaId, err := strconv.Atoi(vars["cinemaId"])
+	if err != nil {
+		return errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+	roomId, err := strconv.Atoi(vars["roomId"])
+	if err != nil {
+		return errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+
+	var input map[string]interface{}
+	if err := json.Parse(r, &input); err != nil {
+		return errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+
+	if err := h.roomService.UpdateLayout(r.Context(), cinemaId, roomId, input); err != nil {
+		return err
+	}
+
+	if err := json.Write(w, http.StatusAccepted, nil); err != nil {
+		return errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+
+	return nil
+}
+
 func (h *CinemaHandler) DeleteRoom(w http.ResponseWriter, r *http.Request) error {
 	vars := mux.Vars(r)
 	cinemaId, err := strconv.Atoi(vars["cinemaId"])
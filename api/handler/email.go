@@ -0,0 +1,84 @@
+package handler
+
+import (
+	goErrors "errors"
+	"net/http"
+
+	"github.com/cinema-booker/api/middleware"
+	"github.com/cinema-booker/internal/constants"
+	"github.com/cinema-booker/internal/email"
+	"github.com/cinema-booker/pkg/errors"
+	"github.com/cinema-booker/pkg/json"
+	"github.com/gorilla/mux"
+)
+
+type EmailHandler struct {
+	registry *email.Registry
+	auth     *middleware.Auth
+}
+
+func NewEmailHandler(registry *email.Registry, auth *middleware.Auth) *EmailHandler {
+	return &EmailHandler{
+		registry: registry,
+		auth:     auth,
+	}
+}
+
+func (h *EmailHandler) RegisterRoutes(mux *mux.Router) {
+	mux.Handle("/admin/emails", errors.ErrorHandler(h.auth.IsAuth(middleware.RequireRole(h.GetAll, constants.UserRoleAdmin)))).Methods(http.MethodGet)
+	mux.Handle("/admin/emails/{name}/preview", errors.ErrorHandler(h.auth.IsAuth(middleware.RequireRole(h.Preview, constants.UserRoleAdmin)))).Methods(http.MethodGet)
+}
+
+func (h *EmailHandler) GetAll(w http.ResponseWriter, r *http.Request) error {
+	if err := json.Write(w, http.StatusOK, map[string]interface{}{
+		"templates": h.registry.Names(),
+		"locales":   h.registry.Locales(),
+	}); err != nil {
+		return errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+
+	return nil
+}
+
+// Preview renders a template with sample data, as HTML by default or as the plain
+// text part with ?format=text.
+func (h *EmailHandler) Preview(w http.ResponseWriter, r *http.Request) error {
+	name := mux.Vars(r)["name"]
+	data, ok := email.Samples()[name]
+	if !ok {
+		return errors.CustomError{
+			Key: errors.NotFound,
+			Err: goErrors.New("unknown email template"),
+		}
+	}
+
+	locale := r.URL.Query().Get("locale")
+	if locale == "" {
+		locale = constants.DefaultLocale
+	}
+
+	message, err := h.registry.Render(name, locale, data)
+	if err != nil {
+		return errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+
+	w.Header().Set("X-Email-Subject", message.Subject)
+	if r.URL.Query().Get("format") == "text" {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		_, err = w.Write([]byte(message.Text))
+		return err
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write([]byte(message.HTML))
+
+	return err
+}
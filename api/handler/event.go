@@ -1,14 +1,16 @@
 package handler
 
 import (
+	goErrors "errors"
 	"net/http"
 	"strconv"
 
 	"github.com/cinema-booker/api/middleware"
 	"github.com/cinema-booker/api/utils"
+	"github.com/cinema-booker/internal/cinema"
+	"github.com/cinema-booker/internal/constants"
 	"github.com/cinema-booker/internal/event"
 	"github.com/cinema-booker/internal/session"
-	"github.com/cinema-booker/internal/user"
 	"github.com/cinema-booker/pkg/errors"
 	"github.com/cinema-booker/pkg/json"
 	"github.com/gorilla/mux"
@@ -17,27 +19,62 @@ import (
 type EventHandler struct {
 	service        event.EventService
 	sessionService session.SessionService
-	userStore      user.UserStore
+	cinemaService  cinema.CinemaService
+	auth           *middleware.Auth
 }
 
-func NewEventHandler(service event.EventService, sessionService session.SessionService, userStore user.UserStore) *EventHandler {
+func NewEventHandler(service event.EventService, sessionService session.SessionService, cinemaService cinema.CinemaService, auth *middleware.Auth) *EventHandler {
 	return &EventHandler{
 		service:        service,
 		sessionService: sessionService,
-		userStore:      userStore,
+		cinemaService:  cinemaService,
+		auth:           auth,
 	}
 }
 
 func (h *EventHandler) RegisterRoutes(mux *mux.Router) {
-	mux.Handle("/events", errors.ErrorHandler(middleware.IsAuth(h.GetAll, h.userStore))).Methods(http.MethodGet)
-	mux.Handle("/events/{id}", errors.ErrorHandler(middleware.IsAuth(h.Get, h.userStore))).Methods(http.MethodGet)
-	mux.Handle("/events", errors.ErrorHandler(middleware.IsAuth(h.Create, h.userStore))).Methods(http.MethodPost)
-	mux.Handle("/events/{id}", errors.ErrorHandler(middleware.IsAuth(h.Update, h.userStore))).Methods(http.MethodPatch)
-	mux.Handle("/events/{id}", errors.ErrorHandler(middleware.IsAuth(h.Delete, h.userStore))).Methods(http.MethodDelete)
-	mux.Handle("/events/{id}/restore", errors.ErrorHandler(middleware.IsAuth(h.Restore, h.userStore))).Methods(http.MethodPatch)
-
-	mux.Handle("/events/{eventId}/sessions", errors.ErrorHandler(middleware.IsAuth(h.CreateSession, h.userStore))).Methods(http.MethodPost)
-	mux.Handle("/events/{eventId}/sessions/{sessionId}", errors.ErrorHandler(middleware.IsAuth(h.DeleteSession, h.userStore))).Methods(http.MethodDelete)
+	mux.Handle("/events", errors.ErrorHandler(h.auth.IsAuth(h.GetAll))).Methods(http.MethodGet)
+	mux.Handle("/events/{id}", errors.ErrorHandler(h.auth.IsAuth(h.Get))).Methods(http.MethodGet)
+	mux.Handle("/events", errors.ErrorHandler(h.auth.IsAuth(h.Create))).Methods(http.MethodPost)
+	mux.Handle("/events/{id}", errors.ErrorHandler(h.auth.IsAuth(h.canManage(h.Update, "id")))).Methods(http.MethodPatch)
+	mux.Handle("/events/{id}", errors.ErrorHandler(h.auth.IsAuth(h.canManage(h.Delete, "id")))).Methods(http.MethodDelete)
+	mux.Handle("/events/{id}/restore", errors.ErrorHandler(h.auth.IsAuth(h.canManage(h.Restore, "id")))).Methods(http.MethodPatch)
+
+	mux.Handle("/events/{eventId}/sessions", errors.ErrorHandler(h.auth.IsAuth(h.canManage(h.CreateSession, "eventId")))).Methods(http.MethodPost)
+	mux.Handle("/events/{eventId}/sessions/{sessionId}", errors.ErrorHandler(h.auth.IsAuth(h.canManage(h.DeleteSession, "eventId")))).Methods(http.MethodDelete)
+}
+
+// canManage restricts a route to the users allowed to manage the events of the cinema
+// showing the event in the path variable name.
+func (h *EventHandler) canManage(handlerFunc errors.ErrorHandler, name string) errors.ErrorHandler {
+	return middleware.HasCinemaPermission(handlerFunc, h.cinemaService, func(r *http.Request) (int, error) {
+		id, err := strconv.Atoi(mux.Vars(r)[name])
+		if err != nil {
+			return 0, errors.CustomError{
+				Key: errors.BadRequest,
+				Err: err,
+			}
+		}
+		return h.service.GetCinemaId(r.Context(), id)
+	}, constants.PermissionEventsManage)
+}
+
+// authorizeCinema checks that the user may manage the events of the cinema_id of
+// input, if any.
+func (h *EventHandler) authorizeCinema(r *http.Request, input map[string]interface{}) error {
+	value, ok := input["cinema_id"]
+	if !ok {
+		return nil
+	}
+	cinemaId, ok := value.(float64)
+	if !ok {
+		return errors.CustomError{
+			Key: errors.BadRequest,
+			Err: goErrors.New("invalid cinema_id"),
+		}
+	}
+
+	return h.cinemaService.Authorize(r.Context(), int(cinemaId), constants.PermissionEventsManage)
 }
 
 func (h *EventHandler) GetAll(w http.ResponseWriter, r *http.Request) error {
@@ -93,6 +130,16 @@ func (h *EventHandler) Create(w http.ResponseWriter, r *http.Request) error {
 		}
 	}
 
+	if _, ok := input["cinema_id"]; !ok {
+		return errors.CustomError{
+			Key: errors.BadRequest,
+			Err: goErrors.New("cinema_id is required"),
+		}
+	}
+	if err := h.authorizeCinema(r, input); err != nil {
+		return err
+	}
+
 	if err := h.service.Create(r.Context(), input); err != nil {
 		return err
 	}
@@ -125,6 +172,10 @@ func (h *EventHandler) Update(w http.ResponseWriter, r *http.Request) error {
 		}
 	}
 
+	if err := h.authorizeCinema(r, input); err != nil {
+		return err
+	}
+
 	if err := h.service.Update(r.Context(), id, input); err != nil {
 		return err
 	}
@@ -240,7 +291,7 @@ func (h *EventHandler) DeleteSession(w http.ResponseWriter, r *http.Request) err
 		return err
 	}
 
-	if err := json.Write(w, http.StatusCreated, nil); err != nil {
+	if err := json.Write(w, http.StatusNoContent, nil); err != nil {
 		return errors.CustomError{
 			Key: errors.InternalServerError,
 			Err: err,
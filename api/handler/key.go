@@ -0,0 +1,38 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/cinema-booker/pkg/errors"
+	"github.com/cinema-booker/pkg/json"
+	"github.com/cinema-booker/pkg/jwt"
+	"github.com/gorilla/mux"
+)
+
+type KeyHandler struct {
+	keys *jwt.KeySet
+}
+
+func NewKeyHandler(keys *jwt.KeySet) *KeyHandler {
+	return &KeyHandler{
+		keys: keys,
+	}
+}
+
+func (h *KeyHandler) RegisterRoutes(mux *mux.Router) {
+	mux.Handle("/.well-known/jwks.json", errors.ErrorHandler(h.GetJWKS)).Methods(http.MethodGet)
+}
+
+// GetJWKS publishes the public keys access tokens are signed with, retired ones
+// included, for other services to verify the tokens by their "kid" header.
+func (h *KeyHandler) GetJWKS(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	if err := json.Write(w, http.StatusOK, h.keys.JWKS()); err != nil {
+		return errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,244 @@
+package handler
+
+import (
+	goJson "encoding/json"
+	goErrors "errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cinema-booker/api/middleware"
+	"github.com/cinema-booker/api/utils"
+	"github.com/cinema-booker/internal/constants"
+	"github.com/cinema-booker/internal/notification"
+	"github.com/cinema-booker/pkg/errors"
+	"github.com/cinema-booker/pkg/json"
+	"github.com/gorilla/mux"
+)
+
+// notificationReplayPage is how many missed notifications are read from the log at once
+// when a stream resumes.
+const notificationReplayPage = 100
+
+type NotificationHandler struct {
+	service notification.NotificationService
+	broker  *notification.Broker
+	tickets *notification.Tickets
+	auth    *middleware.Auth
+}
+
+func NewNotificationHandler(service notification.NotificationService, broker *notification.Broker, tickets *notification.Tickets, auth *middleware.Auth) *NotificationHandler {
+	return &NotificationHandler{
+		service: service,
+		broker:  broker,
+		tickets: tickets,
+		auth:    auth,
+	}
+}
+
+func (h *NotificationHandler) RegisterRoutes(mux *mux.Router) {
+	mux.Handle("/notifications", errors.ErrorHandler(h.auth.IsAuth(h.GetAll))).Methods(http.MethodGet)
+	mux.Handle("/notifications/unread-count", errors.ErrorHandler(h.auth.IsAuth(h.CountUnread))).Methods(http.MethodGet)
+	mux.Handle("/notifications/read", errors.ErrorHandler(h.auth.IsAuth(h.MarkAllRead))).Methods(http.MethodPatch)
+	mux.Handle("/notifications/{id}/read", errors.ErrorHandler(h.auth.IsAuth(h.MarkRead))).Methods(http.MethodPatch)
+	mux.Handle("/notifications/stream", errors.ErrorHandler(h.auth.IsStreamAuth(h.Stream, h.tickets))).Methods(http.MethodGet)
+}
+
+// GetAll returns the inbox of the authenticated user, newest first, with only the
+// unread notifications when ?unread=true and only those of a type when ?type= is set.
+func (h *NotificationHandler) GetAll(w http.ResponseWriter, r *http.Request) error {
+	pagination := utils.GetPaginationQueryParams(r)
+	unreadOnly := r.URL.Query().Get("unread") == "true"
+	notificationType := r.URL.Query().Get("type")
+
+	notifications, err := h.service.GetAll(r.Context(), pagination, unreadOnly, notificationType)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Write(w, http.StatusOK, notifications); err != nil {
+		return errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+
+	return nil
+}
+
+func (h *NotificationHandler) CountUnread(w http.ResponseWriter, r *http.Request) error {
+	count, err := h.service.CountUnread(r.Context())
+	if err != nil {
+		return err
+	}
+
+	if err := json.Write(w, http.StatusOK, map[string]int{"count": count}); err != nil {
+		return errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+
+	return nil
+}
+
+func (h *NotificationHandler) MarkRead(w http.ResponseWriter, r *http.Request) error {
+	vars := mux.Vars(r)
+	id, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		return errors.CustomError{
+			Key: errors.BadRequest,
+			Err: err,
+		}
+	}
+
+	notification, err := h.service.MarkRead(r.Context(), id)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Write(w, http.StatusOK, notification); err != nil {
+		return errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+
+	return nil
+}
+
+func (h *NotificationHandler) MarkAllRead(w http.ResponseWriter, r *http.Request) error {
+	count, err := h.service.MarkAllRead(r.Context())
+	if err != nil {
+		return err
+	}
+
+	if err := json.Write(w, http.StatusOK, map[string]int64{"updated": count}); err != nil {
+		return errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+
+	return nil
+}
+
+// lastEventId reads where a resumed stream left off. EventSource sends the Last-Event-ID
+// header when it reconnects by itself; clients reconnecting with a new ticket pass it as
+// ?last_event_id= instead.
+func lastEventId(r *http.Request) (int64, bool, error) {
+	value := r.Header.Get("Last-Event-ID")
+	if value == "" {
+		value = r.URL.Query().Get("last_event_id")
+	}
+	if value == "" {
+		return 0, false, nil
+	}
+
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || id < 0 {
+		return 0, false, errors.CustomError{
+			Key: errors.BadRequest,
+			Err: fmt.Errorf("invalid last event id %q", value),
+		}
+	}
+
+	return id, true, nil
+}
+
+func sendEvent(stream *eventStream, event notification.Event) error {
+	data, err := goJson.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	return stream.Send(strconv.FormatInt(event.Id, 10), event.Type, data)
+}
+
+// Stream pushes the notifications of the authenticated user as Server-Sent Events, for
+// clients which cannot keep a WebSocket open. Each event carries the id of the
+// notification, so a resumed stream first replays what was logged since that id.
+func (h *NotificationHandler) Stream(w http.ResponseWriter, r *http.Request) error {
+	userId, ok := r.Context().Value(constants.UserIDKey).(int)
+	if !ok {
+		return errors.CustomError{
+			Key: errors.Unauthorized,
+			Err: goErrors.New("user id not authenticated"),
+		}
+	}
+
+	lastId, resume, err := lastEventId(r)
+	if err != nil {
+		return err
+	}
+
+	// subscribe before replaying the log so that no notification falls in between
+	subscription := h.broker.Subscribe(notification.UserTopic(userId))
+	defer subscription.Close()
+
+	missed := []notification.Notification{}
+	if resume {
+		missed, err = h.service.GetAfter(r.Context(), lastId, notificationReplayPage)
+		if err != nil {
+			return err
+		}
+	}
+
+	stream, err := openEventStream(w)
+	if err != nil {
+		return errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+
+	for len(missed) > 0 {
+		for _, n := range missed {
+			if err := sendEvent(stream, n.Event); err != nil {
+				return nil
+			}
+			lastId = n.Id
+		}
+		if len(missed) < notificationReplayPage {
+			break
+		}
+		missed, err = h.service.GetAfter(r.Context(), lastId, notificationReplayPage)
+		if err != nil {
+			log.Printf("❌ Error replaying notifications of user %d: %v", userId, err)
+			return nil
+		}
+	}
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return nil
+		case message, ok := <-subscription.C:
+			if !ok {
+				return nil
+			}
+			var event notification.Event
+			if err := goJson.Unmarshal(message, &event); err != nil {
+				log.Printf("❌ Error decoding notification for user %d: %v", userId, err)
+				continue
+			}
+			// already replayed from the inbox
+			if event.Id <= lastId {
+				continue
+			}
+			if err := sendEvent(stream, event); err != nil {
+				return nil
+			}
+			lastId = event.Id
+		case <-heartbeat.C:
+			if err := stream.Ping(); err != nil {
+				return nil
+			}
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/cinema-booker/api/middleware"
+	"github.com/cinema-booker/api/utils"
+	"github.com/cinema-booker/internal/booking"
+	"github.com/cinema-booker/pkg/errors"
+	"github.com/cinema-booker/pkg/json"
+	"github.com/gorilla/mux"
+)
+
+type OrderHandler struct {
+	service booking.BookingService
+	auth    *middleware.Auth
+}
+
+func NewOrderHandler(service booking.BookingService, auth *middleware.Auth) *OrderHandler {
+	return &OrderHandler{
+		service: service,
+		auth:    auth,
+	}
+}
+
+func (h *OrderHandler) RegisterRoutes(mux *mux.Router) {
+	mux.Handle("/orders", errors.ErrorHandler(h.auth.IsAuth(h.GetAll))).Methods(http.MethodGet)
+	mux.Handle("/orders/{id}", errors.ErrorHandler(h.auth.IsAuth(h.Get))).Methods(http.MethodGet)
+	mux.Handle("/orders/{id}", errors.ErrorHandler(h.auth.IsAuth(h.Cancel))).Methods(http.MethodDelete)
+	mux.Handle("/orders/{id}/tickets.pdf", errors.ErrorHandler(h.auth.IsAuth(h.TicketsPDF))).Methods(http.MethodGet)
+}
+
+func (h *OrderHandler) GetAll(w http.ResponseWriter, r *http.Request) error {
+	pagination := utils.GetPaginationQueryParams(r)
+
+	orders, err := h.service.GetAllOrders(r.Context(), pagination)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Write(w, http.StatusOK, orders); err != nil {
+		return errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+
+	return nil
+}
+
+func (h *OrderHandler) Get(w http.ResponseWriter, r *http.Request) error {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		return errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+
+	order, err := h.service.GetOrder(r.Context(), id)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Write(w, http.StatusOK, order); err != nil {
+		return errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+
+	return nil
+}
+
+func (h *OrderHandler) Cancel(w http.ResponseWriter, r *http.Request) error {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		return errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+
+	if err := h.service.CancelOrder(r.Context(), id); err != nil {
+		return err
+	}
+
+	if err := json.Write(w, http.StatusNoContent, nil); err != nil {
+		return errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+
+	return nil
+}
+
+func (h *OrderHandler) TicketsPDF(w http.ResponseWriter, r *http.Request) error {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		return errors.CustomError{
+			Key: errors.BadRequest,
+			Err: err,
+		}
+	}
+
+	content, err := h.service.GetOrderTicketsPDF(r.Context(), id)
+	if err != nil {
+		return err
+	}
+
+	return writePDF(w, fmt.Sprintf("order-%d-tickets.pdf", id), content)
+}
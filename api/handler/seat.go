@@ -0,0 +1,137 @@
+package handler
+
+import (
+	goJson "encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cinema-booker/api/middleware"
+	"github.com/cinema-booker/internal/booking"
+	"github.com/cinema-booker/internal/constants"
+	"github.com/cinema-booker/internal/notification"
+	"github.com/cinema-booker/pkg/errors"
+	"github.com/cinema-booker/pkg/json"
+	"github.com/gorilla/mux"
+)
+
+type SeatHandler struct {
+	service booking.BookingService
+	broker  *notification.Broker
+	tickets *notification.Tickets
+	auth    *middleware.Auth
+}
+
+func NewSeatHandler(service booking.BookingService, broker *notification.Broker, tickets *notification.Tickets, auth *middleware.Auth) *SeatHandler {
+	return &SeatHandler{
+		service: service,
+		broker:  broker,
+		tickets: tickets,
+		auth:    auth,
+	}
+}
+
+func (h *SeatHandler) RegisterRoutes(mux *mux.Router) {
+	mux.Handle("/sessions/{id}/seats", errors.ErrorHandler(h.auth.IsAuth(h.Get))).Methods(http.MethodGet)
+	mux.Handle("/sessions/{id}/seats/stream", errors.ErrorHandler(h.auth.IsStreamAuth(h.Stream, h.tickets))).Methods(http.MethodGet)
+}
+
+func (h *SeatHandler) Get(w http.ResponseWriter, r *http.Request) error {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		return errors.CustomError{
+			Key: errors.BadRequest,
+			Err: err,
+		}
+	}
+
+	seats, err := h.service.GetSeats(r.Context(), id)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Write(w, http.StatusOK, seats); err != nil {
+		return errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+
+	return nil
+}
+
+// Stream pushes the seat events of a session as Server-Sent Events, starting with a
+// snapshot of the held and booked seats. Clients subscribe again, getting a new
+// snapshot, whenever the stream ends.
+func (h *SeatHandler) Stream(w http.ResponseWriter, r *http.Request) error {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		return errors.CustomError{
+			Key: errors.BadRequest,
+			Err: err,
+		}
+	}
+
+	// subscribe before reading the snapshot so that no change falls in between
+	subscription := h.broker.Subscribe(booking.SeatsTopic(id))
+	defer subscription.Close()
+
+	seats, err := h.service.GetSeats(r.Context(), id)
+	if err != nil {
+		return err
+	}
+	// the snapshot comes first, so that the seat map is up to date without racing the
+	// events that follow it
+	event, err := notification.NewEvent(constants.SeatEventSnapshot, seats)
+	if err != nil {
+		return errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+	event.SessionId = id
+	snapshot, err := goJson.Marshal(event)
+	if err != nil {
+		return errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+
+	stream, err := openEventStream(w)
+	if err != nil {
+		return errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+	if err := stream.Send("", "", snapshot); err != nil {
+		return nil
+	}
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return nil
+		case message, ok := <-subscription.C:
+			if !ok {
+				// dropped for falling behind, or the server is shutting down
+				return nil
+			}
+			if err := stream.Send("", "", message); err != nil {
+				log.Printf("❌ Error writing seat event for session %d: %v", id, err)
+				return nil
+			}
+		case <-heartbeat.C:
+			if err := stream.Ping(); err != nil {
+				return nil
+			}
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// streamHeartbeat is how often idle event streams send a comment, so that proxies
+// do not close them and dead clients are noticed.
+const streamHeartbeat = 15 * time.Second
+
+// eventStream writes Server-Sent Events to a response.
+type eventStream struct {
+	w          http.ResponseWriter
+	controller *http.ResponseController
+}
+
+func openEventStream(w http.ResponseWriter) (*eventStream, error) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	// stop nginx from buffering the stream
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	stream := &eventStream{
+		w:          w,
+		controller: http.NewResponseController(w),
+	}
+
+	return stream, stream.controller.Flush()
+}
+
+// Send writes an event with an optional id and name. data must be a single line,
+// as JSON encoded by encoding/json is.
+func (s *eventStream) Send(id string, event string, data []byte) error {
+	if id != "" {
+		if _, err := fmt.Fprintf(s.w, "id: %s\n", id); err != nil {
+			return err
+		}
+	}
+	if event != "" {
+		if _, err := fmt.Fprintf(s.w, "event: %s\n", event); err != nil {
+			return err
+		}
+	}
+	if _, err := fmt.Fprintf(s.w, "data: %s\n\n", data); err != nil {
+		return err
+	}
+
+	return s.controller.Flush()
+}
+
+func (s *eventStream) Ping() error {
+	if _, err := fmt.Fprint(s.w, ": ping\n\n"); err != nil {
+		return err
+	}
+
+	return s.controller.Flush()
+}
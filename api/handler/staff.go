@@ -0,0 +1,398 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/cinema-booker/api/middleware"
+	"github.com/cinema-booker/internal/cinema"
+	"github.com/cinema-booker/internal/constants"
+	"github.com/cinema-booker/internal/staff"
+	"github.com/cinema-booker/pkg/errors"
+	"github.com/cinema-booker/pkg/json"
+	"github.com/gorilla/mux"
+)
+
+type StaffHandler struct {
+	service       staff.StaffService
+	cinemaService cinema.CinemaService
+	auth          *middleware.Auth
+}
+
+func NewStaffHandler(service staff.StaffService, cinemaService cinema.CinemaService, auth *middleware.Auth) *StaffHandler {
+	return &StaffHandler{
+		service:       service,
+		cinemaService: cinemaService,
+		auth:          auth,
+	}
+}
+
+func (h *StaffHandler) RegisterRoutes(mux *mux.Router) {
+	mux.Handle("/permissions", errors.ErrorHandler(h.auth.IsAuth(middleware.RequireRole(h.GetPermissions, constants.UserRoleAdmin)))).Methods(http.MethodGet)
+	mux.Handle("/roles", errors.ErrorHandler(h.auth.IsAuth(middleware.RequireRole(h.GetRoles, constants.UserRoleAdmin)))).Methods(http.MethodGet)
+	mux.Handle("/roles", errors.ErrorHandler(h.auth.IsAuth(middleware.RequireRole(h.CreateRole, constants.UserRoleAdmin)))).Methods(http.MethodPost)
+	mux.Handle("/roles/{id}", errors.ErrorHandler(h.auth.IsAuth(middleware.RequireRole(h.UpdateRole, constants.UserRoleAdmin)))).Methods(http.MethodPatch)
+	mux.Handle("/roles/{id}", errors.ErrorHandler(h.auth.IsAuth(middleware.RequireRole(h.DeleteRole, constants.UserRoleAdmin)))).Methods(http.MethodDelete)
+
+	mux.Handle("/cinemas/{cinemaId}/roles", errors.ErrorHandler(h.auth.IsAuth(h.canManage(h.GetCinemaRoles)))).Methods(http.MethodGet)
+	mux.Handle("/cinemas/{cinemaId}/staff", errors.ErrorHandler(h.auth.IsAuth(h.canManage(h.GetMembers)))).Methods(http.MethodGet)
+	mux.Handle("/cinemas/{cinemaId}/staff/{userId}", errors.ErrorHandler(h.auth.IsAuth(h.canManage(h.UpdateMember)))).Methods(http.MethodPut)
+	mux.Handle("/cinemas/{cinemaId}/staff/{userId}", errors.ErrorHandler(h.auth.IsAuth(h.canManage(h.RemoveMember)))).Methods(http.MethodDelete)
+	mux.Handle("/cinemas/{cinemaId}/invitations", errors.ErrorHandler(h.auth.IsAuth(h.canManage(h.GetInvitations)))).Methods(http.MethodGet)
+	mux.Handle("/cinemas/{cinemaId}/invitations", errors.ErrorHandler(h.auth.IsAuth(h.canManage(h.Invite)))).Methods(http.MethodPost)
+	mux.Handle("/cinemas/{cinemaId}/invitations/{id}", errors.ErrorHandler(h.auth.IsAuth(h.canManage(h.RevokeInvitation)))).Methods(http.MethodDelete)
+	mux.Handle("/invitations/accept", errors.ErrorHandler(h.auth.IsAuth(h.AcceptInvitation))).Methods(http.MethodPost)
+}
+
+// canManage restricts a route to the users allowed to manage the staff of the cinema
+// in the path.
+func (h *StaffHandler) canManage(handlerFunc errors.ErrorHandler) errors.ErrorHandler {
+	return middleware.HasCinemaPermission(handlerFunc, h.cinemaService, middleware.CinemaFromPath("cinemaId"), constants.PermissionStaffManage)
+}
+
+func (h *StaffHandler) GetPermissions(w http.ResponseWriter, r *http.Request) error {
+	if err := json.Write(w, http.StatusOK, constants.Permissions); err != nil {
+		return errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+
+	return nil
+}
+
+// GetRoles returns every role, or those which can be assigned in the cinema ?cinema_id=.
+func (h *StaffHandler) GetRoles(w http.ResponseWriter, r *http.Request) error {
+	cinemaId := 0
+	if value := r.URL.Query().Get("cinema_id"); value != "" {
+		id, err := strconv.Atoi(value)
+		if err != nil {
+			return errors.CustomError{
+				Key: errors.BadRequest,
+				Err: err,
+			}
+		}
+		cinemaId = id
+	}
+
+	roles, err := h.service.GetRoles(r.Context(), cinemaId)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Write(w, http.StatusOK, roles); err != nil {
+		return errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+
+	return nil
+}
+
+func (h *StaffHandler) CreateRole(w http.ResponseWriter, r *http.Request) error {
+	var input map[string]interface{}
+	if err := json.Parse(r, &input); err != nil {
+		return errors.CustomError{
+			Key: errors.BadRequest,
+			Err: err,
+		}
+	}
+
+	role, err := h.service.CreateRole(r.Context(), input)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Write(w, http.StatusCreated, role); err != nil {
+		return errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+
+	return nil
+}
+
+func (h *StaffHandler) UpdateRole(w http.ResponseWriter, r *http.Request) error {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		return errors.CustomError{
+			Key: errors.BadRequest,
+			Err: err,
+		}
+	}
+
+	var input map[string]interface{}
+	if err := json.Parse(r, &input); err != nil {
+		return errors.CustomError{
+			Key: errors.BadRequest,
+			Err: err,
+		}
+	}
+
+	if err := h.service.UpdateRole(r.Context(), id, input); err != nil {
+		return err
+	}
+
+	if err := json.Write(w, http.StatusAccepted, nil); err != nil {
+		return errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+
+	return nil
+}
+
+func (h *StaffHandler) DeleteRole(w http.ResponseWriter, r *http.Request) error {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		return errors.CustomError{
+			Key: errors.BadRequest,
+			Err: err,
+		}
+	}
+
+	if err := h.service.DeleteRole(r.Context(), id); err != nil {
+		return err
+	}
+
+	if err := json.Write(w, http.StatusNoContent, nil); err != nil {
+		return errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+
+	return nil
+}
+
+func (h *StaffHandler) GetCinemaRoles(w http.ResponseWriter, r *http.Request) error {
+	cinemaId, err := strconv.Atoi(mux.Vars(r)["cinemaId"])
+	if err != nil {
+		return errors.CustomError{
+			Key: errors.BadRequest,
+			Err: err,
+		}
+	}
+
+	roles, err := h.service.GetRoles(r.Context(), cinemaId)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Write(w, http.StatusOK, roles); err != nil {
+		return errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+
+	return nil
+}
+
+func (h *StaffHandler) GetMembers(w http.ResponseWriter, r *http.Request) error {
+	cinemaId, err := strconv.Atoi(mux.Vars(r)["cinemaId"])
+	if err != nil {
+		return errors.CustomError{
+			Key: errors.BadRequest,
+			Err: err,
+		}
+	}
+
+	members, err := h.service.GetMembers(r.Context(), cinemaId)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Write(w, http.StatusOK, members); err != nil {
+		return errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+
+	return nil
+}
+
+func (h *StaffHandler) UpdateMember(w http.ResponseWriter, r *http.Request) error {
+	vars := mux.Vars(r)
+	cinemaId, err := strconv.Atoi(vars["cinemaId"])
+	if err != nil {
+		return errors.CustomError{
+			Key: errors.BadRequest,
+			Err: err,
+		}
+	}
+	userId, err := strconv.Atoi(vars["userId"])
+	if err != nil {
+		return errors.CustomError{
+			Key: errors.BadRequest,
+			Err: err,
+		}
+	}
+
+	var input map[string]interface{}
+	if err := json.Parse(r, &input); err != nil {
+		return errors.CustomError{
+			Key: errors.BadRequest,
+			Err: err,
+		}
+	}
+
+	if err := h.service.UpdateMember(r.Context(), cinemaId, userId, input); err != nil {
+		return err
+	}
+
+	if err := json.Write(w, http.StatusAccepted, nil); err != nil {
+		return errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+
+	return nil
+}
+
+func (h *StaffHandler) RemoveMember(w http.ResponseWriter, r *http.Request) error {
+	vars := mux.Vars(r)
+	cinemaId, err := strconv.Atoi(vars["cinemaId"])
+	if err != nil {
+		return errors.CustomError{
+			Key: errors.BadRequest,
+			Err: err,
+		}
+	}
+	userId, err := strconv.Atoi(vars["userId"])
+	if err != nil {
+		return errors.CustomError{
+			Key: errors.BadRequest,
+			Err: err,
+		}
+	}
+
+	if err := h.service.RemoveMember(r.Context(), cinemaId, userId); err != nil {
+		return err
+	}
+
+	if err := json.Write(w, http.StatusNoContent, nil); err != nil {
+		return errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+
+	return nil
+}
+
+func (h *StaffHandler) GetInvitations(w http.ResponseWriter, r *http.Request) error {
+	cinemaId, err := strconv.Atoi(mux.Vars(r)["cinemaId"])
+	if err != nil {
+		return errors.CustomError{
+			Key: errors.BadRequest,
+			Err: err,
+		}
+	}
+
+	invitations, err := h.service.GetInvitations(r.Context(), cinemaId)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Write(w, http.StatusOK, invitations); err != nil {
+		return errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+
+	return nil
+}
+
+func (h *StaffHandler) Invite(w http.ResponseWriter, r *http.Request) error {
+	cinemaId, err := strconv.Atoi(mux.Vars(r)["cinemaId"])
+	if err != nil {
+		return errors.CustomError{
+			Key: errors.BadRequest,
+			Err: err,
+		}
+	}
+
+	var input map[string]interface{}
+	if err := json.Parse(r, &input); err != nil {
+		return errors.CustomError{
+			Key: errors.BadRequest,
+			Err: err,
+		}
+	}
+
+	invitation, err := h.service.Invite(r.Context(), cinemaId, input)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Write(w, http.StatusCreated, invitation); err != nil {
+		return errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+
+	return nil
+}
+
+func (h *StaffHandler) RevokeInvitation(w http.ResponseWriter, r *http.Request) error {
+	vars := mux.Vars(r)
+	cinemaId, err := strconv.Atoi(vars["cinemaId"])
+	if err != nil {
+		return errors.CustomError{
+			Key: errors.BadRequest,
+			Err: err,
+		}
+	}
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		return errors.CustomError{
+			Key: errors.BadRequest,
+			Err: err,
+		}
+	}
+
+	if err := h.service.RevokeInvitation(r.Context(), cinemaId, id); err != nil {
+		return err
+	}
+
+	if err := json.Write(w, http.StatusNoContent, nil); err != nil {
+		return errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+
+	return nil
+}
+
+func (h *StaffHandler) AcceptInvitation(w http.ResponseWriter, r *http.Request) error {
+	var input map[string]interface{}
+	if err := json.Parse(r, &input); err != nil {
+		return errors.CustomError{
+			Key: errors.BadRequest,
+			Err: err,
+		}
+	}
+
+	invitation, err := h.service.AcceptInvitation(r.Context(), input)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Write(w, http.StatusOK, invitation); err != nil {
+		return errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+
+	return nil
+}
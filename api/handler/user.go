@@ -2,7 +2,9 @@ package handler
 
 import (
 	goErrors "errors"
+	"fmt"
 	"net/http"
+	"slices"
 	"strconv"
 
 	"github.com/cinema-booker/internal/constants"
@@ -18,34 +20,41 @@ import (
 
 type UserHandler struct {
 	service        user.UserService
-	userStore      user.UserStore
+	auth           *middleware.Auth
 	sessionService session.SessionService
 }
 
-func NewUserHandler(service user.UserService, userStore user.UserStore, sessionService session.SessionService) *UserHandler {
+func NewUserHandler(service user.UserService, auth *middleware.Auth, sessionService session.SessionService) *UserHandler {
 	return &UserHandler{
 		service:        service,
-		userStore:      userStore,
+		auth:           auth,
 		sessionService: sessionService,
 	}
 }
 
 func (h *UserHandler) RegisterRoutes(mux *mux.Router) {
-	mux.Handle("/users", errors.ErrorHandler(middleware.IsAuth(h.GetAll, h.userStore))).Methods(http.MethodGet)
-	mux.Handle("/users/{id}", errors.ErrorHandler(middleware.IsAuth(h.Get, h.userStore))).Methods(http.MethodGet)
-	mux.Handle("/users", errors.ErrorHandler(middleware.IsAuth(h.Create, h.userStore))).Methods(http.MethodPost)
-	mux.Handle("/users/{id}", errors.ErrorHandler(middleware.IsAuth(h.Update, h.userStore))).Methods(http.MethodPatch)
-	mux.Handle("/users/{id}", errors.ErrorHandler(middleware.IsAuth(h.Delete, h.userStore))).Methods(http.MethodDelete)
-	mux.Handle("/users/{id}/restore", errors.ErrorHandler(middleware.IsAuth(h.Restore, h.userStore))).Methods(http.MethodPatch)
-	mux.Handle("/users/{id}/password", errors.ErrorHandler(middleware.IsAuth(h.EditPassword, h.userStore))).Methods(http.MethodPatch)
-	mux.Handle("/dashboard", errors.ErrorHandler(middleware.IsAuth(h.getDashboardForUser, h.userStore))).Methods(http.MethodGet)
+	mux.Handle("/users", errors.ErrorHandler(h.auth.IsAuth(middleware.RequireRole(h.GetAll, constants.UserRoleAdmin)))).Methods(http.MethodGet)
+	mux.Handle("/users/{id}", errors.ErrorHandler(h.auth.IsAuth(middleware.IsSelf(h.Get, "id")))).Methods(http.MethodGet)
+	mux.Handle("/users", errors.ErrorHandler(h.auth.IsAuth(middleware.RequireRole(h.Create, constants.UserRoleAdmin)))).Methods(http.MethodPost)
+	mux.Handle("/users/{id}", errors.ErrorHandler(h.auth.IsAuth(middleware.IsSelf(h.Update, "id")))).Methods(http.MethodPatch)
+	mux.Handle("/users/{id}", errors.ErrorHandler(h.auth.IsAuth(middleware.RequireRole(h.Delete, constants.UserRoleAdmin)))).Methods(http.MethodDelete)
+	mux.Handle("/users/{id}/restore", errors.ErrorHandler(h.auth.IsAuth(middleware.RequireRole(h.Restore, constants.UserRoleAdmin)))).Methods(http.MethodPatch)
+	mux.Handle("/users/{id}/password", errors.ErrorHandler(h.auth.IsAuth(middleware.IsSelf(h.EditPassword, "id")))).Methods(http.MethodPatch)
+	mux.Handle("/dashboard", errors.ErrorHandler(h.auth.IsAuth(h.getDashboardForUser))).Methods(http.MethodGet)
 	mux.Handle("/sign-up", errors.ErrorHandler(h.SignUp)).Methods(http.MethodPost)
 	mux.Handle("/sign-in", errors.ErrorHandler(h.SignIn)).Methods(http.MethodPost)
+	mux.Handle("/token/refresh", errors.ErrorHandler(h.Refresh)).Methods(http.MethodPost)
+	mux.Handle("/sign-out", errors.ErrorHandler(h.auth.IsAuth(h.SignOut))).Methods(http.MethodPost)
+	mux.Handle("/sign-out/everywhere", errors.ErrorHandler(h.auth.IsAuth(h.SignOutEverywhere))).Methods(http.MethodPost)
 	mux.Handle("/send-password-reset", errors.ErrorHandler(h.SendPasswordReset)).Methods(http.MethodPost)
 	mux.Handle("/reset-password", errors.ErrorHandler(h.ResetPassword)).Methods(http.MethodPost)
-	mux.Handle("/me", errors.ErrorHandler(middleware.IsAuth(h.GetMe, h.userStore))).Methods(http.MethodGet)
+	mux.Handle("/me", errors.ErrorHandler(h.auth.IsAuth(h.GetMe))).Methods(http.MethodGet)
 }
 
+// selfEditableFields are the fields users other than admins may change on their own
+// profile; their role in particular is not one of them.
+var selfEditableFields = []string{"name", "email", "locale"}
+
 func (h *UserHandler) GetAll(w http.ResponseWriter, r *http.Request) error {
 	pagination := utils.GetPaginationQueryParams(r)
 	search := r.URL.Query().Get("search")
@@ -145,6 +154,17 @@ func (h *UserHandler) Update(w http.ResponseWriter, r *http.Request) error {
 		}
 	}
 
+	if role, _ := r.Context().Value(constants.UserRoleKey).(string); role != constants.UserRoleAdmin {
+		for field := range input {
+			if !slices.Contains(selfEditableFields, field) {
+				return errors.CustomError{
+					Key: errors.Forbidden,
+					Err: fmt.Errorf("field %s cannot be changed", field),
+				}
+			}
+		}
+	}
+
 	if err := h.service.Update(r.Context(), id, input); err != nil {
 		return err
 	}
@@ -254,6 +274,60 @@ func (h *UserHandler) SignIn(w http.ResponseWriter, r *http.Request) error {
 	return nil
 }
 
+func (h *UserHandler) Refresh(w http.ResponseWriter, r *http.Request) error {
+	var input map[string]interface{}
+	if err := json.Parse(r, &input); err != nil {
+		return errors.CustomError{
+			Key: errors.BadRequest,
+			Err: err,
+		}
+	}
+
+	response, err := h.service.Refresh(r.Context(), input)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Write(w, http.StatusOK, response); err != nil {
+		return errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+
+	return nil
+}
+
+func (h *UserHandler) SignOut(w http.ResponseWriter, r *http.Request) error {
+	if err := h.service.SignOut(r.Context()); err != nil {
+		return err
+	}
+
+	if err := json.Write(w, http.StatusNoContent, nil); err != nil {
+		return errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+
+	return nil
+}
+
+func (h *UserHandler) SignOutEverywhere(w http.ResponseWriter, r *http.Request) error {
+	if err := h.service.SignOutEverywhere(r.Context()); err != nil {
+		return err
+	}
+
+	if err := json.Write(w, http.StatusNoContent, nil); err != nil {
+		return errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+
+	return nil
+}
+
 func (h *UserHandler) SendPasswordReset(w http.ResponseWriter, r *http.Request) error {
 	var input map[string]interface{}
 	if err := json.Parse(r, &input); err != nil {
@@ -352,32 +426,9 @@ func (h *UserHandler) GetMe(w http.ResponseWriter, r *http.Request) error {
 }
 
 func (h *UserHandler) getDashboardForUser(w http.ResponseWriter, r *http.Request) error {
-
-	ctx := r.Context()
-	userRole, ok := ctx.Value(constants.UserRoleKey).(string)
-	if !ok {
-		return errors.CustomError{
-			Key: errors.Unauthorized,
-			Err: goErrors.New("user role not found in context"),
-		}
-	}
-
-	if userRole != "ADMIN" {
-		if err := json.Write(w, http.StatusOK, struct{}{}); err != nil {
-			return errors.CustomError{
-				Key: errors.InternalServerError,
-				Err: err,
-			}
-		}
-		return nil
-	}
-
-	response, err := h.sessionService.GetDashboardData()
+	response, err := h.sessionService.GetDashboardData(r.Context())
 	if err != nil {
-		return errors.CustomError{
-			Key: errors.InternalServerError,
-			Err: err,
-		}
+		return err
 	}
 
 	if err := json.Write(w, http.StatusOK, response); err != nil {
@@ -1,84 +1,141 @@
 package handler
 
 import (
-	"fmt"
+	goErrors "errors"
+	"log"
 	"net/http"
-	"sync"
+	"net/url"
+	"strings"
 
+	"github.com/cinema-booker/api/middleware"
+	"github.com/cinema-booker/internal/constants"
+	"github.com/cinema-booker/internal/notification"
 	"github.com/cinema-booker/internal/user"
+	"github.com/cinema-booker/pkg/errors"
+	"github.com/cinema-booker/pkg/json"
+	"github.com/gorilla/mux"
 	"github.com/gorilla/websocket"
 )
 
+// tokenSubprotocol is offered by browsers along with their JWT, as in
+// new WebSocket(url, ["access_token", token]).
+const tokenSubprotocol = "access_token"
+
 type WebSocketHandler struct {
-	upgrader  websocket.Upgrader
-	userStore user.UserStore
+	upgrader websocket.Upgrader
+	hub      *notification.Hub
+	tickets  *notification.Tickets
+	auth     *middleware.Auth
 }
 
-var (
-	managerConnections = make(map[string]*websocket.Conn)
-	connMutex          sync.Mutex
-)
+// NewWebSocketHandler accepts connections from pages served by allowedOrigins only.
+// Clients which send no Origin header, such as mobile apps, are not browsers and are
+// always accepted.
+func NewWebSocketHandler(hub *notification.Hub, tickets *notification.Tickets, auth *middleware.Auth, allowedOrigins []string) *WebSocketHandler {
+	origins := make(map[string]bool, len(allowedOrigins))
+	for _, origin := range allowedOrigins {
+		origins[strings.TrimRight(origin, "/")] = true
+	}
 
-func NewWebSocketHandler() *WebSocketHandler {
 	return &WebSocketHandler{
 		upgrader: websocket.Upgrader{
 			ReadBufferSize:  1024,
 			WriteBufferSize: 1024,
+			Subprotocols:    []string{tokenSubprotocol},
 			CheckOrigin: func(r *http.Request) bool {
-				return true
+				origin := r.Header.Get("Origin")
+				if origin == "" {
+					return true
+				}
+				u, err := url.Parse(origin)
+				if err != nil {
+					return false
+				}
+				return origins[u.Scheme+"://"+u.Host]
 			},
 		},
+		hub:     hub,
+		tickets: tickets,
+		auth:    auth,
 	}
 }
 
-func (h *WebSocketHandler) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
-	managerID := r.URL.Query().Get("managerID")
-	if managerID == "" {
-		http.Error(w, "Manager ID is required", http.StatusBadRequest)
-		return
+func (h *WebSocketHandler) RegisterRoutes(mux *mux.Router) {
+	mux.Handle("/ws", errors.ErrorHandler(h.HandleWebSocket)).Methods(http.MethodGet)
+	mux.Handle("/ws/ticket", errors.ErrorHandler(h.auth.IsAuth(h.CreateTicket))).Methods(http.MethodPost)
+}
+
+// authenticate returns the user opening the connection, identified by, in order, a
+// ticket from POST /ws/ticket, the Authorization header or the token subprotocol.
+func (h *WebSocketHandler) authenticate(r *http.Request) (user.User, error) {
+	if ticket := r.URL.Query().Get("ticket"); ticket != "" {
+		return h.auth.RedeemTicket(h.tickets, ticket)
 	}
 
-	conn, err := h.upgrader.Upgrade(w, r, nil)
-	if err != nil {
-		fmt.Println("Upgrade error:", err)
-		return
+	if header := r.Header.Get("Authorization"); header != "" {
+		token, ok := strings.CutPrefix(header, "Bearer ")
+		if !ok {
+			return user.User{}, errors.CustomError{
+				Key: errors.Unauthorized,
+				Err: goErrors.New("invalid token"),
+			}
+		}
+		return h.auth.Authenticate(token)
 	}
-	defer conn.Close()
-
-	connMutex.Lock()
-	managerConnections[managerID] = conn
-	connMutex.Unlock()
-
-	fmt.Printf("Manager %s connected\n", managerID, managerConnections)
-
-	defer func() {
-		connMutex.Lock()
-		delete(managerConnections, managerID)
-		connMutex.Unlock()
-		fmt.Printf("Manager %s disconnected\n", managerID)
-	}()
-
-	for {
-		_, message, err := conn.ReadMessage()
-		if err != nil {
-			fmt.Println("Read error:", err)
-			break
+
+	protocols := websocket.Subprotocols(r)
+	for i, protocol := range protocols {
+		if protocol == tokenSubprotocol && i+1 < len(protocols) {
+			return h.auth.Authenticate(protocols[i+1])
 		}
-		fmt.Printf("Received from %s: %s\n", managerID, message)
 	}
 
+	return user.User{}, errors.CustomError{
+		Key: errors.Unauthorized,
+		Err: goErrors.New("token is required"),
+	}
+}
+
+func (h *WebSocketHandler) HandleWebSocket(w http.ResponseWriter, r *http.Request) error {
+	user, err := h.authenticate(r)
+	if err != nil {
+		return err
+	}
+
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// The upgrader already replied with an error status.
+		log.Printf("❌ WebSocket upgrade error: %v", err)
+		return nil
+	}
+
+	h.hub.Serve(user.Id, conn)
+	return nil
 }
 
-func NotifyManager(managerID, message string) {
-	connMutex.Lock()
-	conn, ok := managerConnections[managerID]
-	connMutex.Unlock()
+func (h *WebSocketHandler) CreateTicket(w http.ResponseWriter, r *http.Request) error {
+	userId, ok := r.Context().Value(constants.UserIDKey).(int)
+	if !ok {
+		return errors.CustomError{
+			Key: errors.Unauthorized,
+			Err: goErrors.New("user id not authenticated"),
+		}
+	}
+
+	ticket, err := h.tickets.Issue(userId)
+	if err != nil {
+		return errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
 
-	if ok {
-		if err := conn.WriteMessage(websocket.TextMessage, []byte(message)); err != nil {
-			fmt.Println("Write error:", err)
+	if err := json.Write(w, http.StatusCreated, map[string]string{"ticket": ticket}); err != nil {
+		return errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
 		}
-	} else {
-		fmt.Printf("Manager %s not connected\n", managerID)
 	}
+
+	return nil
 }
@@ -1,72 +1,74 @@
 package handler
 
 import (
-	"encoding/json"
+	goErrors "errors"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
-	"strconv"
 
 	"github.com/cinema-booker/internal/booking"
-	"github.com/stripe/stripe-go/v79"
-	"github.com/stripe/stripe-go/v79/webhook"
+	"github.com/cinema-booker/pkg/errors"
+	"github.com/cinema-booker/third_party/payment"
+	"github.com/gorilla/mux"
 )
 
-const webhookSecret = "whsec_VNoJIPPJ2PsCaunGEDQ8bCAXB87VCkeA"
+type WebhookHandler struct {
+	service  booking.BookingService
+	payments payment.Provider
+}
 
-func HandleWebhook(bookingService *booking.Service) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		const MaxBodyBytes = int64(65536)
-		r.Body = http.MaxBytesReader(w, r.Body, MaxBodyBytes)
-		payload, err := io.ReadAll(r.Body)
-		if err != nil {
-			http.Error(w, "Request Body Read Error", http.StatusServiceUnavailable)
-			return
-		}
+func NewWebhookHandler(service booking.BookingService, payments payment.Provider) *WebhookHandler {
+	return &WebhookHandler{
+		service:  service,
+		payments: payments,
+	}
+}
 
-		signatureHeader := r.Header.Get("Stripe-Signature")
-		event, err := webhook.ConstructEvent(payload, signatureHeader, webhookSecret)
-		if err != nil {
-			http.Error(w, fmt.Sprintf("Webhook signature verification failed: %v", err), http.StatusBadRequest)
-			return
-		}
+func (h *WebhookHandler) RegisterRoutes(mux *mux.Router) {
+	mux.Handle("/webhook", errors.ErrorHandler(h.Handle)).Methods(http.MethodPost)
+}
 
-		switch event.Type {
-		case "checkout.session.completed":
-			var session stripe.CheckoutSession
-			if err := json.Unmarshal(event.Data.Raw, &session); err != nil {
-				http.Error(w, "Webhook Error", http.StatusBadRequest)
-				return
-			}
+func (h *WebhookHandler) Handle(w http.ResponseWriter, r *http.Request) error {
+	const MaxBodyBytes = int64(65536)
+	r.Body = http.MaxBytesReader(w, r.Body, MaxBodyBytes)
+	payload, err := io.ReadAll(r.Body)
+	if err != nil {
+		return errors.CustomError{
+			Key: errors.BadRequest,
+			Err: err,
+		}
+	}
 
-			sessionIDStr := session.Metadata["session_id"]
-			sessionId, err := strconv.Atoi(sessionIDStr)
-			if err != nil {
-				http.Error(w, "Invalid session ID", http.StatusBadRequest)
-				return
-			}
+	event, err := h.payments.VerifyWebhook(payload, r.Header.Get("Stripe-Signature"))
+	if err != nil {
+		return errors.CustomError{
+			Key: errors.BadRequest,
+			Err: fmt.Errorf("webhook signature verification failed: %w", err),
+		}
+	}
 
-			seats := session.Metadata["seats"]
-			var seatList []string
-			if err := json.Unmarshal([]byte(seats), &seatList); err != nil {
-				fmt.Printf("Failed to decode seats: %v\n", err)
-			} else {
-				fmt.Printf("Seats list: %v\n", seatList)
-			}
+	switch event.Type {
+	case payment.EventCheckoutCompleted:
+		_, err = h.service.ConfirmOrder(event.OrderId, event.Id)
 
-			bookingWithUsers, err := bookingService.GetBookingWithUsersBySessionID(sessionId, seatList)
-			if err != nil {
-				http.Error(w, "Booking not found", http.StatusNotFound)
-				return
-			}
+	case payment.EventCheckoutExpired, payment.EventPaymentFailed:
+		err = h.service.ExpireOrder(event.OrderId, event.Id)
 
-			message := fmt.Sprintf("User %s reserved %d seats: %v", bookingWithUsers.BookingUser.Name, len(seatList), seatList)
-			NotifyManager(strconv.Itoa(bookingWithUsers.CinemaUser.Id), message)
+	case payment.EventRefunded:
+		err = h.service.CompleteRefunds(event.OrderId, event.Id, event.RefundIds)
 
-		default:
-			fmt.Printf("Unhandled event type: %s\n", event.Type)
-		}
+	default:
+		log.Printf("Unhandled payment event %s of type %s", event.Id, event.Type)
+	}
 
-		w.WriteHeader(http.StatusOK)
+	// The provider retries deliveries until it gets a 2xx response, so an event that
+	// was already applied is acknowledged without being processed again.
+	if err != nil && !goErrors.Is(err, booking.ErrPaymentEventProcessed) {
+		log.Printf("❌ Error processing payment event %s of type %s: %v", event.Id, event.Type, err)
+		return err
 	}
+
+	w.WriteHeader(http.StatusOK)
+	return nil
 }
@@ -0,0 +1,109 @@
+package middleware
+
+import (
+	"context"
+	goErrors "errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/cinema-booker/internal/constants"
+	"github.com/cinema-booker/pkg/errors"
+	"github.com/gorilla/mux"
+)
+
+// CinemaAuthorizer checks that the authenticated user has a permission in a cinema.
+type CinemaAuthorizer interface {
+	Authorize(ctx context.Context, id int, permission string) error
+}
+
+// CinemaFunc returns the cinema a request acts on.
+type CinemaFunc func(r *http.Request) (int, error)
+
+// RequireRole lets through the users having one of roles. It must be wrapped by IsAuth.
+func RequireRole(handlerFunc errors.ErrorHandler, roles ...string) errors.ErrorHandler {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		role, ok := r.Context().Value(constants.UserRoleKey).(string)
+		if !ok {
+			return errors.CustomError{
+				Key: errors.Unauthorized,
+				Err: goErrors.New("user id not authenticated"),
+			}
+		}
+
+		if !slices.Contains(roles, role) {
+			return errors.CustomError{
+				Key: errors.Forbidden,
+				Err: fmt.Errorf("role %s is not allowed", role),
+			}
+		}
+
+		return handlerFunc(w, r)
+	}
+}
+
+// IsSelf lets through admins and the user whose id is the path variable name, e.g.
+// for a user to edit their own profile. It must be wrapped by IsAuth.
+func IsSelf(handlerFunc errors.ErrorHandler, name string) errors.ErrorHandler {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		userId, ok := r.Context().Value(constants.UserIDKey).(int)
+		if !ok {
+			return errors.CustomError{
+				Key: errors.Unauthorized,
+				Err: goErrors.New("user id not authenticated"),
+			}
+		}
+		role, _ := r.Context().Value(constants.UserRoleKey).(string)
+
+		id, err := pathId(r, name)
+		if err != nil {
+			return err
+		}
+
+		if role != constants.UserRoleAdmin && id != userId {
+			return errors.CustomError{
+				Key: errors.Forbidden,
+				Err: fmt.Errorf("user %d cannot act on user %d", userId, id),
+			}
+		}
+
+		return handlerFunc(w, r)
+	}
+}
+
+// HasCinemaPermission lets through the users having permission in the cinema returned
+// by cinemaId. It must be wrapped by IsAuth.
+func HasCinemaPermission(handlerFunc errors.ErrorHandler, cinemas CinemaAuthorizer, cinemaId CinemaFunc, permission string) errors.ErrorHandler {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		id, err := cinemaId(r)
+		if err != nil {
+			return err
+		}
+
+		if err := cinemas.Authorize(r.Context(), id, permission); err != nil {
+			return err
+		}
+
+		return handlerFunc(w, r)
+	}
+}
+
+// CinemaFromPath reads the cinema from the path variable name.
+func CinemaFromPath(name string) CinemaFunc {
+	return func(r *http.Request) (int, error) {
+		return pathId(r, name)
+	}
+}
+
+func pathId(r *http.Request, name string) (int, error) {
+	id, err := strconv.Atoi(mux.Vars(r)[name])
+	if err != nil {
+		return 0, errors.CustomError{
+			Key: errors.BadRequest,
+			Err: fmt.Errorf("invalid %s: %w", name, err),
+		}
+	}
+
+	return id, nil
+}
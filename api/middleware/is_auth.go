@@ -2,9 +2,9 @@ package middleware
 
 import (
 	"context"
+	"database/sql"
 	goErrors "errors"
 	"net/http"
-	"os"
 	"strings"
 
 	"github.com/cinema-booker/internal/constants"
@@ -13,7 +13,77 @@ import (
 	"github.com/cinema-booker/pkg/jwt"
 )
 
-func IsAuth(handlerFunc errors.ErrorHandler, store user.UserStore) errors.ErrorHandler {
+// Auth authenticates the users of requests by the access tokens the keys signed.
+type Auth struct {
+	keys  *jwt.KeySet
+	store user.UserStore
+}
+
+func NewAuth(keys *jwt.KeySet, store user.UserStore) *Auth {
+	return &Auth{
+		keys:  keys,
+		store: store,
+	}
+}
+
+// Authenticate returns the user a JWT was issued to.
+func (a *Auth) Authenticate(token string) (user.User, error) {
+	user, _, err := a.authenticate(token)
+	return user, err
+}
+
+// authenticate returns the user a JWT was issued to and the session it belongs to,
+// which must not have been signed out of.
+func (a *Auth) authenticate(token string) (user.User, string, error) {
+	claims, err := jwt.Parse(a.keys, token)
+	if err != nil {
+		return user.User{}, "", errors.CustomError{
+			Key: errors.Unauthorized,
+			Err: goErrors.New("invalid token"),
+		}
+	}
+
+	active, err := a.store.IsSessionActive(claims.SessionId)
+	if err != nil {
+		return user.User{}, "", errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+	if !active {
+		return user.User{}, "", errors.CustomError{
+			Key: errors.Unauthorized,
+			Err: goErrors.New("token revoked"),
+		}
+	}
+
+	// The role is read from the database rather than the claims, so that a change of
+	// role applies before the token expires.
+	userId, _ := claims.UserId()
+	user, err := a.store.FindById(userId)
+	if err != nil {
+		if goErrors.Is(err, sql.ErrNoRows) {
+			return user, "", errors.CustomError{
+				Key: errors.Unauthorized,
+				Err: goErrors.New("user not found"),
+			}
+		}
+		return user, "", errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+
+	return user, claims.SessionId, nil
+}
+
+// WithUser returns a copy of ctx carrying the authenticated user, as read by the services.
+func WithUser(ctx context.Context, user user.User) context.Context {
+	ctx = context.WithValue(ctx, constants.UserIDKey, user.Id)
+	return context.WithValue(ctx, constants.UserRoleKey, user.Role)
+}
+
+func (a *Auth) IsAuth(handlerFunc errors.ErrorHandler) errors.ErrorHandler {
 	return func(w http.ResponseWriter, r *http.Request) error {
 		token := r.Header.Get("Authorization")
 		if token == "" {
@@ -31,23 +101,13 @@ func IsAuth(handlerFunc errors.ErrorHandler, store user.UserStore) errors.ErrorH
 			}
 		}
 
-		userId, err := jwt.GetTokenUserId(tokenParts[1], os.Getenv("JWT_SECRET"))
-		if err != nil {
-			return errors.CustomError{
-				Key: errors.Unauthorized,
-				Err: goErrors.New("invalid token"),
-			}
-		}
-
-		user, err := store.FindById(userId)
+		user, sessionId, err := a.authenticate(tokenParts[1])
 		if err != nil {
 			return err
 		}
 
-		ctx := r.Context()
-		ctx = context.WithValue(ctx, constants.UserIDKey, user.Id)
-		ctx = context.WithValue(ctx, constants.UserRoleKey, user.Role)
-		r = r.WithContext(ctx)
+		ctx := context.WithValue(r.Context(), constants.SessionIDKey, sessionId)
+		r = r.WithContext(WithUser(ctx, user))
 
 		return handlerFunc(w, r)
 	}
@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	goErrors "errors"
+	"net/http"
+
+	"github.com/cinema-booker/internal/notification"
+	"github.com/cinema-booker/internal/user"
+	"github.com/cinema-booker/pkg/errors"
+)
+
+// IsStreamAuth authenticates long-lived streams. Browsers opening an EventSource
+// cannot set the Authorization header, so they pass a ticket from POST /ws/ticket
+// as ?ticket= instead; other clients use the Authorization header as with IsAuth.
+func (a *Auth) IsStreamAuth(handlerFunc errors.ErrorHandler, tickets *notification.Tickets) errors.ErrorHandler {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		ticket := r.URL.Query().Get("ticket")
+		if ticket == "" {
+			return a.IsAuth(handlerFunc)(w, r)
+		}
+
+		user, err := a.RedeemTicket(tickets, ticket)
+		if err != nil {
+			return err
+		}
+
+		r = r.WithContext(WithUser(r.Context(), user))
+
+		return handlerFunc(w, r)
+	}
+}
+
+// RedeemTicket returns the user a ticket from POST /ws/ticket was given to. A ticket
+// can only be redeemed once.
+func (a *Auth) RedeemTicket(tickets *notification.Tickets, ticket string) (user.User, error) {
+	userId, ok := tickets.Redeem(ticket)
+	if !ok {
+		return user.User{}, errors.CustomError{
+			Key: errors.Unauthorized,
+			Err: goErrors.New("invalid or expired ticket"),
+		}
+	}
+
+	user, err := a.store.FindById(userId)
+	if err != nil {
+		return user, errors.CustomError{
+			Key: errors.Unauthorized,
+			Err: err,
+		}
+	}
+
+	return user, nil
+}
@@ -0,0 +1,26 @@
+package analytics
+
+import "github.com/cinema-booker/internal/outbox"
+
+type Service struct {
+	tracker Tracker
+}
+
+func NewService(tracker Tracker) *Service {
+	return &Service{
+		tracker: tracker,
+	}
+}
+
+// Deliver reports an Event queued in the outbox to the analytics backend.
+func (s *Service) Deliver(message outbox.Message) error {
+	var event Event
+	if err := message.Decode(&event); err != nil {
+		return err
+	}
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = message.CreatedAt
+	}
+
+	return s.tracker.Track(event)
+}
@@ -0,0 +1,74 @@
+package analytics
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Tracker reports events to an analytics backend. Webhook posts them to an HTTP endpoint,
+// Memory keeps them in memory for tests and local development.
+type Tracker interface {
+	Track(event Event) error
+}
+
+type Webhook struct {
+	client *http.Client
+	url    string
+}
+
+func NewWebhook(url string) *Webhook {
+	return &Webhook{
+		client: &http.Client{Timeout: 10 * time.Second},
+		url:    url,
+	}
+}
+
+// Track posts the event as JSON, failing unless the endpoint answers with a 2xx status.
+func (w *Webhook) Track(event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	response, err := w.client.Post(w.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return fmt.Errorf("analytics webhook answered %s", response.Status)
+	}
+
+	return nil
+}
+
+// Memory is a Tracker keeping every event it is asked to track, see Memory.Tracked.
+type Memory struct {
+	mutex   sync.Mutex
+	tracked []Event
+}
+
+func NewMemory() *Memory {
+	return &Memory{}
+}
+
+func (m *Memory) Track(event Event) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.tracked = append(m.tracked, event)
+	return nil
+}
+
+// Tracked returns the events tracked so far, oldest first.
+func (m *Memory) Tracked() []Event {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return append([]Event{}, m.tracked...)
+}
@@ -0,0 +1,12 @@
+package analytics
+
+import "time"
+
+// Event is something that happened to a booking, reported to the analytics backend.
+// It is also the outbox payload of constants.OutboxTopicAnalytics, OccurredAt being
+// set on delivery to the time the message was queued.
+type Event struct {
+	Name       string                 `json:"name"`
+	Properties map[string]interface{} `json:"properties"`
+	OccurredAt time.Time              `json:"occurred_at"`
+}
@@ -0,0 +1,117 @@
+package booking
+
+import (
+	"github.com/cinema-booker/internal/constants"
+	"github.com/cinema-booker/internal/email"
+	"github.com/cinema-booker/internal/outbox"
+	"github.com/cinema-booker/third_party/mailer"
+)
+
+// ConfirmationEmailPayload is the outbox payload of constants.OutboxTopicConfirmationEmail.
+type ConfirmationEmailPayload struct {
+	OrderId int `json:"order_id"`
+}
+
+// CancellationEmailPayload is the outbox payload of constants.OutboxTopicCancellationEmail.
+// BookingId is nil when the whole order was canceled. Seats are those canceled, which the
+// order no longer lists.
+type CancellationEmailPayload struct {
+	OrderId   int      `json:"order_id"`
+	BookingId *int     `json:"booking_id"`
+	Seats     []string `json:"seats"`
+	Refund    int      `json:"refund"`
+}
+
+// SessionCanceledEmailPayload is the outbox payload of constants.OutboxTopicSessionCanceledEmail.
+type SessionCanceledEmailPayload struct {
+	OrderId int      `json:"order_id"`
+	Seats   []string `json:"seats"`
+}
+
+// sendEmail renders the template name in the locale of the viewer and sends it to them.
+func (s *Service) sendEmail(user User, name string, data interface{}) error {
+	message, err := s.emails.Render(name, user.Locale, data)
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.Send(mailer.Email{
+		To:      []string{user.Email},
+		Subject: message.Subject,
+		HTML:    message.HTML,
+		Text:    message.Text,
+	})
+}
+
+// SendConfirmationEmail delivers the confirmation email queued in the outbox when an order is confirmed.
+func (s *Service) SendConfirmationEmail(message outbox.Message) error {
+	var payload ConfirmationEmailPayload
+	if err := message.Decode(&payload); err != nil {
+		return err
+	}
+
+	order, err := s.store.FindOrderById(0, constants.UserRoleAdmin, payload.OrderId)
+	if err != nil {
+		return err
+	}
+
+	return s.sendEmail(order.User, email.TemplateBookingConfirmation, email.BookingConfirmationData{
+		Name:     order.User.Name,
+		OrderId:  order.Id,
+		Movie:    order.Session.Event.Movie.Title,
+		Cinema:   order.Session.Event.Cinema.Name,
+		Address:  order.Session.Event.Cinema.Address.Address,
+		Room:     order.Session.Room.Number,
+		Seats:    order.Seats,
+		StartsAt: order.Session.StartsAt,
+		Total:    order.Amount,
+		Currency: s.config.Currency,
+	})
+}
+
+// SendCancellationEmail delivers the email queued in the outbox when a booking or an order is canceled.
+func (s *Service) SendCancellationEmail(message outbox.Message) error {
+	var payload CancellationEmailPayload
+	if err := message.Decode(&payload); err != nil {
+		return err
+	}
+
+	order, err := s.store.FindOrderById(0, constants.UserRoleAdmin, payload.OrderId)
+	if err != nil {
+		return err
+	}
+
+	return s.sendEmail(order.User, email.TemplateBookingCancellation, email.BookingCancellationData{
+		Name:     order.User.Name,
+		OrderId:  order.Id,
+		Movie:    order.Session.Event.Movie.Title,
+		Cinema:   order.Session.Event.Cinema.Name,
+		Seats:    payload.Seats,
+		StartsAt: order.Session.StartsAt,
+		Refund:   payload.Refund,
+		Currency: s.config.Currency,
+	})
+}
+
+// SendSessionCanceledEmail delivers the email queued in the outbox for every confirmed
+// order of a session canceled by its cinema.
+func (s *Service) SendSessionCanceledEmail(message outbox.Message) error {
+	var payload SessionCanceledEmailPayload
+	if err := message.Decode(&payload); err != nil {
+		return err
+	}
+
+	order, err := s.store.FindOrderById(0, constants.UserRoleAdmin, payload.OrderId)
+	if err != nil {
+		return err
+	}
+
+	return s.sendEmail(order.User, email.TemplateSessionCanceled, email.SessionCanceledData{
+		Name:     order.User.Name,
+		OrderId:  order.Id,
+		Movie:    order.Session.Event.Movie.Title,
+		Cinema:   order.Session.Event.Cinema.Name,
+		Seats:    payload.Seats,
+		StartsAt: order.Session.StartsAt,
+	})
+}
@@ -0,0 +1,159 @@
+package booking
+
+import (
+	"fmt"
+
+	"github.com/cinema-booker/internal/constants"
+	"github.com/cinema-booker/internal/notification"
+	"github.com/cinema-booker/internal/outbox"
+	"github.com/cinema-booker/internal/room"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// BookingConfirmedPayload is the payload of booking.confirmed events.
+type BookingConfirmedPayload struct {
+	User   User     `json:"user"`
+	Seats  []string `json:"seats"`
+	Amount int      `json:"amount"`
+}
+
+// BookingCanceledPayload is the payload of booking.canceled events.
+type BookingCanceledPayload struct {
+	User   User     `json:"user"`
+	Seats  []string `json:"seats"`
+	Refund int      `json:"refund"`
+}
+
+// SessionSoldOutPayload is the payload of session.sold_out events.
+type SessionSoldOutPayload struct {
+	Capacity int `json:"capacity"`
+}
+
+// addNotification queues a notification in the outbox for every manager of a cinema,
+// that is its members allowed to manage it.
+func addNotification(tx *sqlx.Tx, cinemaId int, message string, event notification.Event) error {
+	managerIds := []int{}
+	err := tx.Select(&managerIds, `
+		SELECT m.user_id
+		FROM cinema_members m
+		JOIN staff_roles r ON m.role_id = r.id
+		WHERE m.cinema_id = $1 AND $2 = ANY(r.permissions)
+		ORDER BY m.user_id
+	`, cinemaId, constants.PermissionCinemaManage)
+	if err != nil {
+		return err
+	}
+
+	for _, managerId := range managerIds {
+		err = outbox.Add(tx, constants.OutboxTopicManagerNotification, notification.Delivery{
+			UserId:  managerId,
+			Message: message,
+			Event:   event,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// addConfirmedNotification tells the cinema managers that an order was paid.
+func addConfirmedNotification(tx *sqlx.Tx, order OrderWithUsers) error {
+	event, err := notification.NewEvent(constants.NotificationTypeBookingConfirmed, BookingConfirmedPayload{
+		User:   order.BookingUser,
+		Seats:  order.Seats,
+		Amount: order.Amount,
+	})
+	if err != nil {
+		return err
+	}
+	event.CinemaId = order.CinemaId
+	event.SessionId = order.SessionId
+	event.OrderId = order.OrderId
+	event.BookingIds = order.BookingIds
+
+	message := fmt.Sprintf("User %s reserved %d seats: %v", order.BookingUser.Name, len(order.Seats), order.Seats)
+
+	return addNotification(tx, order.CinemaId, message, event)
+}
+
+// addCanceledNotification tells the cinema managers that paid bookings of an order were canceled.
+func addCanceledNotification(tx *sqlx.Tx, orderId int, bookingIds []int, refund *Refund) error {
+	if len(bookingIds) == 0 {
+		return nil
+	}
+
+	var (
+		sessionId int
+		cinemaId  int
+		viewer    User
+		seats     pq.StringArray
+	)
+	err := tx.QueryRowx(`
+		SELECT o.session_id, c.id, u.id, u.name, array_agg(b.place ORDER BY b.place)
+		FROM orders o
+		JOIN users u ON o.user_id = u.id
+		JOIN sessions s ON o.session_id = s.id
+		JOIN rooms r ON s.room_id = r.id
+		JOIN cinemas c ON r.cinema_id = c.id
+		JOIN bookings b ON b.order_id = o.id
+		WHERE o.id = $1 AND b.id = ANY($2)
+		GROUP BY o.id, c.id, u.id
+	`, orderId, pq.Array(bookingIds)).Scan(&sessionId, &cinemaId, &viewer.Id, &viewer.Name, &seats)
+	if err != nil {
+		return err
+	}
+
+	payload := BookingCanceledPayload{
+		User:  viewer,
+		Seats: seats,
+	}
+	if refund != nil {
+		payload.Refund = refund.Amount
+	}
+	event, err := notification.NewEvent(constants.NotificationTypeBookingCanceled, payload)
+	if err != nil {
+		return err
+	}
+	event.CinemaId = cinemaId
+	event.SessionId = sessionId
+	event.OrderId = orderId
+	event.BookingIds = bookingIds
+
+	message := fmt.Sprintf("User %s canceled %d seats: %v", viewer.Name, len(seats), []string(seats))
+
+	return addNotification(tx, cinemaId, message, event)
+}
+
+// addSoldOutNotification tells the cinema managers when the last seat of a session was booked.
+func addSoldOutNotification(tx *sqlx.Tx, sessionId int, cinemaId int) error {
+	var layout room.Layout
+	err := tx.Get(&layout, "SELECT r.layout FROM sessions s JOIN rooms r ON s.room_id = r.id WHERE s.id=$1", sessionId)
+	if err != nil {
+		return err
+	}
+
+	var booked int
+	err = tx.Get(&booked, "SELECT COUNT(*) FROM bookings WHERE session_id=$1 AND status=$2", sessionId, constants.BookingStatusConfirmed)
+	if err != nil {
+		return err
+	}
+
+	capacity := layout.Capacity()
+	if capacity == 0 || booked < capacity {
+		return nil
+	}
+
+	event, err := notification.NewEvent(constants.NotificationTypeSessionSoldOut, SessionSoldOutPayload{
+		Capacity: capacity,
+	})
+	if err != nil {
+		return err
+	}
+	event.CinemaId = cinemaId
+	event.SessionId = sessionId
+
+	return addNotification(tx, cinemaId, fmt.Sprintf("Session #%d is sold out", sessionId), event)
+}
@@ -0,0 +1,147 @@
+package booking
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	goErrors "errors"
+	"fmt"
+
+	"github.com/cinema-booker/internal/constants"
+	"github.com/cinema-booker/pkg/errors"
+	"github.com/cinema-booker/pkg/ticket"
+	"github.com/go-pdf/fpdf"
+)
+
+// renderTickets lays out one A4 page per booking with the session details and the
+// QR code of its ticket.
+func (s *Service) renderTickets(bookings []Booking) ([]byte, error) {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetTitle("Tickets", true)
+	// The core fonts are encoded in cp1252, e.g. for accents in cinema addresses.
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+
+	for _, booking := range bookings {
+		token := ticket.Sign(s.config.TicketSecret, booking.TicketId)
+		image, err := ticket.PNG(token, 512)
+		if err != nil {
+			return nil, err
+		}
+
+		pdf.AddPage()
+
+		pdf.SetFont("Helvetica", "B", 24)
+		pdf.MultiCell(0, 12, tr(booking.Session.Event.Movie.Title), "", "L", false)
+		pdf.Ln(4)
+
+		details := [][2]string{
+			{"Cinema", booking.Session.Event.Cinema.Name},
+			{"Address", booking.Session.Event.Cinema.Address.Address},
+			{"Room", booking.Session.Room.Number},
+			{"Seat", booking.Place},
+			{"Starts at", booking.Session.StartsAt.Format("Monday 2 January 2006 at 15:04")},
+			{"Booking", fmt.Sprintf("#%d", booking.Id)},
+		}
+		for _, detail := range details {
+			pdf.SetFont("Helvetica", "B", 12)
+			pdf.CellFormat(40, 8, tr(detail[0]), "", 0, "L", false, 0, "")
+			pdf.SetFont("Helvetica", "", 12)
+			pdf.MultiCell(0, 8, tr(detail[1]), "", "L", false)
+		}
+
+		name := fmt.Sprintf("ticket-%d", booking.Id)
+		pdf.RegisterImageOptionsReader(name, fpdf.ImageOptions{ImageType: "PNG"}, bytes.NewReader(image))
+		pdf.ImageOptions(name, 55, pdf.GetY()+10, 100, 100, false, fpdf.ImageOptions{ImageType: "PNG"}, 0, "")
+
+		pdf.SetY(pdf.GetY() + 115)
+		pdf.SetFont("Courier", "", 8)
+		pdf.MultiCell(0, 4, token, "", "C", false)
+	}
+
+	var buffer bytes.Buffer
+	if err := pdf.Output(&buffer); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// GetTicketPDF returns the printable ticket of a confirmed booking.
+func (s *Service) GetTicketPDF(ctx context.Context, id int) ([]byte, error) {
+	booking, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if booking.Status != constants.BookingStatusConfirmed {
+		return nil, errors.CustomError{
+			Key: errors.BadRequest,
+			Err: goErrors.New("only confirmed bookings have a ticket"),
+		}
+	}
+
+	content, err := s.renderTickets([]Booking{booking})
+	if err != nil {
+		return nil, errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+
+	return content, nil
+}
+
+// GetOrderTicketsPDF returns the printable tickets of the confirmed seats of an order.
+func (s *Service) GetOrderTicketsPDF(ctx context.Context, id int) ([]byte, error) {
+	userId, ok := ctx.Value(constants.UserIDKey).(int)
+	if !ok {
+		return nil, errors.CustomError{
+			Key: errors.Unauthorized,
+			Err: goErrors.New("user id not authenticated"),
+		}
+	}
+	userRole, ok := ctx.Value(constants.UserRoleKey).(string)
+	if !ok {
+		return nil, errors.CustomError{
+			Key: errors.Unauthorized,
+			Err: goErrors.New("user id not authenticated"),
+		}
+	}
+
+	bookings, err := s.store.FindByOrderId(userId, userRole, id)
+	if err != nil {
+		return nil, errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+	if len(bookings) == 0 {
+		return nil, errors.CustomError{
+			Key: errors.NotFound,
+			Err: sql.ErrNoRows,
+		}
+	}
+
+	confirmed := []Booking{}
+	for _, booking := range bookings {
+		if booking.Status == constants.BookingStatusConfirmed {
+			confirmed = append(confirmed, booking)
+		}
+	}
+	if len(confirmed) == 0 {
+		return nil, errors.CustomError{
+			Key: errors.BadRequest,
+			Err: goErrors.New("order has no confirmed seats"),
+		}
+	}
+
+	content, err := s.renderTickets(confirmed)
+	if err != nil {
+		return nil, errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+
+	return content, nil
+}
@@ -0,0 +1,48 @@
+package booking
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/cinema-booker/internal/constants"
+)
+
+// ReleaseExpiredHolds marks every pending booking whose hold has expired as EXPIRED,
+// which frees its seat for other viewers, and returns how many seats were released.
+func (s *Service) ReleaseExpiredHolds() (int, error) {
+	changes, err := s.store.ExpirePending(time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, change := range changes {
+		s.publishSeats(constants.SeatEventReleased, change.SessionId, change.Seats)
+		count += len(change.Seats)
+	}
+
+	return count, nil
+}
+
+// RunHoldReaper releases expired holds every interval until ctx is done.
+func (s *Service) RunHoldReaper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			count, err := s.ReleaseExpiredHolds()
+			if err != nil {
+				log.Printf("❌ Error releasing expired booking holds: %v", err)
+				continue
+			}
+			if count > 0 {
+				log.Printf("⌛ Released %d expired booking holds", count)
+			}
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package booking
+
+import (
+	"fmt"
+
+	"github.com/cinema-booker/internal/constants"
+	"github.com/cinema-booker/internal/outbox"
+)
+
+// RefundPayload is the outbox payload of constants.OutboxTopicRefund.
+type RefundPayload struct {
+	RefundId int `json:"refund_id"`
+}
+
+// SendRefund asks the payment provider for the refund queued in the outbox when it was
+// recorded. The refund id is the idempotency key, so a retried message refunds only once.
+func (s *Service) SendRefund(message outbox.Message) error {
+	var payload RefundPayload
+	if err := message.Decode(&payload); err != nil {
+		return err
+	}
+
+	refund, err := s.store.FindRefundById(payload.RefundId)
+	if err != nil {
+		return err
+	}
+	if refund.Status != constants.RefundStatusRequested {
+		return nil
+	}
+
+	order, err := s.store.FindOrderById(0, constants.UserRoleAdmin, refund.OrderId)
+	if err != nil {
+		return err
+	}
+	if order.PaymentReference == nil {
+		return fmt.Errorf("order %d has no payment reference", order.Id)
+	}
+
+	paymentRefund, err := s.payments.Refund(*order.PaymentReference, int64(refund.Amount), fmt.Sprintf("refund-%d", refund.Id))
+	if err != nil {
+		return err
+	}
+
+	return s.store.SetRefundPaymentId(refund.Id, paymentRefund.Id)
+}
@@ -0,0 +1,89 @@
+package booking
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	goErrors "errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/cinema-booker/internal/constants"
+	"github.com/cinema-booker/internal/notification"
+	"github.com/cinema-booker/pkg/errors"
+)
+
+// SeatsPayload is the payload of the events pushed to the viewers looking at the seat
+// map of a session whenever some of its seats are held, booked or released.
+type SeatsPayload struct {
+	Seats []string `json:"seats"`
+}
+
+// SeatsTopic is the topic seat events of a session are published on.
+func SeatsTopic(sessionId int) string {
+	return fmt.Sprintf("session.%d.seats", sessionId)
+}
+
+// GetSeats returns which seats of a session are currently held or booked.
+func (s *Service) GetSeats(ctx context.Context, sessionId int) (SessionSeats, error) {
+	if _, ok := ctx.Value(constants.UserIDKey).(int); !ok {
+		return SessionSeats{}, errors.CustomError{
+			Key: errors.Unauthorized,
+			Err: goErrors.New("user id not authenticated"),
+		}
+	}
+
+	session, err := s.sessionStore.FindById(sessionId)
+	if err != nil {
+		if goErrors.Is(err, sql.ErrNoRows) {
+			return SessionSeats{}, errors.CustomError{
+				Key: errors.NotFound,
+				Err: err,
+			}
+		}
+		return SessionSeats{}, errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+	if session.DeletedAt != nil {
+		return SessionSeats{}, errors.CustomError{
+			Key: errors.NotFound,
+			Err: goErrors.New("session not found"),
+		}
+	}
+
+	seats, err := s.store.FindSessionSeats(sessionId, time.Now())
+	if err != nil {
+		return seats, errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+
+	return seats, nil
+}
+
+// publishSeats tells the viewers of a session that some of its seats changed. Seat
+// events are best effort: viewers who miss one reload the seat map when they reconnect.
+func (s *Service) publishSeats(eventType string, sessionId int, seats []string) {
+	if len(seats) == 0 {
+		return
+	}
+
+	event, err := notification.NewEvent(eventType, SeatsPayload{Seats: seats})
+	if err != nil {
+		log.Printf("❌ Error encoding %s event for session %d: %v", eventType, sessionId, err)
+		return
+	}
+	event.SessionId = sessionId
+
+	message, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("❌ Error encoding %s event for session %d: %v", eventType, sessionId, err)
+		return
+	}
+
+	s.publisher.Publish(SeatsTopic(sessionId), message)
+}
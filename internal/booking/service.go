@@ -4,10 +4,17 @@ import (
 	"context"
 	"database/sql"
 	goErrors "errors"
+	"fmt"
+	"strings"
+	"time"
 
 	"github.com/cinema-booker/internal/constants"
+	"github.com/cinema-booker/internal/email"
+	"github.com/cinema-booker/internal/notification"
 	"github.com/cinema-booker/internal/session"
 	"github.com/cinema-booker/pkg/errors"
+	"github.com/cinema-booker/third_party/mailer"
+	"github.com/cinema-booker/third_party/payment"
 )
 
 type BookingService interface {
@@ -15,18 +22,58 @@ type BookingService interface {
 	Get(ctx context.Context, id int) (Booking, error)
 	Create(ctx context.Context, input map[string]interface{}) (map[string]interface{}, error)
 	Cancel(ctx context.Context, id int) error
-	GetBookingWithUsersBySessionID(sessionID int, seats []string) (BookingWithUsers, error)
+
+	GetAllOrders(ctx context.Context, pagination map[string]int) ([]Order, error)
+	GetOrder(ctx context.Context, id int) (Order, error)
+	CancelOrder(ctx context.Context, id int) error
+	CancelSession(id int) error
+	ConfirmOrder(id int, eventId string) (OrderWithUsers, error)
+	ExpireOrder(id int, eventId string) error
+	CompleteRefunds(orderId int, eventId string, paymentRefundIds []string) error
+
+	GetTicket(ctx context.Context, id int) (string, error)
+	ValidateTicket(ctx context.Context, token string) (Booking, error)
+	GetTicketPDF(ctx context.Context, id int) ([]byte, error)
+	GetOrderTicketsPDF(ctx context.Context, id int) ([]byte, error)
+
+	GetSeats(ctx context.Context, sessionId int) (SessionSeats, error)
+}
+
+type Config struct {
+	// HoldDuration is how long seats stay reserved while the viewer pays.
+	HoldDuration time.Duration
+	// Currency is the currency prices are charged in, e.g. "eur".
+	Currency string
+	// TicketSecret signs the tokens encoded in ticket QR codes.
+	TicketSecret string
+}
+
+// CinemaAuthorizer checks that the authenticated user has a permission in a cinema.
+type CinemaAuthorizer interface {
+	Authorize(ctx context.Context, id int, permission string) error
 }
 
 type Service struct {
 	store        BookingStore
 	sessionStore session.SessionStore
+	cinemas      CinemaAuthorizer
+	payments     payment.Provider
+	mailer       mailer.Mailer
+	emails       *email.Registry
+	publisher    notification.Publisher
+	config       Config
 }
 
-func NewService(store BookingStore, sessionStore session.SessionStore) *Service {
+func NewService(store BookingStore, sessionStore session.SessionStore, cinemas CinemaAuthorizer, payments payment.Provider, mailer mailer.Mailer, emails *email.Registry, publisher notification.Publisher, config Config) *Service {
 	return &Service{
 		store:        store,
 		sessionStore: sessionStore,
+		cinemas:      cinemas,
+		payments:     payments,
+		mailer:       mailer,
+		emails:       emails,
+		publisher:    publisher,
+		config:       config,
 	}
 }
 
@@ -126,35 +173,96 @@ func (s *Service) Create(ctx context.Context, input map[string]interface{}) (map
 	}
 	sessionId := int(sessionIdFloat)
 
-	count, err := s.store.VerifySeatsCount(sessionId, seats)
+	session, err := s.sessionStore.FindById(sessionId)
 	if err != nil {
+		if goErrors.Is(err, sql.ErrNoRows) {
+			return nil, errors.CustomError{
+				Key: errors.NotFound,
+				Err: err,
+			}
+		}
 		return nil, errors.CustomError{
 			Key: errors.InternalServerError,
 			Err: err,
 		}
 	}
-	if count > 0 {
+	if session.DeletedAt != nil {
 		return nil, errors.CustomError{
-			Key: errors.BadRequest,
-			Err: goErrors.New("seats already booked"),
+			Key: errors.NotFound,
+			Err: goErrors.New("session not found"),
 		}
 	}
 
+	if len(seats) == 0 {
+		return nil, errors.CustomError{
+			Key: errors.BadRequest,
+			Err: goErrors.New("at least one seat is required"),
+		}
+	}
+	requested := make(map[string]bool, len(seats))
 	for _, seat := range seats {
-		err := s.store.Create(map[string]interface{}{
-			"session_id": input["session_id"],
-			"place":      seat,
-			"user_id":    userId,
-		})
-		if err != nil {
+		if requested[seat] {
+			return nil, errors.CustomError{
+				Key: errors.BadRequest,
+				Err: fmt.Errorf("seat %s requested twice", seat),
+			}
+		}
+		requested[seat] = true
+	}
+	if unavailable := session.Room.Layout.UnavailableSeats(seats); len(unavailable) > 0 {
+		return nil, errors.CustomError{
+			Key: errors.BadRequest,
+			Err: fmt.Errorf("seats not available in room %s: %s", session.Room.Number, strings.Join(unavailable, ", ")),
+		}
+	}
+
+	expiresAt := time.Now().Add(s.config.HoldDuration)
+	order, released, err := s.store.Reserve(userId, sessionId, seats, expiresAt)
+	if err != nil {
+		var taken *SeatsTakenError
+		if goErrors.As(err, &taken) {
 			return nil, errors.CustomError{
-				Key: errors.InternalServerError,
+				Key: errors.Conflict,
 				Err: err,
+				Details: map[string]interface{}{
+					"seats": taken.Seats,
+				},
 			}
 		}
+		return nil, errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
 	}
+	s.publishSeats(constants.SeatEventReleased, sessionId, released)
+	s.publishSeats(constants.SeatEventHeld, sessionId, seats)
 
-	session, err := s.sessionStore.FindById(sessionId)
+	checkout, err := s.payments.CreateCheckout(payment.CheckoutRequest{
+		OrderId: order.Id,
+		Items: []payment.LineItem{
+			{
+				Name:        fmt.Sprintf("Session #%d - Room %s", session.Id, session.Room.Number),
+				Description: fmt.Sprintf("Seats: %s", strings.Join(seats, ", ")),
+				UnitAmount:  int64(session.Price),
+				Quantity:    int64(len(seats)),
+			},
+		},
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		// free the seats right away since the viewer cannot pay for them
+		if released, cancelErr := s.store.CancelOrder(order.Id, constants.OrderStatusPending, 0); cancelErr != nil {
+			err = goErrors.Join(err, cancelErr)
+		} else {
+			s.publishSeats(constants.SeatEventReleased, sessionId, released)
+		}
+		return nil, errors.CustomError{
+			Key: errors.BadGateway,
+			Err: err,
+		}
+	}
+
+	err = s.store.SetOrderPaymentReference(order.Id, checkout.Id)
 	if err != nil {
 		return nil, errors.CustomError{
 			Key: errors.InternalServerError,
@@ -163,9 +271,13 @@ func (s *Service) Create(ctx context.Context, input map[string]interface{}) (map
 	}
 
 	return map[string]interface{}{
-		"session_id": session.Id,
-		"seats":      seats,
-		"price":      int64(session.Price),
+		"order_id":     order.Id,
+		"checkout_url": checkout.URL,
+		"session_id":   session.Id,
+		"seats":        seats,
+		"price":        int64(session.Price),
+		"amount":       int64(order.Amount),
+		"expires_at":   expiresAt,
 	}, nil
 }
 
@@ -185,7 +297,7 @@ func (s *Service) Cancel(ctx context.Context, id int) error {
 		}
 	}
 
-	_, err := s.store.FindById(userId, userRole, id)
+	booking, err := s.store.FindById(userId, userRole, id)
 	if err != nil {
 		if goErrors.Is(err, sql.ErrNoRows) {
 			return errors.CustomError{
@@ -199,10 +311,155 @@ func (s *Service) Cancel(ctx context.Context, id int) error {
 		}
 	}
 
-	err = s.store.Update(id, map[string]interface{}{
-		"status": constants.BookingStatusCanceled,
-	})
+	if booking.Status == constants.BookingStatusCanceled || booking.Status == constants.BookingStatusExpired {
+		return errors.CustomError{
+			Key: errors.BadRequest,
+			Err: fmt.Errorf("booking %d is already %s", booking.Id, booking.Status),
+		}
+	}
+
+	percent := 0
+	if booking.Status == constants.BookingStatusConfirmed && booking.OrderId != nil {
+		percent, err = s.refundPercent(ctx, booking.Session)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = s.store.Cancel(id, booking.Status, percent)
+	if err != nil {
+		if goErrors.Is(err, ErrStatusChanged) {
+			return errors.CustomError{
+				Key: errors.Conflict,
+				Err: err,
+			}
+		}
+		return errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+	s.publishSeats(constants.SeatEventReleased, booking.Session.Id, []string{booking.Place})
+
+	return nil
+}
+
+func (s *Service) GetAllOrders(ctx context.Context, pagination map[string]int) ([]Order, error) {
+	userId, ok := ctx.Value(constants.UserIDKey).(int)
+	if !ok {
+		return nil, errors.CustomError{
+			Key: errors.Unauthorized,
+			Err: goErrors.New("user id not authenticated"),
+		}
+	}
+	userRole, ok := ctx.Value(constants.UserRoleKey).(string)
+	if !ok {
+		return nil, errors.CustomError{
+			Key: errors.Unauthorized,
+			Err: goErrors.New("user id not authenticated"),
+		}
+	}
+
+	orders, err := s.store.FindAllOrders(userId, userRole, pagination)
+	if err != nil {
+		return nil, errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+
+	return orders, nil
+}
+
+func (s *Service) GetOrder(ctx context.Context, id int) (Order, error) {
+	userId, ok := ctx.Value(constants.UserIDKey).(int)
+	if !ok {
+		return Order{}, errors.CustomError{
+			Key: errors.Unauthorized,
+			Err: goErrors.New("user id not authenticated"),
+		}
+	}
+	userRole, ok := ctx.Value(constants.UserRoleKey).(string)
+	if !ok {
+		return Order{}, errors.CustomError{
+			Key: errors.Unauthorized,
+			Err: goErrors.New("user id not authenticated"),
+		}
+	}
+
+	order, err := s.store.FindOrderById(userId, userRole, id)
+	if err != nil {
+		if goErrors.Is(err, sql.ErrNoRows) {
+			return order, errors.CustomError{
+				Key: errors.NotFound,
+				Err: err,
+			}
+		}
+		return order, errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+
+	return order, nil
+}
+
+func (s *Service) CancelOrder(ctx context.Context, id int) error {
+	order, err := s.GetOrder(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if order.Status == constants.OrderStatusCanceled || order.Status == constants.OrderStatusExpired {
+		return errors.CustomError{
+			Key: errors.BadRequest,
+			Err: fmt.Errorf("order %d is already %s", order.Id, order.Status),
+		}
+	}
+
+	percent := 0
+	if order.Status == constants.OrderStatusConfirmed {
+		percent, err = s.refundPercent(ctx, order.Session)
+		if err != nil {
+			return err
+		}
+	}
+
+	released, err := s.store.CancelOrder(id, order.Status, percent)
+	if err != nil {
+		if goErrors.Is(err, ErrStatusChanged) {
+			return errors.CustomError{
+				Key: errors.Conflict,
+				Err: err,
+			}
+		}
+		return errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+	s.publishSeats(constants.SeatEventReleased, order.Session.Id, released)
+
+	return nil
+}
+
+// CancelSession cancels and fully refunds the orders of a session canceled by its cinema.
+// Callers check that the user may manage the session.
+func (s *Service) CancelSession(id int) error {
+	err := s.store.CancelSession(id, time.Now())
 	if err != nil {
+		if goErrors.Is(err, ErrSessionCanceled) {
+			return errors.CustomError{
+				Key: errors.Conflict,
+				Err: err,
+			}
+		}
+		if goErrors.Is(err, sql.ErrNoRows) {
+			return errors.CustomError{
+				Key: errors.NotFound,
+				Err: err,
+			}
+		}
 		return errors.CustomError{
 			Key: errors.InternalServerError,
 			Err: err,
@@ -212,6 +469,90 @@ func (s *Service) Cancel(ctx context.Context, id int) error {
 	return nil
 }
 
-func (s *Service) GetBookingWithUsersBySessionID(sessionID int, seats []string) (BookingWithUsers, error) {
-	return s.store.ConfirmBookingBySessionAndSeats(sessionID, seats)
+// refundPercent applies the cancellation policy of the session's cinema to paid bookings.
+// Users allowed to manage the bookings of the cinema can always cancel and refund in full.
+func (s *Service) refundPercent(ctx context.Context, session SessionWithEvent) (int, error) {
+	err := s.cinemas.Authorize(ctx, session.Event.Cinema.Id, constants.PermissionBookingsManage)
+	if err == nil {
+		return 100, nil
+	}
+	var customErr errors.CustomError
+	if !goErrors.As(err, &customErr) || customErr.Key != errors.Forbidden {
+		return 0, err
+	}
+
+	percent, allowed := session.Event.Cinema.CancellationPolicy.RefundPercent(session.StartsAt, time.Now())
+	if !allowed {
+		reason := "the session has already started"
+		if session.StartsAt.After(time.Now()) {
+			reason = "the cancellation period for this session is over"
+		}
+		return 0, errors.CustomError{
+			Key: errors.Forbidden,
+			Err: goErrors.New(reason),
+			Details: map[string]interface{}{
+				"reason": reason,
+				"policy": session.Event.Cinema.CancellationPolicy,
+			},
+		}
+	}
+
+	return percent, nil
+}
+
+// paymentEventError wraps a store error raised while applying a payment webhook event.
+// Events delivered again by the provider keep ErrPaymentEventProcessed in the chain.
+func paymentEventError(err error) error {
+	if goErrors.Is(err, ErrPaymentEventProcessed) {
+		return errors.CustomError{
+			Key: errors.Conflict,
+			Err: err,
+		}
+	}
+	if goErrors.Is(err, sql.ErrNoRows) {
+		return errors.CustomError{
+			Key: errors.NotFound,
+			Err: err,
+		}
+	}
+	return errors.CustomError{
+		Key: errors.InternalServerError,
+		Err: err,
+	}
+}
+
+// CompleteRefunds marks the refunds of an order settled by the payment provider as succeeded.
+func (s *Service) CompleteRefunds(orderId int, eventId string, paymentRefundIds []string) error {
+	err := s.store.CompleteRefunds(orderId, eventId, paymentRefundIds, time.Now())
+	if err != nil {
+		return paymentEventError(err)
+	}
+
+	return nil
+}
+
+// ConfirmOrder confirms a paid order. When the payment arrives after the seats were
+// given to someone else, the order stays unconfirmed and the store refunds the payment.
+func (s *Service) ConfirmOrder(id int, eventId string) (OrderWithUsers, error) {
+	order, err := s.store.ConfirmOrder(id, eventId)
+	if err != nil {
+		return order, paymentEventError(err)
+	}
+
+	if order.Status == constants.OrderStatusConfirmed {
+		s.publishSeats(constants.SeatEventBooked, order.SessionId, order.Seats)
+	}
+
+	return order, nil
+}
+
+// ExpireOrder releases the seats of an order whose checkout expired or whose payment failed.
+func (s *Service) ExpireOrder(id int, eventId string) error {
+	released, err := s.store.ExpireOrder(id, eventId)
+	if err != nil {
+		return paymentEventError(err)
+	}
+	s.publishSeats(constants.SeatEventReleased, released.SessionId, released.Seats)
+
+	return nil
 }
@@ -1,20 +1,66 @@
 package booking
 
 import (
+	goErrors "errors"
 	"fmt"
 	"strings"
+	"time"
 
+	"github.com/cinema-booker/internal/analytics"
 	"github.com/cinema-booker/internal/constants"
+	"github.com/cinema-booker/internal/outbox"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
+const uniqueViolation = "23505"
+
+// ErrPaymentEventProcessed is returned when a payment webhook event was already applied.
+var ErrPaymentEventProcessed = goErrors.New("payment event already processed")
+
+var (
+	ErrTicketNotConfirmed = goErrors.New("ticket booking is not confirmed")
+	ErrTicketUsed         = goErrors.New("ticket already used")
+	ErrTicketOtherCinema  = goErrors.New("ticket belongs to another cinema")
+)
+
+// ErrSessionCanceled is returned by CancelSession when the session was already canceled.
+var ErrSessionCanceled = goErrors.New("session already canceled")
+
+// ErrStatusChanged is returned by Cancel and CancelOrder when the booking or order is no
+// longer in the status the cancellation was decided for, e.g. when canceled concurrently.
+var ErrStatusChanged = goErrors.New("status changed since it was read")
+
+// SeatsTakenError is returned by Reserve when some of the requested seats already have an active booking.
+type SeatsTakenError struct {
+	Seats []string
+}
+
+func (e *SeatsTakenError) Error() string {
+	return fmt.Sprintf("seats already booked: %s", strings.Join(e.Seats, ", "))
+}
+
 type BookingStore interface {
 	FindAll(userId int, userRole string, pagination map[string]int, search string) ([]Booking, error)
 	FindById(userId int, userRole string, id int) (Booking, error)
-	VerifySeatsCount(sessionId int, seats []string) (int, error)
-	Create(input map[string]interface{}) error
+	FindByOrderId(userId int, userRole string, orderId int) ([]Booking, error)
+	FindSessionSeats(sessionId int, now time.Time) (SessionSeats, error)
+	Reserve(userId int, sessionId int, seats []string, expiresAt time.Time) (Order, []string, error)
+	ExpirePending(now time.Time) ([]SeatChange, error)
 	Update(id int, input map[string]interface{}) error
-	ConfirmBookingBySessionAndSeats(sessionID int, seats []string) (BookingWithUsers, error)
+	Cancel(id int, status string, refundPercent int) error
+
+	FindAllOrders(userId int, userRole string, pagination map[string]int) ([]Order, error)
+	FindOrderById(userId int, userRole string, id int) (Order, error)
+	CancelOrder(id int, status string, refundPercent int) ([]string, error)
+	CancelSession(id int, canceledAt time.Time) error
+	FindRefundById(id int) (Refund, error)
+	SetRefundPaymentId(id int, paymentRefundId string) error
+	CompleteRefunds(orderId int, eventId string, paymentRefundIds []string, refundedAt time.Time) error
+	SetOrderPaymentReference(id int, reference string) error
+	ConfirmOrder(id int, eventId string) (OrderWithUsers, error)
+	ExpireOrder(id int, eventId string) (SeatChange, error)
+	UseTicket(ticketId string, userId int, userRole string, usedAt time.Time) (Booking, error)
 }
 
 type Store struct {
@@ -27,27 +73,26 @@ func NewStore(db *sqlx.DB) *Store {
 	}
 }
 
-func (s *Store) FindAll(userId int, userRole string, pagination map[string]int, search string) ([]Booking, error) {
-	bookings := []Booking{}
-
-	offset := (pagination["page"] - 1) * pagination["limit"]
-	query := `
-		SELECT 
-      b.id AS id,
-      b.place AS place,
+const bookingColumns = `
+			b.id AS id,
+			b.order_id AS order_id,
+			b.place AS place,
 			b.status AS status,
+			b.ticket_id AS ticket_id,
+			b.used_at AS used_at,
 			u.id AS "user.id",
 			u.name AS "user.name",
 			s.id AS "session.id",
 			s.price AS "session.price",
-      s.starts_at AS "session.starts_at",
-      r.id AS "session.room.id",
-      r.number AS "session.room.number",
-      r.type AS "session.room.type",
+			s.starts_at AS "session.starts_at",
+			r.id AS "session.room.id",
+			r.number AS "session.room.number",
+			r.type AS "session.room.type",
 			e.id AS "session.event.id",
 			c.id AS "session.event.cinema.id",
 			c.name AS "session.event.cinema.name",
 			c.description AS "session.event.cinema.description",
+			c.cancellation_policy AS "session.event.cinema.cancellation_policy",
 			c.user_id AS "session.event.cinema.user_id",
 			c.deleted_at AS "session.event.cinema.deleted_at",
 			a.id AS "session.event.cinema.address.id",
@@ -60,25 +105,30 @@ func (s *Store) FindAll(userId int, userRole string, pagination map[string]int,
 			m.language AS "session.event.movie.language",
 			m.poster AS "session.event.movie.poster",
 			m.backdrop AS "session.event.movie.backdrop"
-    FROM bookings b
-    LEFT JOIN users u ON b.user_id = u.id
-    LEFT JOIN sessions s ON b.session_id = s.id
-    LEFT JOIN rooms r ON s.room_id = r.id
-    LEFT JOIN events e ON s.event_id = e.id
-    LEFT JOIN cinemas c ON e.cinema_id = c.id
-    LEFT JOIN movies m ON e.movie_id = m.id
-    LEFT JOIN addresses a ON c.address_id = a.id
+		FROM bookings b
+		LEFT JOIN users u ON b.user_id = u.id
+		LEFT JOIN sessions s ON b.session_id = s.id
+		LEFT JOIN rooms r ON s.room_id = r.id
+		LEFT JOIN events e ON s.event_id = e.id
+		LEFT JOIN cinemas c ON e.cinema_id = c.id
+		LEFT JOIN movies m ON e.movie_id = m.id
+		LEFT JOIN addresses a ON c.address_id = a.id
+`
+
+func (s *Store) FindAll(userId int, userRole string, pagination map[string]int, search string) ([]Booking, error) {
+	bookings := []Booking{}
+
+	offset := (pagination["page"] - 1) * pagination["limit"]
+	query := `
+		SELECT ` + bookingColumns + `
 		WHERE (
 			u.name ILIKE '%' || $1 || '%'
 			OR c.name ILIKE '%' || $1 || '%'
 			OR m.title ILIKE '%' || $1 || '%'
 		)
 	`
-	if userRole == constants.UserRoleManager {
-		query += fmt.Sprintf(" AND c.user_id = %d", userId)
-	}
-	if userRole == constants.UserRoleViewer {
-		query += fmt.Sprintf(" AND u.id = %d", userId)
+	if userRole != constants.UserRoleAdmin {
+		query += " AND " + visibleTo(userId)
 	}
 	query += " LIMIT $2 OFFSET $3"
 
@@ -87,25 +137,414 @@ func (s *Store) FindAll(userId int, userRole string, pagination map[string]int,
 	return bookings, err
 }
 
+// visibleTo restricts a query to the bookings or orders u of a user, and to those of the
+// cinemas c where the user has the permission to manage bookings.
+func visibleTo(userId int) string {
+	return fmt.Sprintf(`(u.id = %d OR c.id IN (
+		SELECT m.cinema_id
+		FROM cinema_members m
+		JOIN staff_roles r ON m.role_id = r.id
+		WHERE m.user_id = %d AND '%s' = ANY(r.permissions)
+	))`, userId, userId, constants.PermissionBookingsManage)
+}
+
 func (s *Store) FindById(userId int, userRole string, id int) (Booking, error) {
 	booking := Booking{}
 	query := `
-		SELECT 
-      b.id AS id,
-      b.place AS place,
-			b.status AS status,
+		SELECT ` + bookingColumns + `
+		WHERE b.id=$1
+	`
+	if userRole != constants.UserRoleAdmin {
+		query += " AND " + visibleTo(userId)
+	}
+
+	err := s.db.Get(&booking, query, id)
+
+	return booking, err
+}
+
+// FindByOrderId returns the bookings of an order, in seat order.
+func (s *Store) FindByOrderId(userId int, userRole string, orderId int) ([]Booking, error) {
+	bookings := []Booking{}
+	query := "SELECT " + bookingColumns + " WHERE b.order_id=$1"
+	if userRole != constants.UserRoleAdmin {
+		query += " AND " + visibleTo(userId)
+	}
+	query += " ORDER BY b.place"
+
+	err := s.db.Select(&bookings, query, orderId)
+
+	return bookings, err
+}
+
+// Reserve creates an order holding every seat for the user in a single transaction.
+// The session row is locked so concurrent reservations for the same session are
+// serialized. The seats are held as PENDING until expiresAt. It also returns the
+// seats of the session whose hold had expired and was released on the way.
+func (s *Store) Reserve(userId int, sessionId int, seats []string, expiresAt time.Time) (Order, []string, error) {
+	order := Order{}
+
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return order, nil, err
+	}
+	defer tx.Rollback()
+
+	var price int
+	err = tx.Get(&price, "SELECT price FROM sessions WHERE id=$1 FOR UPDATE", sessionId)
+	if err != nil {
+		return order, nil, err
+	}
+
+	// release holds that expired but were not reaped yet
+	released, err := expireSessionHolds(tx, sessionId, time.Now())
+	if err != nil {
+		return order, nil, err
+	}
+
+	query, args, err := sqlx.In(`
+		SELECT b.place
+		FROM bookings b
+		WHERE b.place IN (?) AND b.session_id = ? AND b.status IN (?)
+		ORDER BY b.place
+	`, seats, sessionId, []string{
+		constants.BookingStatusPending,
+		constants.BookingStatusConfirmed,
+	})
+	if err != nil {
+		return order, nil, err
+	}
+
+	taken := []string{}
+	err = tx.Select(&taken, tx.Rebind(query), args...)
+	if err != nil {
+		return order, nil, err
+	}
+	if len(taken) > 0 {
+		return order, nil, &SeatsTakenError{Seats: taken}
+	}
+
+	order = Order{
+		Seats:     seats,
+		Amount:    price * len(seats),
+		Status:    constants.OrderStatusPending,
+		ExpiresAt: &expiresAt,
+	}
+	err = tx.QueryRowx(
+		"INSERT INTO orders (user_id, session_id, amount, expires_at) VALUES ($1, $2, $3, $4) RETURNING id, created_at",
+		userId, sessionId, order.Amount, expiresAt,
+	).Scan(&order.Id, &order.CreatedAt)
+	if err != nil {
+		return order, nil, err
+	}
+
+	for _, seat := range seats {
+		_, err = tx.Exec(
+			"INSERT INTO bookings (user_id, session_id, order_id, place, expires_at) VALUES ($1, $2, $3, $4, $5)",
+			userId, sessionId, order.Id, seat, expiresAt,
+		)
+		if err != nil {
+			var pqErr *pq.Error
+			if goErrors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+				return order, nil, &SeatsTakenError{Seats: []string{seat}}
+			}
+			return order, nil, err
+		}
+	}
+
+	return order, released, tx.Commit()
+}
+
+func (s *Store) ExpirePending(now time.Time) ([]SeatChange, error) {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Queryx(
+		"UPDATE bookings SET status=$1 WHERE status=$2 AND expires_at < $3 RETURNING session_id, place",
+		constants.BookingStatusExpired, constants.BookingStatusPending, now,
+	)
+	if err != nil {
+		return nil, err
+	}
+	changes, err := scanSeatChanges(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(
+		"UPDATE orders SET status=$1 WHERE status=$2 AND expires_at < $3",
+		constants.OrderStatusExpired, constants.OrderStatusPending, now,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return changes, tx.Commit()
+}
+
+// scanSeatChanges groups (session_id, place) rows by session.
+func scanSeatChanges(rows *sqlx.Rows) ([]SeatChange, error) {
+	defer rows.Close()
+
+	changes := []SeatChange{}
+	indexes := map[int]int{}
+	for rows.Next() {
+		var (
+			sessionId int
+			place     string
+		)
+		if err := rows.Scan(&sessionId, &place); err != nil {
+			return nil, err
+		}
+		i, ok := indexes[sessionId]
+		if !ok {
+			i = len(changes)
+			indexes[sessionId] = i
+			changes = append(changes, SeatChange{SessionId: sessionId})
+		}
+		changes[i].Seats = append(changes[i].Seats, place)
+	}
+
+	return changes, rows.Err()
+}
+
+func expireSessionHolds(tx *sqlx.Tx, sessionId int, now time.Time) ([]string, error) {
+	released := []string{}
+	err := tx.Select(
+		&released,
+		"UPDATE bookings SET status=$1 WHERE session_id=$2 AND status=$3 AND expires_at < $4 RETURNING place",
+		constants.BookingStatusExpired, sessionId, constants.BookingStatusPending, now,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(
+		"UPDATE orders SET status=$1 WHERE session_id=$2 AND status=$3 AND expires_at < $4",
+		constants.OrderStatusExpired, sessionId, constants.OrderStatusPending, now,
+	)
+
+	return released, err
+}
+
+// FindSessionSeats returns the seats of a session that are held or booked at now.
+func (s *Store) FindSessionSeats(sessionId int, now time.Time) (SessionSeats, error) {
+	seats := SessionSeats{
+		SessionId: sessionId,
+		Held:      []string{},
+		Booked:    []string{},
+	}
+
+	rows, err := s.db.Queryx(`
+		SELECT place, status
+		FROM bookings
+		WHERE session_id = $1 AND (status = $2 OR (status = $3 AND expires_at >= $4))
+		ORDER BY place
+	`, sessionId, constants.BookingStatusConfirmed, constants.BookingStatusPending, now)
+	if err != nil {
+		return seats, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var place, status string
+		if err := rows.Scan(&place, &status); err != nil {
+			return seats, err
+		}
+		if status == constants.BookingStatusConfirmed {
+			seats.Booked = append(seats.Booked, place)
+		} else {
+			seats.Held = append(seats.Held, place)
+		}
+	}
+
+	return seats, rows.Err()
+}
+
+func (s *Store) Update(id int, input map[string]interface{}) error {
+	columns := make([]string, 0, len(input))
+	values := make([]interface{}, 0, len(input))
+	for col, val := range input {
+		columns = append(columns, fmt.Sprintf("%s=$%d", sqlx.Rebind(sqlx.DOLLAR, col), len(columns)+1))
+		values = append(values, val)
+	}
+
+	query := fmt.Sprintf("UPDATE bookings SET %s WHERE id=$%d", strings.Join(columns, ", "), len(columns)+1)
+	_, err := s.db.Exec(query, append(values, id)...)
+
+	return err
+}
+
+// Cancel cancels a single booking still in status, refunds refundPercent of its price when
+// it was paid, and cancels the order it belongs to once none of its seats remain active.
+func (s *Store) Cancel(id int, status string, refundPercent int) error {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Like CancelOrder and ConfirmOrder, lock the order before its bookings.
+	var orderId *int
+	err = tx.Get(&orderId, "SELECT order_id FROM bookings WHERE id=$1", id)
+	if err != nil {
+		return err
+	}
+	if orderId != nil {
+		_, err = tx.Exec("SELECT id FROM orders WHERE id=$1 FOR UPDATE", *orderId)
+		if err != nil {
+			return err
+		}
+	}
+
+	var booking struct {
+		Status string `db:"status"`
+		Place  string `db:"place"`
+	}
+	err = tx.Get(&booking, "SELECT status, place FROM bookings WHERE id=$1 FOR UPDATE", id)
+	if err != nil {
+		return err
+	}
+	if booking.Status != status {
+		return ErrStatusChanged
+	}
+
+	_, err = tx.Exec("UPDATE bookings SET status=$1 WHERE id=$2", constants.BookingStatusCanceled, id)
+	if err != nil {
+		return err
+	}
+
+	if orderId != nil {
+		_, err = tx.Exec(`
+			UPDATE orders o SET status=$1
+			WHERE o.id=$2 AND NOT EXISTS (
+				SELECT 1 FROM bookings b WHERE b.order_id = o.id AND b.status IN ($3, $4)
+			)
+		`, constants.OrderStatusCanceled, *orderId, constants.BookingStatusPending, constants.BookingStatusConfirmed)
+		if err != nil {
+			return err
+		}
+	}
+
+	if orderId != nil && booking.Status == constants.BookingStatusConfirmed {
+		refund, err := refundOrder(tx, *orderId, &id, refundPercent)
+		if err != nil {
+			return err
+		}
+
+		err = addCancellationEmail(tx, *orderId, &id, []string{booking.Place}, refund)
+		if err != nil {
+			return err
+		}
+
+		err = addCanceledNotification(tx, *orderId, []int{id}, refund)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// addCancellationEmail queues the email telling the viewer about the seats canceled of
+// an order, a single booking or the whole order when bookingId is nil.
+func addCancellationEmail(tx *sqlx.Tx, orderId int, bookingId *int, seats []string, refund *Refund) error {
+	payload := CancellationEmailPayload{
+		OrderId:   orderId,
+		BookingId: bookingId,
+		Seats:     seats,
+	}
+	if refund != nil {
+		payload.Refund = refund.Amount
+	}
+
+	return outbox.Add(tx, constants.OutboxTopicCancellationEmail, payload)
+}
+
+// refundOrder records the refund of percent of what remains paid for an order, or of the
+// price of one of its bookings when bookingId is set. Amounts are read once the order is
+// locked, and nil is returned when there is nothing to give back.
+func refundOrder(tx *sqlx.Tx, orderId int, bookingId *int, percent int) (*Refund, error) {
+	var order struct {
+		Amount           int     `db:"amount"`
+		RefundedAmount   int     `db:"refunded_amount"`
+		PaymentReference *string `db:"payment_reference"`
+		Price            int     `db:"price"`
+	}
+	err := tx.Get(&order, `
+		SELECT
+			o.amount, o.payment_reference, s.price,
+			(SELECT COALESCE(SUM(rf.amount), 0) FROM refunds rf WHERE rf.order_id = o.id) AS refunded_amount
+		FROM orders o
+		JOIN sessions s ON o.session_id = s.id
+		WHERE o.id=$1
+	`, orderId)
+	if err != nil {
+		return nil, err
+	}
+
+	remaining := order.Amount - order.RefundedAmount
+	amount := remaining * percent / 100
+	if bookingId != nil {
+		amount = min(order.Price*percent/100, remaining)
+	}
+	if amount <= 0 {
+		return nil, nil
+	}
+	if order.PaymentReference == nil {
+		return nil, fmt.Errorf("order %d has no payment reference", orderId)
+	}
+
+	refund := &Refund{
+		OrderId:   orderId,
+		BookingId: bookingId,
+		Amount:    amount,
+	}
+	return refund, createRefund(tx, refund)
+}
+
+// createRefund records a requested refund and queues its request to the payment provider,
+// so that the money only goes back once the refund is stored.
+func createRefund(tx *sqlx.Tx, refund *Refund) error {
+	refund.Status = constants.RefundStatusRequested
+	err := tx.QueryRowx(
+		"INSERT INTO refunds (order_id, booking_id, amount, status) VALUES ($1, $2, $3, $4) RETURNING id, created_at",
+		refund.OrderId, refund.BookingId, refund.Amount, refund.Status,
+	).Scan(&refund.Id, &refund.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	return outbox.Add(tx, constants.OutboxTopicRefund, RefundPayload{RefundId: refund.Id})
+}
+
+const orderColumns = `
+			o.id AS id,
+			o.amount AS amount,
+			o.payment_reference AS payment_reference,
+			(SELECT COALESCE(SUM(rf.amount), 0) FROM refunds rf WHERE rf.order_id = o.id) AS refunded_amount,
+			o.status AS status,
+			o.expires_at AS expires_at,
+			o.created_at AS created_at,
+			COALESCE(array_agg(b.place ORDER BY b.place) FILTER (WHERE b.id IS NOT NULL AND b.status <> '` + constants.BookingStatusCanceled + `'), '{}') AS seats,
 			u.id AS "user.id",
 			u.name AS "user.name",
+			u.email AS "user.email",
+			u.locale AS "user.locale",
 			s.id AS "session.id",
 			s.price AS "session.price",
-      s.starts_at AS "session.starts_at",
-      r.id AS "session.room.id",
-      r.number AS "session.room.number",
-      r.type AS "session.room.type",
+			s.starts_at AS "session.starts_at",
+			r.id AS "session.room.id",
+			r.number AS "session.room.number",
+			r.type AS "session.room.type",
 			e.id AS "session.event.id",
 			c.id AS "session.event.cinema.id",
 			c.name AS "session.event.cinema.name",
 			c.description AS "session.event.cinema.description",
+			c.cancellation_policy AS "session.event.cinema.cancellation_policy",
 			c.user_id AS "session.event.cinema.user_id",
 			c.deleted_at AS "session.event.cinema.deleted_at",
 			a.id AS "session.event.cinema.address.id",
@@ -118,127 +557,551 @@ func (s *Store) FindById(userId int, userRole string, id int) (Booking, error) {
 			m.language AS "session.event.movie.language",
 			m.poster AS "session.event.movie.poster",
 			m.backdrop AS "session.event.movie.backdrop"
-    FROM bookings b
-    LEFT JOIN users u ON b.user_id = u.id
-    LEFT JOIN sessions s ON b.session_id = s.id
-    LEFT JOIN rooms r ON s.room_id = r.id
-    LEFT JOIN events e ON s.event_id = e.id
-    LEFT JOIN cinemas c ON e.cinema_id = c.id
-    LEFT JOIN movies m ON e.movie_id = m.id
-    LEFT JOIN addresses a ON c.address_id = a.id
-		WHERE b.id=$1
-	`
-	if userRole == constants.UserRoleManager {
-		query += fmt.Sprintf(" AND c.user_id = %d", userId)
+		FROM orders o
+		LEFT JOIN bookings b ON b.order_id = o.id
+		LEFT JOIN users u ON o.user_id = u.id
+		LEFT JOIN sessions s ON o.session_id = s.id
+		LEFT JOIN rooms r ON s.room_id = r.id
+		LEFT JOIN events e ON s.event_id = e.id
+		LEFT JOIN cinemas c ON e.cinema_id = c.id
+		LEFT JOIN movies m ON e.movie_id = m.id
+		LEFT JOIN addresses a ON c.address_id = a.id
+`
+
+const orderGroupBy = " GROUP BY o.id, u.id, s.id, r.id, e.id, c.id, a.id, m.id"
+
+func (s *Store) FindAllOrders(userId int, userRole string, pagination map[string]int) ([]Order, error) {
+	orders := []Order{}
+
+	offset := (pagination["page"] - 1) * pagination["limit"]
+	query := "SELECT " + orderColumns + " WHERE TRUE"
+	if userRole != constants.UserRoleAdmin {
+		query += " AND " + visibleTo(userId)
 	}
-	if userRole == constants.UserRoleViewer {
-		query += fmt.Sprintf(" AND u.id = %d", userId)
+	query += orderGroupBy + " ORDER BY o.created_at DESC LIMIT $1 OFFSET $2"
+
+	err := s.db.Select(&orders, query, pagination["limit"], offset)
+
+	return orders, err
+}
+
+func (s *Store) FindOrderById(userId int, userRole string, id int) (Order, error) {
+	order := Order{}
+
+	query := "SELECT " + orderColumns + " WHERE o.id=$1"
+	if userRole != constants.UserRoleAdmin {
+		query += " AND " + visibleTo(userId)
 	}
+	query += orderGroupBy
 
-	err := s.db.Get(&booking, query, id)
+	err := s.db.Get(&order, query, id)
 
-	return booking, err
+	return order, err
 }
 
-func (s *Store) VerifySeatsCount(sessionId int, seats []string) (int, error) {
-	query := `
-		SELECT COUNT(DISTINCT b.id)
-		FROM bookings b
-		WHERE b.place IN (?) AND b.session_id = ? AND b.status IN (?)
-  `
-	query, args, err := sqlx.In(query, seats, sessionId, []string{
-		constants.BookingStatusPending,
-		constants.BookingStatusConfirmed,
-	})
+// CancelOrder cancels the order still in status with all of its active seats. When it was
+// paid, refundPercent of what remains is refunded, the viewer emailed and the cinema manager
+// notified. It returns the seats released, leaving out those canceled before.
+func (s *Store) CancelOrder(id int, status string, refundPercent int) ([]string, error) {
+	tx, err := s.db.Beginx()
 	if err != nil {
-		return -1, err
+		return nil, err
 	}
+	defer tx.Rollback()
 
-	query = s.db.Rebind(query)
-	var count int
-	err = s.db.Get(&count, query, args...)
+	var locked string
+	err = tx.Get(&locked, "SELECT status FROM orders WHERE id=$1 FOR UPDATE", id)
 	if err != nil {
-		return -1, err
+		return nil, err
+	}
+	if locked != status {
+		return nil, ErrStatusChanged
 	}
 
-	return count, nil
+	_, err = tx.Exec("UPDATE orders SET status=$1 WHERE id=$2", constants.OrderStatusCanceled, id)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Queryx(
+		"UPDATE bookings SET status=$1 WHERE order_id=$2 AND status IN ($3, $4) RETURNING id, place",
+		constants.BookingStatusCanceled, id, constants.BookingStatusPending, constants.BookingStatusConfirmed,
+	)
+	if err != nil {
+		return nil, err
+	}
+	bookingIds := []int{}
+	released := []string{}
+	for rows.Next() {
+		var (
+			bookingId int
+			place     string
+		)
+		if err := rows.Scan(&bookingId, &place); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		bookingIds = append(bookingIds, bookingId)
+		released = append(released, place)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if status == constants.OrderStatusConfirmed {
+		refund, err := refundOrder(tx, id, nil, refundPercent)
+		if err != nil {
+			return nil, err
+		}
+
+		err = addCancellationEmail(tx, id, nil, released, refund)
+		if err != nil {
+			return nil, err
+		}
+
+		err = addCanceledNotification(tx, id, bookingIds, refund)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return released, tx.Commit()
 }
 
-func (s *Store) Create(input map[string]interface{}) error {
-	query := "INSERT INTO bookings (user_id, session_id, place) VALUES ($1, $2, $3)"
-	_, err := s.db.Exec(query, input["user_id"], input["session_id"], input["place"])
+// CancelSession deletes a session canceled by its cinema. Its paid orders are canceled and
+// refunded in full and their viewers emailed, and the seats held by unpaid orders released.
+func (s *Store) CancelSession(id int, canceledAt time.Time) error {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Like Reserve and ConfirmOrder, lock the session before its orders.
+	var deletedAt *time.Time
+	err = tx.Get(&deletedAt, "SELECT deleted_at FROM sessions WHERE id=$1 FOR UPDATE", id)
+	if err != nil {
+		return err
+	}
+	if deletedAt != nil {
+		return ErrSessionCanceled
+	}
+
+	_, err = tx.Exec("UPDATE sessions SET deleted_at=$1 WHERE id=$2", canceledAt, id)
+	if err != nil {
+		return err
+	}
+
+	orders := []Order{}
+	err = tx.Select(&orders, `
+		SELECT
+			o.id, o.amount, o.status,
+			(SELECT COALESCE(SUM(rf.amount), 0) FROM refunds rf WHERE rf.order_id = o.id) AS refunded_amount
+		FROM orders o
+		WHERE o.session_id=$1 AND o.status IN ($2, $3)
+		ORDER BY o.id
+		FOR UPDATE
+	`, id, constants.OrderStatusPending, constants.OrderStatusConfirmed)
+	if err != nil {
+		return err
+	}
+
+	for _, order := range orders {
+		if order.Status == constants.OrderStatusPending {
+			_, err = tx.Exec("UPDATE orders SET status=$1 WHERE id=$2", constants.OrderStatusExpired, order.Id)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		_, err = tx.Exec("UPDATE orders SET status=$1 WHERE id=$2", constants.OrderStatusCanceled, order.Id)
+		if err != nil {
+			return err
+		}
+
+		seats := []string{}
+		err = tx.Select(
+			&seats,
+			"UPDATE bookings SET status=$1 WHERE order_id=$2 AND status=$3 RETURNING place",
+			constants.BookingStatusCanceled, order.Id, constants.BookingStatusConfirmed,
+		)
+		if err != nil {
+			return err
+		}
+
+		if order.Amount > order.RefundedAmount {
+			err = createRefund(tx, &Refund{
+				OrderId: order.Id,
+				Amount:  order.Amount - order.RefundedAmount,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		err = outbox.Add(tx, constants.OutboxTopicSessionCanceledEmail, SessionCanceledEmailPayload{
+			OrderId: order.Id,
+			Seats:   seats,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec(
+		"UPDATE bookings SET status=$1 WHERE session_id=$2 AND status=$3",
+		constants.BookingStatusCanceled, id, constants.BookingStatusConfirmed,
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(
+		"UPDATE bookings SET status=$1 WHERE session_id=$2 AND status=$3",
+		constants.BookingStatusExpired, id, constants.BookingStatusPending,
+	)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *Store) FindRefundById(id int) (Refund, error) {
+	var refund Refund
+	err := s.db.Get(&refund, "SELECT * FROM refunds WHERE id=$1", id)
+
+	return refund, err
+}
+
+// SetRefundPaymentId stores the id given by the payment provider to a requested refund,
+// which is then pending until the provider settles it.
+func (s *Store) SetRefundPaymentId(id int, paymentRefundId string) error {
+	_, err := s.db.Exec(
+		"UPDATE refunds SET payment_refund_id=$1, status=$2 WHERE id=$3 AND status=$4",
+		paymentRefundId, constants.RefundStatusPending, id, constants.RefundStatusRequested,
+	)
 
 	return err
 }
 
-func (s *Store) Update(id int, input map[string]interface{}) error {
-	columns := make([]string, 0, len(input))
-	values := make([]interface{}, 0, len(input))
-	for col, val := range input {
-		columns = append(columns, fmt.Sprintf("%s=$%d", sqlx.Rebind(sqlx.DOLLAR, col), len(columns)+1))
-		values = append(values, val)
+// CompleteRefunds marks the pending refunds of an order with the given provider ids as
+// settled by the payment provider.
+func (s *Store) CompleteRefunds(orderId int, eventId string, paymentRefundIds []string, refundedAt time.Time) error {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return err
 	}
+	defer tx.Rollback()
 
-	query := fmt.Sprintf("UPDATE bookings SET %s WHERE id=$%d", strings.Join(columns, ", "), len(columns)+1)
-	_, err := s.db.Exec(query, append(values, id)...)
+	err = recordPaymentEvent(tx, eventId, "refunded", orderId)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(
+		"UPDATE refunds SET status=$1, refunded_at=$2 WHERE order_id=$3 AND status=$4 AND payment_refund_id = ANY($5)",
+		constants.RefundStatusSucceeded, refundedAt, orderId, constants.RefundStatusPending, pq.StringArray(paymentRefundIds),
+	)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// recordPaymentEvent stores the id of a processed payment event, and fails with
+// ErrPaymentEventProcessed when the provider delivers it again.
+func recordPaymentEvent(tx *sqlx.Tx, eventId string, eventType string, orderId int) error {
+	result, err := tx.Exec(
+		"INSERT INTO payment_events (id, type, order_id) VALUES ($1, $2, $3) ON CONFLICT (id) DO NOTHING",
+		eventId, eventType, orderId,
+	)
+	if err != nil {
+		return err
+	}
+
+	count, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrPaymentEventProcessed
+	}
+
+	return nil
+}
+
+func (s *Store) SetOrderPaymentReference(id int, reference string) error {
+	_, err := s.db.Exec("UPDATE orders SET payment_reference=$1 WHERE id=$2", reference, id)
 
 	return err
 }
 
-func (s *Store) ConfirmBookingBySessionAndSeats(sessionID int, seats []string) (BookingWithUsers, error) {
-	queryUpdate := `
-	UPDATE bookings 
-	SET status = 'CONFIRMED'
-	WHERE session_id = ? AND place IN (?)
-	`
-	queryUpdate, argsUpdate, err := sqlx.In(queryUpdate, sessionID, seats)
+// ConfirmOrder confirms a paid order and its seats, and returns who booked them along
+// with the manager of the cinema. An order whose hold expired in the meantime is
+// confirmed again only if none of its seats were booked since and its session was not
+// canceled; otherwise its status is returned unchanged and the payment is refunded, in
+// the same transaction as the event is recorded so that the refund is not lost. An order
+// confirmed already is left as is.
+// Confirming an order queues the manager notifications, including the one telling the
+// session sold out, the confirmation email and the analytics event in the outbox.
+func (s *Store) ConfirmOrder(id int, eventId string) (OrderWithUsers, error) {
+	result := OrderWithUsers{}
+
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return result, err
+	}
+	defer tx.Rollback()
+
+	err = recordPaymentEvent(tx, eventId, "checkout.completed", id)
+	if err != nil {
+		return result, err
+	}
+
+	// The session of an order never changes, so it can be read before locking anything.
+	// Locks are then taken session first, like Reserve does, and the status is read
+	// under the lock of the order so that a concurrent CancelOrder is seen.
+	err = tx.Get(&result.SessionId, "SELECT session_id FROM orders WHERE id=$1", id)
 	if err != nil {
-		return BookingWithUsers{}, err
+		return result, err
+	}
+
+	var sessionDeletedAt *time.Time
+	err = tx.Get(&sessionDeletedAt, "SELECT deleted_at FROM sessions WHERE id=$1 FOR UPDATE", result.SessionId)
+	if err != nil {
+		return result, err
 	}
-	queryUpdate = s.db.Rebind(queryUpdate)
 
-	_, err = s.db.Exec(queryUpdate, argsUpdate...)
+	err = tx.Get(&result.Status, "SELECT status FROM orders WHERE id=$1 FOR UPDATE", id)
 	if err != nil {
-		return BookingWithUsers{}, err
+		return result, err
 	}
 
-	querySelect := `
-	SELECT 
-		b.id as booking_id, b.place, b.status, 
-		u.id as booking_user_id, u.name as booking_user_name,
-		cu.id as cinema_user_id, cu.name as cinema_user_name, cu.email as cinema_user_email, cu.role as cinema_user_role 
-	FROM 
-		bookings b
-	JOIN 
-		users u ON b.user_id = u.id
-	JOIN 
-		sessions s ON b.session_id = s.id
-	JOIN 
+	confirm := result.Status == constants.OrderStatusPending
+	if result.Status == constants.OrderStatusExpired && sessionDeletedAt == nil {
+		var taken int
+		err = tx.Get(&taken, `
+			SELECT COUNT(*)
+			FROM bookings b
+			JOIN bookings ob ON ob.session_id = b.session_id AND ob.place = b.place
+			WHERE ob.order_id = $1 AND b.order_id IS DISTINCT FROM $1 AND b.status IN ($2, $3)
+		`, id, constants.BookingStatusPending, constants.BookingStatusConfirmed)
+		if err != nil {
+			return result, err
+		}
+		confirm = taken == 0
+	}
+	// Only orders which can no longer be fulfilled give the payment back.
+	refund := !confirm && result.Status != constants.OrderStatusConfirmed
+
+	if confirm {
+		_, err = tx.Exec(
+			"UPDATE orders SET status=$1 WHERE id=$2",
+			constants.OrderStatusConfirmed, id,
+		)
+		if err != nil {
+			return result, err
+		}
+
+		_, err = tx.Exec(
+			"UPDATE bookings SET status=$1 WHERE order_id=$2 AND status IN ($3, $4)",
+			constants.BookingStatusConfirmed, id, constants.BookingStatusPending, constants.BookingStatusExpired,
+		)
+		if err != nil {
+			return result, err
+		}
+		result.Status = constants.OrderStatusConfirmed
+	}
+
+	query := `
+	SELECT
+		o.id, o.amount, o.payment_reference, array_agg(b.place ORDER BY b.place), array_agg(b.id ORDER BY b.place),
+		c.id, u.id, u.name
+	FROM
+		orders o
+	JOIN
+		bookings b ON b.order_id = o.id
+	JOIN
+		users u ON o.user_id = u.id
+	JOIN
+		sessions s ON o.session_id = s.id
+	JOIN
 		rooms r ON s.room_id = r.id
-	JOIN 
+	JOIN
 		cinemas c ON r.cinema_id = c.id
-	JOIN 
-		users cu ON c.user_id = cu.id
-	WHERE 
-		b.session_id = ? AND b.place IN (?)
-	LIMIT 1
+	WHERE
+		o.id = $1
+	GROUP BY
+		o.id, c.id, u.id
 	`
-	querySelect, argsSelect, err := sqlx.In(querySelect, sessionID, seats)
+	var (
+		seats      pq.StringArray
+		bookingIds pq.Int64Array
+	)
+	err = tx.QueryRow(query, id).Scan(
+		&result.OrderId, &result.Amount, &result.PaymentReference, &seats, &bookingIds,
+		&result.CinemaId, &result.BookingUser.Id, &result.BookingUser.Name,
+	)
+	if err != nil {
+		return result, err
+	}
+	result.Seats = seats
+	result.BookingIds = make([]int, len(bookingIds))
+	for i, bookingId := range bookingIds {
+		result.BookingIds[i] = int(bookingId)
+	}
+
+	if confirm {
+		err = addConfirmedNotification(tx, result)
+		if err != nil {
+			return result, err
+		}
+
+		err = addSoldOutNotification(tx, result.SessionId, result.CinemaId)
+		if err != nil {
+			return result, err
+		}
+
+		err = outbox.Add(tx, constants.OutboxTopicConfirmationEmail, ConfirmationEmailPayload{OrderId: id})
+		if err != nil {
+			return result, err
+		}
+
+		err = outbox.Add(tx, constants.OutboxTopicAnalytics, analytics.Event{
+			Name: constants.AnalyticsEventOrderConfirmed,
+			Properties: map[string]interface{}{
+				"order_id":   result.OrderId,
+				"session_id": result.SessionId,
+				"cinema_id":  result.CinemaId,
+				"seats":      len(result.Seats),
+				"amount":     result.Amount,
+			},
+		})
+		if err != nil {
+			return result, err
+		}
+	} else if refund {
+		var refunded int
+		err = tx.Get(&refunded, "SELECT COALESCE(SUM(amount), 0) FROM refunds WHERE order_id=$1", id)
+		if err != nil {
+			return result, err
+		}
+
+		if result.Amount > refunded {
+			err = createRefund(tx, &Refund{
+				OrderId: id,
+				Amount:  result.Amount - refunded,
+			})
+			if err != nil {
+				return result, err
+			}
+		}
+	}
+
+	return result, tx.Commit()
+}
+
+// ExpireOrder releases the seats of a pending order whose payment did not go through,
+// and returns the seats released.
+func (s *Store) ExpireOrder(id int, eventId string) (SeatChange, error) {
+	change := SeatChange{}
+
+	tx, err := s.db.Beginx()
 	if err != nil {
-		return BookingWithUsers{}, err
+		return change, err
 	}
-	querySelect = s.db.Rebind(querySelect)
+	defer tx.Rollback()
 
-	var result BookingWithUsers
+	err = recordPaymentEvent(tx, eventId, "checkout.expired", id)
+	if err != nil {
+		return change, err
+	}
 
-	err = s.db.QueryRow(querySelect, argsSelect...).Scan(
-		&result.Booking.Id, &result.Booking.Place, &result.Booking.Status,
-		&result.BookingUser.Id, &result.BookingUser.Name,
-		&result.CinemaUser.Id, &result.CinemaUser.Name, &result.CinemaUser.Email, &result.CinemaUser.Role,
+	_, err = tx.Exec(
+		"UPDATE orders SET status=$1 WHERE id=$2 AND status=$3",
+		constants.OrderStatusExpired, id, constants.OrderStatusPending,
 	)
 	if err != nil {
-		return result, err
+		return change, err
+	}
+
+	rows, err := tx.Queryx(
+		"UPDATE bookings SET status=$1 WHERE order_id=$2 AND status=$3 RETURNING session_id, place",
+		constants.BookingStatusExpired, id, constants.BookingStatusPending,
+	)
+	if err != nil {
+		return change, err
+	}
+	changes, err := scanSeatChanges(rows)
+	if err != nil {
+		return change, err
+	}
+	if len(changes) > 0 {
+		change = changes[0]
+	}
+
+	return change, tx.Commit()
+}
+
+// UseTicket marks the ticket of a confirmed booking as used at the door. Managers can
+// only check in tickets for the sessions of their own cinemas.
+func (s *Store) UseTicket(ticketId string, userId int, userRole string, usedAt time.Time) (Booking, error) {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return Booking{}, err
+	}
+	defer tx.Rollback()
+
+	var (
+		id         int
+		status     string
+		usedBefore *time.Time
+		isStaff    bool
+	)
+	query := `
+		SELECT b.id, b.status, b.used_at, EXISTS (
+			SELECT 1
+			FROM cinema_members cm
+			JOIN staff_roles r ON cm.role_id = r.id
+			WHERE cm.cinema_id = c.id AND cm.user_id = $2 AND $3 = ANY(r.permissions)
+		)
+		FROM bookings b
+		JOIN sessions s ON b.session_id = s.id
+		JOIN events e ON s.event_id = e.id
+		JOIN cinemas c ON e.cinema_id = c.id
+		WHERE b.ticket_id = $1
+		FOR UPDATE OF b
+	`
+	err = tx.QueryRowx(
+		query, ticketId, userId, constants.PermissionTicketsValidate,
+	).Scan(&id, &status, &usedBefore, &isStaff)
+	if err != nil {
+		return Booking{}, err
+	}
+
+	if userRole != constants.UserRoleAdmin && !isStaff {
+		return Booking{}, ErrTicketOtherCinema
+	}
+	if status != constants.BookingStatusConfirmed {
+		return Booking{}, ErrTicketNotConfirmed
+	}
+	if usedBefore != nil {
+		return Booking{}, ErrTicketUsed
+	}
+
+	_, err = tx.Exec("UPDATE bookings SET used_at=$1 WHERE id=$2", usedAt, id)
+	if err != nil {
+		return Booking{}, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return Booking{}, err
 	}
 
-	return result, nil
+	// Ticket scanners may not have the permission to manage bookings, needed to see them.
+	return s.FindById(userId, constants.UserRoleAdmin, id)
 }
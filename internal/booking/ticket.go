@@ -0,0 +1,90 @@
+package booking
+
+import (
+	"context"
+	"database/sql"
+	goErrors "errors"
+	"time"
+
+	"github.com/cinema-booker/internal/constants"
+	"github.com/cinema-booker/pkg/errors"
+	"github.com/cinema-booker/pkg/ticket"
+)
+
+// GetTicket returns the signed token of a confirmed booking, the content of its QR code.
+func (s *Service) GetTicket(ctx context.Context, id int) (string, error) {
+	booking, err := s.Get(ctx, id)
+	if err != nil {
+		return "", err
+	}
+
+	if booking.Status != constants.BookingStatusConfirmed {
+		return "", errors.CustomError{
+			Key: errors.BadRequest,
+			Err: goErrors.New("only confirmed bookings have a ticket"),
+		}
+	}
+
+	return ticket.Sign(s.config.TicketSecret, booking.TicketId), nil
+}
+
+// ValidateTicket checks the token scanned at the door and marks the ticket as used,
+// so that it cannot be used to enter twice.
+func (s *Service) ValidateTicket(ctx context.Context, token string) (Booking, error) {
+	userId, ok := ctx.Value(constants.UserIDKey).(int)
+	if !ok {
+		return Booking{}, errors.CustomError{
+			Key: errors.Unauthorized,
+			Err: goErrors.New("user id not authenticated"),
+		}
+	}
+	userRole, ok := ctx.Value(constants.UserRoleKey).(string)
+	if !ok {
+		return Booking{}, errors.CustomError{
+			Key: errors.Unauthorized,
+			Err: goErrors.New("user id not authenticated"),
+		}
+	}
+
+	ticketId, err := ticket.Verify(s.config.TicketSecret, token)
+	if err != nil {
+		return Booking{}, errors.CustomError{
+			Key: errors.BadRequest,
+			Err: err,
+		}
+	}
+
+	booking, err := s.store.UseTicket(ticketId, userId, userRole, time.Now())
+	if err != nil {
+		switch {
+		case goErrors.Is(err, sql.ErrNoRows):
+			return booking, errors.CustomError{
+				Key: errors.NotFound,
+				Err: err,
+			}
+		case goErrors.Is(err, ErrTicketOtherCinema):
+			return booking, errors.CustomError{
+				Key: errors.Forbidden,
+				Err: err,
+			}
+		case goErrors.Is(err, ErrTicketNotConfirmed):
+			return booking, errors.CustomError{
+				Key:     errors.BadRequest,
+				Err:     err,
+				Details: map[string]string{"reason": err.Error()},
+			}
+		case goErrors.Is(err, ErrTicketUsed):
+			return booking, errors.CustomError{
+				Key:     errors.Conflict,
+				Err:     err,
+				Details: map[string]string{"reason": err.Error()},
+			}
+		}
+		return booking, errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+
+	return booking, nil
+}
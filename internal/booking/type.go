@@ -1,17 +1,20 @@
 package booking
 
 import (
-	"github.com/cinema-booker/internal/user"
 	"time"
 
 	"github.com/cinema-booker/internal/cinema"
 	"github.com/cinema-booker/internal/event"
 	"github.com/cinema-booker/internal/room"
+	"github.com/lib/pq"
 )
 
 type User struct {
 	Id   int    `json:"id" db:"id"`
 	Name string `json:"name" db:"name"`
+	// Email and Locale are only loaded to send emails to the viewer and never exposed.
+	Email  string `json:"-" db:"email"`
+	Locale string `json:"-" db:"locale"`
 }
 
 type EventBasic struct {
@@ -29,15 +32,62 @@ type SessionWithEvent struct {
 }
 
 type Booking struct {
-	Id      int              `json:"id" db:"id"`
-	Place   string           `json:"place" db:"place"`
-	Status  string           `json:"status" db:"status"`
-	User    User             `json:"user"`
-	Session SessionWithEvent `json:"session"`
+	Id       int              `json:"id" db:"id"`
+	OrderId  *int             `json:"order_id" db:"order_id"`
+	Place    string           `json:"place" db:"place"`
+	Status   string           `json:"status" db:"status"`
+	TicketId string           `json:"-" db:"ticket_id"`
+	UsedAt   *time.Time       `json:"used_at" db:"used_at"`
+	User     User             `json:"user"`
+	Session  SessionWithEvent `json:"session"`
 }
 
-type BookingWithUsers struct {
-	Booking     Booking        `json:"booking"`
-	BookingUser User           `json:"booking_user"`
-	CinemaUser  user.UserBasic `json:"cinema_user"` // Updated type
+type Order struct {
+	Id               int              `json:"id" db:"id"`
+	Seats            pq.StringArray   `json:"seats" db:"seats"`
+	Amount           int              `json:"amount" db:"amount"`
+	PaymentReference *string          `json:"payment_reference" db:"payment_reference"`
+	RefundedAmount   int              `json:"refunded_amount" db:"refunded_amount"`
+	Status           string           `json:"status" db:"status"`
+	ExpiresAt        *time.Time       `json:"expires_at" db:"expires_at"`
+	CreatedAt        time.Time        `json:"created_at" db:"created_at"`
+	User             User             `json:"user"`
+	Session          SessionWithEvent `json:"session"`
+}
+
+type Refund struct {
+	Id              int        `json:"id" db:"id"`
+	OrderId         int        `json:"order_id" db:"order_id"`
+	BookingId       *int       `json:"booking_id" db:"booking_id"`
+	PaymentRefundId *string    `json:"payment_refund_id" db:"payment_refund_id"`
+	Amount          int        `json:"amount" db:"amount"`
+	Status          string     `json:"status" db:"status"`
+	CreatedAt       time.Time  `json:"created_at" db:"created_at"`
+	RefundedAt      *time.Time `json:"refunded_at" db:"refunded_at"`
+}
+
+type OrderWithUsers struct {
+	OrderId          int      `json:"order_id"`
+	SessionId        int      `json:"session_id"`
+	CinemaId         int      `json:"cinema_id"`
+	BookingIds       []int    `json:"booking_ids"`
+	Status           string   `json:"status"`
+	Amount           int      `json:"amount"`
+	PaymentReference *string  `json:"payment_reference"`
+	Seats            []string `json:"seats"`
+	BookingUser      User     `json:"booking_user"`
+}
+
+// SeatChange lists seats of a session whose availability changed.
+type SeatChange struct {
+	SessionId int
+	Seats     []string
+}
+
+// SessionSeats is the availability of the seats of a session. Seats that are not
+// listed are free.
+type SessionSeats struct {
+	SessionId int      `json:"session_id"`
+	Held      []string `json:"held"`
+	Booked    []string `json:"booked"`
 }
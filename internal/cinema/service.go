@@ -4,6 +4,8 @@ import (
 	"context"
 	"database/sql"
 	goErrors "errors"
+	"fmt"
+	"slices"
 	"time"
 
 	"github.com/cinema-booker/internal/constants"
@@ -15,8 +17,10 @@ type CinemaService interface {
 	Get(ctx context.Context, id int) (CinemaWithRooms, error)
 	Create(ctx context.Context, input map[string]interface{}) error
 	Update(ctx context.Context, id int, input map[string]interface{}) error
+	UpdateCancellationPolicy(ctx context.Context, id int, input map[string]interface{}) error
 	Delete(ctx context.Context, id int) error
 	Restore(ctx context.Context, id int) error
+	Authorize(ctx context.Context, id int, permission string) error
 }
 
 type Service struct {
@@ -106,6 +110,48 @@ func (s *Service) Update(ctx context.Context, id int, input map[string]interface
 	return nil
 }
 
+func (s *Service) UpdateCancellationPolicy(ctx context.Context, id int, input map[string]interface{}) error {
+	_, err := s.store.FindById(id)
+	if err != nil {
+		if goErrors.Is(err, sql.ErrNoRows) {
+			return errors.CustomError{
+				Key: errors.NotFound,
+				Err: err,
+			}
+		}
+		return errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+
+	policy, err := ParseCancellationPolicy(input)
+	if err != nil {
+		return errors.CustomError{
+			Key: errors.BadRequest,
+			Err: err,
+		}
+	}
+	if err := policy.Validate(); err != nil {
+		return errors.CustomError{
+			Key: errors.BadRequest,
+			Err: err,
+		}
+	}
+
+	err = s.store.Update(id, map[string]interface{}{
+		"cancellation_policy": policy,
+	})
+	if err != nil {
+		return errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+
+	return nil
+}
+
 func (s *Service) Delete(ctx context.Context, id int) error {
 	_, err := s.store.FindById(id)
 	if err != nil {
@@ -161,3 +207,45 @@ func (s *Service) Restore(ctx context.Context, id int) error {
 
 	return nil
 }
+
+// Authorize checks that the authenticated user has a permission in a cinema: admins
+// have them all, the members of the cinema those of their role.
+func (s *Service) Authorize(ctx context.Context, id int, permission string) error {
+	userId, ok := ctx.Value(constants.UserIDKey).(int)
+	if !ok {
+		return errors.CustomError{
+			Key: errors.Unauthorized,
+			Err: goErrors.New("user id not authenticated"),
+		}
+	}
+	userRole, ok := ctx.Value(constants.UserRoleKey).(string)
+	if !ok {
+		return errors.CustomError{
+			Key: errors.Unauthorized,
+			Err: goErrors.New("user id not authenticated"),
+		}
+	}
+
+	permissions, err := s.store.FindPermissions(id, userId)
+	if err != nil {
+		if goErrors.Is(err, sql.ErrNoRows) {
+			return errors.CustomError{
+				Key: errors.NotFound,
+				Err: err,
+			}
+		}
+		return errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+
+	if userRole != constants.UserRoleAdmin && !slices.Contains(permissions, permission) {
+		return errors.CustomError{
+			Key: errors.Forbidden,
+			Err: fmt.Errorf("permission %s required in cinema %d", permission, id),
+		}
+	}
+
+	return nil
+}
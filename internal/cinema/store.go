@@ -4,12 +4,15 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/cinema-booker/internal/constants"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
 type CinemaStore interface {
 	FindAll(pagination map[string]int, search string) ([]Cinema, error)
 	FindById(id int) (CinemaWithRooms, error)
+	FindPermissions(id int, userId int) ([]string, error)
 	Create(input map[string]interface{}) error
 	Update(id int, input map[string]interface{}) error
 }
@@ -34,6 +37,7 @@ func (s *Store) FindAll(pagination map[string]int, search string) ([]Cinema, err
 			c.user_id AS user_id,
       c.name AS name,
       c.description AS description,
+			c.cancellation_policy AS cancellation_policy,
 			c.deleted_at AS deleted_at,
       a.id AS "address.id",
       a.address AS "address.address",
@@ -60,6 +64,7 @@ func (s *Store) FindById(id int) (CinemaWithRooms, error) {
       c.id AS id,
       c.name AS name,
       c.description AS description,
+      c.cancellation_policy AS cancellation_policy,
       a.id AS "address.id",
       a.address AS "address.address",
       a.longitude AS "address.longitude",
@@ -69,7 +74,8 @@ func (s *Store) FindById(id int) (CinemaWithRooms, error) {
           json_build_object(
             'id', r.id,
             'number', r.number,
-            'type', r.type
+            'type', r.type,
+            'layout', r.layout
           )
         ) FILTER (WHERE r.id IS NOT NULL),
         '[]'
@@ -87,6 +93,22 @@ func (s *Store) FindById(id int) (CinemaWithRooms, error) {
 	return cinema, err
 }
 
+// FindPermissions returns the permissions a user has in a cinema, deleted or not, as a
+// member of it. It returns sql.ErrNoRows when the cinema does not exist.
+func (s *Store) FindPermissions(id int, userId int) ([]string, error) {
+	permissions := pq.StringArray{}
+	query := `
+		SELECT COALESCE(r.permissions, '{}')
+		FROM cinemas c
+		LEFT JOIN cinema_members m ON m.cinema_id = c.id AND m.user_id = $2
+		LEFT JOIN staff_roles r ON m.role_id = r.id
+		WHERE c.id=$1
+	`
+	err := s.db.Get(&permissions, query, id, userId)
+
+	return permissions, err
+}
+
 func (s *Store) Create(input map[string]interface{}) error {
 	tx, err := s.db.Beginx()
 	if err != nil {
@@ -125,6 +147,16 @@ func (s *Store) Create(input map[string]interface{}) error {
 		return err
 	}
 
+	// The user creating a cinema becomes its first manager.
+	memberQuery := `
+		INSERT INTO cinema_members (cinema_id, user_id, role_id)
+		SELECT $1, $2, id FROM staff_roles WHERE cinema_id IS NULL AND name = $3
+	`
+	_, err = tx.Exec(memberQuery, cinemaId, input["user_id"], constants.StaffRoleManager)
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
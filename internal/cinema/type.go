@@ -1,11 +1,91 @@
 package cinema
 
 import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
 	"time"
 
 	"github.com/cinema-booker/internal/room"
 )
 
+// CancellationRule refunds RefundPercent of the price when a booking is canceled
+// at least HoursBefore hours before its session starts.
+type CancellationRule struct {
+	HoursBefore   int `json:"hours_before"`
+	RefundPercent int `json:"refund_percent"`
+}
+
+// CancellationPolicy forbids cancellations once no rule applies anymore.
+type CancellationPolicy struct {
+	Rules []CancellationRule `json:"rules"`
+}
+
+// ParseCancellationPolicy converts a decoded JSON request value into a CancellationPolicy.
+func ParseCancellationPolicy(v interface{}) (CancellationPolicy, error) {
+	policy := CancellationPolicy{}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return policy, err
+	}
+	if err := json.Unmarshal(data, &policy); err != nil {
+		return policy, err
+	}
+
+	return policy, nil
+}
+
+func (p CancellationPolicy) Validate() error {
+	for _, rule := range p.Rules {
+		if rule.HoursBefore < 0 {
+			return fmt.Errorf("invalid hours before %d", rule.HoursBefore)
+		}
+		if rule.RefundPercent < 0 || rule.RefundPercent > 100 {
+			return fmt.Errorf("invalid refund percent %d", rule.RefundPercent)
+		}
+	}
+
+	return nil
+}
+
+// RefundPercent returns the share of the price refunded when canceling at now a
+// session starting at startsAt, and false when cancellation is no longer allowed.
+func (p CancellationPolicy) RefundPercent(startsAt time.Time, now time.Time) (int, bool) {
+	remaining := startsAt.Sub(now)
+
+	percent, allowed := 0, false
+	for _, rule := range p.Rules {
+		if remaining < time.Duration(rule.HoursBefore)*time.Hour {
+			continue
+		}
+		if !allowed || rule.RefundPercent > percent {
+			percent = rule.RefundPercent
+		}
+		allowed = true
+	}
+
+	return percent, allowed
+}
+
+func (p *CancellationPolicy) Scan(src interface{}) error {
+	if src == nil {
+		*p = CancellationPolicy{}
+		return nil
+	}
+	if data, ok := src.([]byte); ok {
+		return json.Unmarshal(data, p)
+	}
+	return fmt.Errorf("unsupported data type: %T", src)
+}
+
+func (p CancellationPolicy) Value() (driver.Value, error) {
+	if p.Rules == nil {
+		p.Rules = []CancellationRule{}
+	}
+	return json.Marshal(p)
+}
+
 type Address struct {
 	Id        int     `json:"id" db:"id"`
 	Address   string  `json:"address" db:"address"`
@@ -14,19 +94,21 @@ type Address struct {
 }
 
 type Cinema struct {
-	Id          int        `json:"id" db:"id"`
-	UserId      int        `json:"user_id" db:"user_id"`
-	Name        string     `json:"name" db:"name"`
-	Description string     `json:"description" db:"description"`
-	DeletedAt   *time.Time `json:"deleted_at" db:"deleted_at"`
-	Address     Address    `json:"address"`
+	Id                 int                `json:"id" db:"id"`
+	UserId             int                `json:"user_id" db:"user_id"`
+	Name               string             `json:"name" db:"name"`
+	Description        string             `json:"description" db:"description"`
+	CancellationPolicy CancellationPolicy `json:"cancellation_policy" db:"cancellation_policy"`
+	DeletedAt          *time.Time         `json:"deleted_at" db:"deleted_at"`
+	Address            Address            `json:"address"`
 }
 
 type CinemaWithRooms struct {
-	Id          int            `json:"id" db:"id"`
-	Name        string         `json:"name" db:"name"`
-	Description string         `json:"description" db:"description"`
-	DeletedAt   *time.Time     `json:"deleted_at" db:"deleted_at"`
-	Address     Address        `json:"address"`
-	Rooms       room.RoomArray `json:"rooms"`
+	Id                 int                `json:"id" db:"id"`
+	Name               string             `json:"name" db:"name"`
+	Description        string             `json:"description" db:"description"`
+	CancellationPolicy CancellationPolicy `json:"cancellation_policy" db:"cancellation_policy"`
+	DeletedAt          *time.Time         `json:"deleted_at" db:"deleted_at"`
+	Address            Address            `json:"address"`
+	Rooms              room.RoomArray     `json:"rooms"`
 }
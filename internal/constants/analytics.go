@@ -0,0 +1,5 @@
+package constants
+
+const (
+	AnalyticsEventOrderConfirmed = "order.confirmed"
+)
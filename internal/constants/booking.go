@@ -4,4 +4,25 @@ const (
 	BookingStatusPending   = "PENDING"
 	BookingStatusConfirmed = "CONFIRMED"
 	BookingStatusCanceled  = "CANCELED"
+	BookingStatusExpired   = "EXPIRED"
+)
+
+const (
+	OrderStatusPending   = "PENDING"
+	OrderStatusConfirmed = "CONFIRMED"
+	OrderStatusCanceled  = "CANCELED"
+	OrderStatusExpired   = "EXPIRED"
+)
+
+const (
+	RefundStatusRequested = "REQUESTED"
+	RefundStatusPending   = "PENDING"
+	RefundStatusSucceeded = "SUCCEEDED"
+)
+
+const (
+	SeatEventSnapshot = "seats.snapshot"
+	SeatEventHeld     = "seat.held"
+	SeatEventBooked   = "seat.booked"
+	SeatEventReleased = "seat.released"
 )
@@ -0,0 +1,7 @@
+package constants
+
+const (
+	NotificationTypeBookingConfirmed = "booking.confirmed"
+	NotificationTypeBookingCanceled  = "booking.canceled"
+	NotificationTypeSessionSoldOut   = "session.sold_out"
+)
@@ -0,0 +1,16 @@
+package constants
+
+const (
+	OutboxStatusPending   = "PENDING"
+	OutboxStatusDelivered = "DELIVERED"
+	OutboxStatusFailed    = "FAILED"
+)
+
+const (
+	OutboxTopicManagerNotification  = "manager.notification"
+	OutboxTopicConfirmationEmail    = "booking.confirmation_email"
+	OutboxTopicCancellationEmail    = "booking.cancellation_email"
+	OutboxTopicSessionCanceledEmail = "session.canceled_email"
+	OutboxTopicRefund               = "payment.refund"
+	OutboxTopicAnalytics            = "booking.analytics"
+)
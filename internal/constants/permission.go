@@ -0,0 +1,25 @@
+package constants
+
+// Permissions are granted to the members of a cinema through their staff role. Admins
+// have all of them.
+const (
+	PermissionCinemaManage    = "cinema.manage"
+	PermissionRoomsManage     = "rooms.manage"
+	PermissionEventsManage    = "events.manage"
+	PermissionTicketsValidate = "tickets.validate"
+	PermissionStaffManage     = "staff.manage"
+	// PermissionBookingsManage lets a member see, cancel and refund the bookings of the cinema.
+	PermissionBookingsManage = "bookings.manage"
+)
+
+var Permissions = []string{
+	PermissionCinemaManage,
+	PermissionRoomsManage,
+	PermissionEventsManage,
+	PermissionTicketsValidate,
+	PermissionStaffManage,
+	PermissionBookingsManage,
+}
+
+// StaffRoleManager is the built-in staff role given to the creator of a cinema.
+const StaffRoleManager = "Manager"
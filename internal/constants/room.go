@@ -5,3 +5,9 @@ const (
 	RoomTypeMedium = "MEDIUM"
 	RoomTypeLarge  = "LARGE"
 )
+
+const (
+	SeatCategoryStandard   = "STANDARD"
+	SeatCategoryPremium    = "PREMIUM"
+	SeatCategoryAccessible = "ACCESSIBLE"
+)
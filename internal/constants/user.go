@@ -5,6 +5,8 @@ type contextKey string
 const (
 	UserIDKey   contextKey = "userId"
 	UserRoleKey contextKey = "userRole"
+	// SessionIDKey is the sign in the access token of the request was issued for.
+	SessionIDKey contextKey = "sessionId"
 )
 
 const (
@@ -12,3 +14,6 @@ const (
 	UserRoleManager string = "MANAGER"
 	UserRoleViewer  string = "VIEWER"
 )
+
+// DefaultLocale is the language of the emails sent to users who did not choose one.
+const DefaultLocale = "en"
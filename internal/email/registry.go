@@ -0,0 +1,178 @@
+package email
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmlTemplate "html/template"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	textTemplate "text/template"
+)
+
+//go:embed templates
+var templates embed.FS
+
+// Message is a rendered email, ready to be addressed and sent.
+type Message struct {
+	Subject string
+	HTML    string
+	Text    string
+}
+
+type template struct {
+	html *htmlTemplate.Template
+	text *textTemplate.Template
+}
+
+// Registry holds the email templates of every locale. Each locale directory has a
+// layout.html and a layout.txt wrapping the "content" of its templates, and every
+// template defines its "subject" in both parts.
+type Registry struct {
+	defaultLocale string
+	templates     map[string]map[string]template
+}
+
+var funcs = map[string]interface{}{
+	"join":  strings.Join,
+	"money": formatMoney,
+}
+
+// formatMoney formats an amount in the smallest currency unit, e.g. 1600 as "16.00 EUR".
+func formatMoney(amount int, currency string) string {
+	return fmt.Sprintf("%d.%02d %s", amount/100, amount%100, strings.ToUpper(currency))
+}
+
+// NewRegistry parses the templates embedded in the binary.
+func NewRegistry(defaultLocale string) (*Registry, error) {
+	files, err := fs.Sub(templates, "templates")
+	if err != nil {
+		return nil, err
+	}
+
+	return NewRegistryFS(files, defaultLocale)
+}
+
+// NewRegistryFS parses the templates of files, one directory per locale.
+func NewRegistryFS(files fs.FS, defaultLocale string) (*Registry, error) {
+	registry := &Registry{
+		defaultLocale: defaultLocale,
+		templates:     make(map[string]map[string]template),
+	}
+
+	locales, err := fs.ReadDir(files, ".")
+	if err != nil {
+		return nil, err
+	}
+	for _, locale := range locales {
+		if !locale.IsDir() {
+			continue
+		}
+
+		names, err := fs.Glob(files, path.Join(locale.Name(), "*.html"))
+		if err != nil {
+			return nil, err
+		}
+
+		registry.templates[locale.Name()] = make(map[string]template)
+		for _, name := range names {
+			name = strings.TrimSuffix(path.Base(name), ".html")
+			if name == "layout" {
+				continue
+			}
+
+			html, err := htmlTemplate.New("layout.html").Funcs(funcs).ParseFS(files,
+				path.Join(locale.Name(), "layout.html"),
+				path.Join(locale.Name(), name+".html"),
+			)
+			if err != nil {
+				return nil, err
+			}
+			text, err := textTemplate.New("layout.txt").Funcs(funcs).ParseFS(files,
+				path.Join(locale.Name(), "layout.txt"),
+				path.Join(locale.Name(), name+".txt"),
+			)
+			if err != nil {
+				return nil, err
+			}
+
+			registry.templates[locale.Name()][name] = template{html: html, text: text}
+		}
+	}
+
+	if _, ok := registry.templates[defaultLocale]; !ok {
+		return nil, fmt.Errorf("no templates for the default locale %s", defaultLocale)
+	}
+
+	return registry, nil
+}
+
+// Names returns the templates available in the default locale.
+func (r *Registry) Names() []string {
+	names := []string{}
+	for name := range r.templates[r.defaultLocale] {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func (r *Registry) Locales() []string {
+	locales := []string{}
+	for locale := range r.templates {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+
+	return locales
+}
+
+// HasLocale reports whether templates exist for locale, e.g. "fr" for "fr-FR".
+func (r *Registry) HasLocale(locale string) bool {
+	_, ok := r.templates[baseLocale(locale)]
+	return ok
+}
+
+// baseLocale strips the region of a locale, "fr-FR" becomes "fr".
+func baseLocale(locale string) string {
+	locale, _, _ = strings.Cut(strings.ReplaceAll(locale, "_", "-"), "-")
+	return strings.ToLower(locale)
+}
+
+// lookup finds the template in locale, falling back to the default locale.
+func (r *Registry) lookup(name string, locale string) (template, bool) {
+	if tmpl, ok := r.templates[baseLocale(locale)][name]; ok {
+		return tmpl, true
+	}
+	tmpl, ok := r.templates[r.defaultLocale][name]
+
+	return tmpl, ok
+}
+
+// Render renders the template name in locale with data.
+func (r *Registry) Render(name string, locale string, data interface{}) (Message, error) {
+	tmpl, ok := r.lookup(name, locale)
+	if !ok {
+		return Message{}, fmt.Errorf("unknown email template %s", name)
+	}
+
+	var subject, html, text bytes.Buffer
+	if err := tmpl.text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return Message{}, err
+	}
+	if err := tmpl.html.Execute(&html, data); err != nil {
+		return Message{}, err
+	}
+	if err := tmpl.text.Execute(&text, data); err != nil {
+		return Message{}, err
+	}
+
+	return Message{
+		Subject: strings.TrimSpace(subject.String()),
+		HTML:    html.String(),
+		Text:    strings.TrimSpace(text.String()) + "\n",
+	}, nil
+}
@@ -0,0 +1,94 @@
+package email
+
+import "time"
+
+const (
+	TemplatePasswordReset       = "password_reset"
+	TemplateWelcome             = "welcome"
+	TemplateBookingConfirmation = "booking_confirmation"
+	TemplateBookingCancellation = "booking_cancellation"
+	TemplateSessionCanceled     = "session_canceled"
+	TemplateCinemaInvitation    = "cinema_invitation"
+)
+
+type PasswordResetData struct {
+	Name string
+	Code string
+	// ExpiresIn is the validity of the code in minutes.
+	ExpiresIn int
+}
+
+type WelcomeData struct {
+	Name string
+}
+
+type BookingConfirmationData struct {
+	Name     string
+	OrderId  int
+	Movie    string
+	Cinema   string
+	Address  string
+	Room     string
+	Seats    []string
+	StartsAt time.Time
+	// Total is in the smallest currency unit.
+	Total    int
+	Currency string
+}
+
+type BookingCancellationData struct {
+	Name     string
+	OrderId  int
+	Movie    string
+	Cinema   string
+	Seats    []string
+	StartsAt time.Time
+	// Refund is in the smallest currency unit, 0 when nothing is refunded.
+	Refund   int
+	Currency string
+}
+
+type SessionCanceledData struct {
+	Name     string
+	OrderId  int
+	Movie    string
+	Cinema   string
+	Seats    []string
+	StartsAt time.Time
+}
+
+type CinemaInvitationData struct {
+	InvitedBy string
+	Cinema    string
+	Role      string
+	Token     string
+	// ExpiresIn is the validity of the invitation in days.
+	ExpiresIn int
+}
+
+// Samples returns example data for every template, used to preview them.
+func Samples() map[string]interface{} {
+	startsAt := time.Date(2030, time.March, 8, 20, 30, 0, 0, time.UTC)
+
+	return map[string]interface{}{
+		TemplatePasswordReset: PasswordResetData{Name: "Jane Doe", Code: "X7K2P9QA", ExpiresIn: 15},
+		TemplateWelcome:       WelcomeData{Name: "Jane Doe"},
+		TemplateBookingConfirmation: BookingConfirmationData{
+			Name: "Jane Doe", OrderId: 42, Movie: "Dune: Part Two", Cinema: "Le Grand Rex",
+			Address: "1 Boulevard Poissonnière, 75002 Paris", Room: "1", Seats: []string{"F7", "F8"},
+			StartsAt: startsAt, Total: 2400, Currency: "eur",
+		},
+		TemplateBookingCancellation: BookingCancellationData{
+			Name: "Jane Doe", OrderId: 42, Movie: "Dune: Part Two", Cinema: "Le Grand Rex",
+			Seats: []string{"F7", "F8"}, StartsAt: startsAt, Refund: 1200, Currency: "eur",
+		},
+		TemplateSessionCanceled: SessionCanceledData{
+			Name: "Jane Doe", OrderId: 42, Movie: "Dune: Part Two", Cinema: "Le Grand Rex",
+			Seats: []string{"F7", "F8"}, StartsAt: startsAt,
+		},
+		TemplateCinemaInvitation: CinemaInvitationData{
+			InvitedBy: "Jane Doe", Cinema: "Le Grand Rex", Role: "Ticket scanner",
+			Token: "3f9c2a7e51d84b06", ExpiresIn: 7,
+		},
+	}
+}
@@ -16,6 +16,7 @@ type EventService interface {
 	Update(ctx context.Context, id int, input map[string]interface{}) error
 	Delete(ctx context.Context, id int) error
 	Restore(ctx context.Context, id int) error
+	GetCinemaId(ctx context.Context, id int) (int, error)
 }
 
 type Service struct {
@@ -151,3 +152,22 @@ func (s *Service) Restore(ctx context.Context, id int) error {
 
 	return nil
 }
+
+// GetCinemaId returns the cinema showing an event, which manages it.
+func (s *Service) GetCinemaId(ctx context.Context, id int) (int, error) {
+	cinemaId, err := s.store.FindCinemaId(id)
+	if err != nil {
+		if goErrors.Is(err, sql.ErrNoRows) {
+			return 0, errors.CustomError{
+				Key: errors.NotFound,
+				Err: err,
+			}
+		}
+		return 0, errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+
+	return cinemaId, nil
+}
@@ -10,6 +10,7 @@ import (
 type EventStore interface {
 	FindAll(pagination map[string]int, search string) ([]EventBasic, error)
 	FindById(id int) (Event, error)
+	FindCinemaId(id int) (int, error)
 	Create(input map[string]interface{}) error
 	Update(id int, input map[string]interface{}) error
 }
@@ -103,7 +104,8 @@ func (s *Store) FindById(id int) (Event, error) {
 						'room', json_build_object(
 							'id', r.id,
 							'number', r.number,
-							'type', r.type
+							'type', r.type,
+							'layout', r.layout
 						)
 					)
 				) FILTER (WHERE s.id IS NOT NULL), '[]'
@@ -124,6 +126,14 @@ func (s *Store) FindById(id int) (Event, error) {
 	return event, err
 }
 
+// FindCinemaId returns the cinema showing an event, deleted or not.
+func (s *Store) FindCinemaId(id int) (int, error) {
+	var cinemaId int
+	err := s.db.Get(&cinemaId, "SELECT cinema_id FROM events WHERE id=$1", id)
+
+	return cinemaId, err
+}
+
 func (s *Store) Create(input map[string]interface{}) error {
 	tx, err := s.db.Beginx()
 	if err != nil {
@@ -0,0 +1,122 @@
+package notification
+
+import "sync"
+
+// Publisher sends a message to every subscriber of a topic.
+type Publisher interface {
+	Publish(topic string, message []byte)
+}
+
+// Subscription receives the messages published on a topic. C is closed once the
+// subscription is closed, either by its owner or because it fell behind.
+type Subscription struct {
+	C <-chan []byte
+
+	broker *Broker
+	topic  string
+	send   chan []byte
+	once   sync.Once
+}
+
+// Close stops the subscription. It is safe to call more than once.
+func (s *Subscription) Close() {
+	s.broker.unsubscribe(s)
+}
+
+// Broker fans out messages published on a topic to the subscribers of that topic,
+// e.g. the seat updates of a session to everyone looking at its seat map.
+type Broker struct {
+	mutex       sync.Mutex
+	buffer      int
+	closed      bool
+	subscribers map[string]map[*Subscription]bool
+}
+
+// NewBroker returns a broker whose subscribers can fall up to buffer messages behind
+// before being dropped.
+func NewBroker(buffer int) *Broker {
+	return &Broker{
+		buffer:      buffer,
+		subscribers: make(map[string]map[*Subscription]bool),
+	}
+}
+
+func (b *Broker) Subscribe(topic string) *Subscription {
+	send := make(chan []byte, b.buffer)
+	subscription := &Subscription{
+		C:      send,
+		broker: b,
+		topic:  topic,
+		send:   send,
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.closed {
+		subscription.once.Do(func() { close(send) })
+		return subscription
+	}
+	if b.subscribers[topic] == nil {
+		b.subscribers[topic] = make(map[*Subscription]bool)
+	}
+	b.subscribers[topic][subscription] = true
+
+	return subscription
+}
+
+// Publish never blocks: subscribers whose buffer is full are dropped, and have to
+// subscribe again and reload what they missed.
+func (b *Broker) Publish(topic string, message []byte) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	for subscription := range b.subscribers[topic] {
+		select {
+		case subscription.send <- message:
+		default:
+			b.remove(subscription)
+		}
+	}
+}
+
+// Subscribers returns the number of subscribers of a topic.
+func (b *Broker) Subscribers(topic string) int {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return len(b.subscribers[topic])
+}
+
+// Close ends every subscription, letting the streams serving them return, and
+// refuses new ones.
+func (b *Broker) Close() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.closed = true
+	for _, subscribers := range b.subscribers {
+		for subscription := range subscribers {
+			b.remove(subscription)
+		}
+	}
+}
+
+func (b *Broker) unsubscribe(subscription *Subscription) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.remove(subscription)
+}
+
+// remove must be called with the mutex held.
+func (b *Broker) remove(subscription *Subscription) {
+	subscription.once.Do(func() {
+		subscribers := b.subscribers[subscription.topic]
+		delete(subscribers, subscription)
+		if len(subscribers) == 0 {
+			delete(b.subscribers, subscription.topic)
+		}
+		close(subscription.send)
+	})
+}
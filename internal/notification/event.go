@@ -0,0 +1,72 @@
+package notification
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// EventVersion is the version of the Event envelope. It changes whenever a field of the
+// envelope or of a payload changes in a way clients have to adapt to.
+const EventVersion = 1
+
+// Event is the envelope of every real-time message, whether pushed over a WebSocket, an
+// event stream or kept in the inbox. The ids tell clients what the event is about, so
+// they can filter events without decoding the payload, whose shape depends on Type.
+type Event struct {
+	// Id is the id of the notification the event was stored as, if any.
+	Id         int64           `json:"id,omitempty"`
+	Version    int             `json:"version"`
+	Type       string          `json:"type"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	CinemaId   int             `json:"cinema_id,omitempty"`
+	SessionId  int             `json:"session_id,omitempty"`
+	OrderId    int             `json:"order_id,omitempty"`
+	BookingIds []int           `json:"booking_ids,omitempty"`
+	Payload    json.RawMessage `json:"payload"`
+}
+
+func NewEvent(eventType string, payload interface{}) (Event, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return Event{}, err
+	}
+
+	return Event{
+		Version:    EventVersion,
+		Type:       eventType,
+		OccurredAt: time.Now(),
+		Payload:    data,
+	}, nil
+}
+
+// Decode unmarshals the payload of the event into v.
+func (e Event) Decode(v interface{}) error {
+	return json.Unmarshal(e.Payload, v)
+}
+
+func (e *Event) Scan(src interface{}) error {
+	if src == nil {
+		*e = Event{}
+		return nil
+	}
+	if data, ok := src.([]byte); ok {
+		return json.Unmarshal(data, e)
+	}
+	return fmt.Errorf("unsupported data type: %T", src)
+}
+
+// Value stores the event without its id, which is the id of the row storing it.
+func (e Event) Value() (driver.Value, error) {
+	e.Id = 0
+	return json.Marshal(e)
+}
+
+// Delivery is an event to notify a user of, queued in the outbox along with the text
+// shown in their inbox.
+type Delivery struct {
+	UserId  int    `json:"user_id"`
+	Message string `json:"message"`
+	Event   Event  `json:"event"`
+}
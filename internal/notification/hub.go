@@ -0,0 +1,197 @@
+package notification
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+type HubConfig struct {
+	// WriteWait is the time allowed to write a message to a connection.
+	WriteWait time.Duration
+	// PongWait is the time allowed to read the next pong from a connection.
+	PongWait time.Duration
+	// PingPeriod is how often connections are pinged, it must be less than PongWait.
+	PingPeriod time.Duration
+	// SendBuffer is the number of messages queued per connection. A connection too
+	// slow to keep up with its queue is closed rather than slowing down the others.
+	SendBuffer int
+	// MaxMessageSize is the largest message accepted from a connection.
+	MaxMessageSize int64
+}
+
+func DefaultHubConfig() HubConfig {
+	return HubConfig{
+		WriteWait:      10 * time.Second,
+		PongWait:       60 * time.Second,
+		PingPeriod:     54 * time.Second,
+		SendBuffer:     64,
+		MaxMessageSize: 4096,
+	}
+}
+
+// client is a WebSocket connection of a user. Only its write pump writes to conn.
+type client struct {
+	userId int
+	conn   *websocket.Conn
+	send   chan []byte
+	once   sync.Once
+}
+
+// close stops the write pump, which then closes the connection.
+func (c *client) close() {
+	c.once.Do(func() {
+		close(c.send)
+	})
+}
+
+// Hub keeps the WebSocket connections of every user, a user can be connected from
+// several tabs or devices at once.
+type Hub struct {
+	config  HubConfig
+	mutex   sync.RWMutex
+	clients map[int]map[*client]bool
+	closed  bool
+}
+
+func NewHub(config HubConfig) *Hub {
+	return &Hub{
+		config:  config,
+		clients: make(map[int]map[*client]bool),
+	}
+}
+
+// Serve registers conn for the user and pumps its messages until it is closed. It
+// blocks, and is meant to be called from the handler which upgraded the connection.
+func (h *Hub) Serve(userId int, conn *websocket.Conn) {
+	c := &client{
+		userId: userId,
+		conn:   conn,
+		send:   make(chan []byte, h.config.SendBuffer),
+	}
+
+	h.mutex.Lock()
+	if h.closed {
+		h.mutex.Unlock()
+		conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, ""), time.Now().Add(h.config.WriteWait))
+		conn.Close()
+		return
+	}
+	if h.clients[userId] == nil {
+		h.clients[userId] = make(map[*client]bool)
+	}
+	h.clients[userId][c] = true
+	h.mutex.Unlock()
+
+	go h.writePump(c)
+	h.readPump(c)
+}
+
+func (h *Hub) unregister(c *client) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if clients, ok := h.clients[c.userId]; ok {
+		delete(clients, c)
+		if len(clients) == 0 {
+			delete(h.clients, c.userId)
+		}
+	}
+	c.close()
+}
+
+// readPump discards incoming messages and keeps the connection alive with the pongs
+// answering the pings of the write pump.
+func (h *Hub) readPump(c *client) {
+	defer h.unregister(c)
+
+	c.conn.SetReadLimit(h.config.MaxMessageSize)
+	c.conn.SetReadDeadline(time.Now().Add(h.config.PongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(h.config.PongWait))
+	})
+
+	for {
+		if _, _, err := c.conn.ReadMessage(); err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure) {
+				log.Printf("❌ WebSocket read error for user %d: %v", c.userId, err)
+			}
+			return
+		}
+	}
+}
+
+func (h *Hub) writePump(c *client) {
+	ticker := time.NewTicker(h.config.PingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
+	}()
+
+	for {
+		select {
+		case message, ok := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(h.config.WriteWait))
+			if !ok {
+				c.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, ""))
+				return
+			}
+			if err := c.conn.WriteMessage(websocket.TextMessage, message); err != nil {
+				return
+			}
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(h.config.WriteWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// Send queues message on every connection of the user and returns how many
+// connections it was queued on.
+func (h *Hub) Send(userId int, message []byte) int {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	sent := 0
+	for c := range h.clients[userId] {
+		select {
+		case c.send <- message:
+			sent++
+		default:
+			log.Printf("❌ Dropping slow WebSocket connection of user %d", userId)
+			delete(h.clients[userId], c)
+			c.close()
+		}
+	}
+	if len(h.clients[userId]) == 0 {
+		delete(h.clients, userId)
+	}
+
+	return sent
+}
+
+// Connections returns the number of open connections of the user.
+func (h *Hub) Connections(userId int) int {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	return len(h.clients[userId])
+}
+
+// Close closes every connection and refuses new ones.
+func (h *Hub) Close() {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.closed = true
+	for userId, clients := range h.clients {
+		for c := range clients {
+			c.close()
+		}
+		delete(h.clients, userId)
+	}
+}
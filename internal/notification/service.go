@@ -0,0 +1,187 @@
+package notification
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	goErrors "errors"
+	"fmt"
+	"time"
+
+	"github.com/cinema-booker/internal/constants"
+	"github.com/cinema-booker/internal/outbox"
+	"github.com/cinema-booker/pkg/errors"
+)
+
+type NotificationService interface {
+	Notify(userId int, message string, event Event) (Notification, error)
+	Deliver(message outbox.Message) error
+	GetAfter(ctx context.Context, afterId int64, limit int) ([]Notification, error)
+	GetAll(ctx context.Context, pagination map[string]int, unreadOnly bool, notificationType string) ([]Notification, error)
+	CountUnread(ctx context.Context) (int, error)
+	MarkRead(ctx context.Context, id int64) (Notification, error)
+	MarkAllRead(ctx context.Context) (int64, error)
+}
+
+type Service struct {
+	store     NotificationStore
+	hub       *Hub
+	publisher Publisher
+}
+
+func NewService(store NotificationStore, hub *Hub, publisher Publisher) *Service {
+	return &Service{
+		store:     store,
+		hub:       hub,
+		publisher: publisher,
+	}
+}
+
+// Notify stores a notification in the user's inbox, then pushes it to the WebSocket
+// connections and event streams the user has open. Users who are not connected find it
+// in their inbox when they come back, so being offline is not an error.
+func (s *Service) Notify(userId int, message string, event Event) (Notification, error) {
+	notification, err := s.store.Create(userId, message, event)
+	if err != nil {
+		return notification, errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+
+	data, err := json.Marshal(notification.Event)
+	if err != nil {
+		return notification, errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+	s.publisher.Publish(UserTopic(userId), data)
+	s.hub.Send(userId, data)
+
+	return notification, nil
+}
+
+// Deliver notifies the recipient of a Delivery queued in the outbox.
+func (s *Service) Deliver(message outbox.Message) error {
+	var delivery Delivery
+	if err := message.Decode(&delivery); err != nil {
+		return err
+	}
+	if delivery.UserId == 0 {
+		return fmt.Errorf("notification %s has no recipient", delivery.Event.Type)
+	}
+
+	_, err := s.Notify(delivery.UserId, delivery.Message, delivery.Event)
+
+	return err
+}
+
+// GetAfter returns up to limit notifications of the authenticated user logged after afterId.
+func (s *Service) GetAfter(ctx context.Context, afterId int64, limit int) ([]Notification, error) {
+	userId, ok := ctx.Value(constants.UserIDKey).(int)
+	if !ok {
+		return nil, errors.CustomError{
+			Key: errors.Unauthorized,
+			Err: goErrors.New("user id not authenticated"),
+		}
+	}
+
+	notifications, err := s.store.FindAfter(userId, afterId, limit)
+	if err != nil {
+		return nil, errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+
+	return notifications, nil
+}
+
+func (s *Service) GetAll(ctx context.Context, pagination map[string]int, unreadOnly bool, notificationType string) ([]Notification, error) {
+	userId, ok := ctx.Value(constants.UserIDKey).(int)
+	if !ok {
+		return nil, errors.CustomError{
+			Key: errors.Unauthorized,
+			Err: goErrors.New("user id not authenticated"),
+		}
+	}
+
+	notifications, err := s.store.FindAll(userId, pagination, unreadOnly, notificationType)
+	if err != nil {
+		return nil, errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+
+	return notifications, nil
+}
+
+func (s *Service) CountUnread(ctx context.Context) (int, error) {
+	userId, ok := ctx.Value(constants.UserIDKey).(int)
+	if !ok {
+		return 0, errors.CustomError{
+			Key: errors.Unauthorized,
+			Err: goErrors.New("user id not authenticated"),
+		}
+	}
+
+	count, err := s.store.CountUnread(userId)
+	if err != nil {
+		return 0, errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+
+	return count, nil
+}
+
+// MarkRead marks one of the authenticated user's notifications as read. Notifications
+// of other users are reported as not found.
+func (s *Service) MarkRead(ctx context.Context, id int64) (Notification, error) {
+	userId, ok := ctx.Value(constants.UserIDKey).(int)
+	if !ok {
+		return Notification{}, errors.CustomError{
+			Key: errors.Unauthorized,
+			Err: goErrors.New("user id not authenticated"),
+		}
+	}
+
+	notification, err := s.store.MarkRead(userId, id, time.Now())
+	if err != nil {
+		if goErrors.Is(err, sql.ErrNoRows) {
+			return notification, errors.CustomError{
+				Key: errors.NotFound,
+				Err: err,
+			}
+		}
+		return notification, errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+
+	return notification, nil
+}
+
+func (s *Service) MarkAllRead(ctx context.Context) (int64, error) {
+	userId, ok := ctx.Value(constants.UserIDKey).(int)
+	if !ok {
+		return 0, errors.CustomError{
+			Key: errors.Unauthorized,
+			Err: goErrors.New("user id not authenticated"),
+		}
+	}
+
+	count, err := s.store.MarkAllRead(userId, time.Now())
+	if err != nil {
+		return 0, errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+
+	return count, nil
+}
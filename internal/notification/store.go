@@ -0,0 +1,106 @@
+package notification
+
+import (
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type NotificationStore interface {
+	Create(userId int, message string, event Event) (Notification, error)
+	FindAfter(userId int, afterId int64, limit int) ([]Notification, error)
+	FindAll(userId int, pagination map[string]int, unreadOnly bool, notificationType string) ([]Notification, error)
+	CountUnread(userId int) (int, error)
+	MarkRead(userId int, id int64, readAt time.Time) (Notification, error)
+	MarkAllRead(userId int, readAt time.Time) (int64, error)
+}
+
+type Store struct {
+	db *sqlx.DB
+}
+
+func NewStore(db *sqlx.DB) *Store {
+	return &Store{
+		db: db,
+	}
+}
+
+// withEventIds sets the id of the event stored in each notification to the notification's.
+func withEventIds(notifications []Notification) []Notification {
+	for i := range notifications {
+		notifications[i].Event.Id = notifications[i].Id
+	}
+
+	return notifications
+}
+
+func (s *Store) Create(userId int, message string, event Event) (Notification, error) {
+	notification := Notification{}
+	err := s.db.Get(
+		&notification,
+		"INSERT INTO notifications (user_id, type, message, event) VALUES ($1, $2, $3, $4) RETURNING *",
+		userId, event.Type, message, event,
+	)
+	notification.Event.Id = notification.Id
+
+	return notification, err
+}
+
+// FindAfter returns up to limit notifications of the user logged after afterId, oldest first.
+func (s *Store) FindAfter(userId int, afterId int64, limit int) ([]Notification, error) {
+	notifications := []Notification{}
+	err := s.db.Select(
+		&notifications,
+		"SELECT * FROM notifications WHERE user_id=$1 AND id > $2 ORDER BY id LIMIT $3",
+		userId, afterId, limit,
+	)
+
+	return withEventIds(notifications), err
+}
+
+// FindAll returns a page of the user's inbox, newest first, optionally keeping only
+// the unread notifications or those of one type.
+func (s *Store) FindAll(userId int, pagination map[string]int, unreadOnly bool, notificationType string) ([]Notification, error) {
+	notifications := []Notification{}
+
+	offset := (pagination["page"] - 1) * pagination["limit"]
+	query := "SELECT * FROM notifications WHERE user_id=$1 AND ($2 = '' OR type = $2)"
+	if unreadOnly {
+		query += " AND read_at IS NULL"
+	}
+	query += " ORDER BY id DESC LIMIT $3 OFFSET $4"
+
+	err := s.db.Select(&notifications, query, userId, notificationType, pagination["limit"], offset)
+
+	return withEventIds(notifications), err
+}
+
+func (s *Store) CountUnread(userId int) (int, error) {
+	var count int
+	err := s.db.Get(&count, "SELECT COUNT(*) FROM notifications WHERE user_id=$1 AND read_at IS NULL", userId)
+
+	return count, err
+}
+
+// MarkRead marks a notification of the user as read, keeping the time it was first read.
+func (s *Store) MarkRead(userId int, id int64, readAt time.Time) (Notification, error) {
+	notification := Notification{}
+	err := s.db.Get(
+		&notification,
+		"UPDATE notifications SET read_at=COALESCE(read_at, $1) WHERE id=$2 AND user_id=$3 RETURNING *",
+		readAt, id, userId,
+	)
+	notification.Event.Id = notification.Id
+
+	return notification, err
+}
+
+// MarkAllRead marks every unread notification of the user as read and returns how many there were.
+func (s *Store) MarkAllRead(userId int, readAt time.Time) (int64, error) {
+	result, err := s.db.Exec("UPDATE notifications SET read_at=$1 WHERE user_id=$2 AND read_at IS NULL", readAt, userId)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
@@ -0,0 +1,70 @@
+package notification
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+type connectionTicket struct {
+	userId    int
+	expiresAt time.Time
+}
+
+// Tickets issues single-use tickets letting browsers open an authenticated WebSocket,
+// since they cannot set the Authorization header of the upgrade request.
+type Tickets struct {
+	mutex   sync.Mutex
+	ttl     time.Duration
+	tickets map[string]connectionTicket
+}
+
+func NewTickets(ttl time.Duration) *Tickets {
+	return &Tickets{
+		ttl:     ttl,
+		tickets: make(map[string]connectionTicket),
+	}
+}
+
+// Issue returns a new ticket for the user, valid once within the ticket TTL.
+func (t *Tickets) Issue(userId int) (string, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	ticket := hex.EncodeToString(bytes)
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	now := time.Now()
+	for id, issued := range t.tickets {
+		if now.After(issued.expiresAt) {
+			delete(t.tickets, id)
+		}
+	}
+	t.tickets[ticket] = connectionTicket{
+		userId:    userId,
+		expiresAt: now.Add(t.ttl),
+	}
+
+	return ticket, nil
+}
+
+// Redeem consumes a ticket and returns the user it was issued to.
+func (t *Tickets) Redeem(ticket string) (int, bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	issued, ok := t.tickets[ticket]
+	if !ok {
+		return 0, false
+	}
+	delete(t.tickets, ticket)
+	if time.Now().After(issued.expiresAt) {
+		return 0, false
+	}
+
+	return issued.userId, true
+}
@@ -0,0 +1,23 @@
+package notification
+
+import (
+	"fmt"
+	"time"
+)
+
+// Notification is a message sent to a user. Notifications are kept in the user's inbox
+// until read, so that users who were offline can see what happened while they were away.
+type Notification struct {
+	Id        int64      `json:"id" db:"id"`
+	UserId    int        `json:"user_id" db:"user_id"`
+	Type      string     `json:"type" db:"type"`
+	Message   string     `json:"message" db:"message"`
+	Event     Event      `json:"event" db:"event"`
+	ReadAt    *time.Time `json:"read_at" db:"read_at"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+}
+
+// UserTopic is the topic the notifications of a user are published on.
+func UserTopic(userId int) string {
+	return fmt.Sprintf("user.%d.notifications", userId)
+}
@@ -0,0 +1,115 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Handler delivers a message. A returned error schedules a retry.
+type Handler func(message Message) error
+
+type DispatcherConfig struct {
+	// BatchSize is the number of messages claimed on each tick.
+	BatchSize int
+	// MaxAttempts is the number of deliveries tried before a message is marked FAILED.
+	MaxAttempts int
+	// Backoff is the delay before the first retry, doubled after every failed attempt.
+	Backoff time.Duration
+	// MaxBackoff caps the delay between two attempts.
+	MaxBackoff time.Duration
+	// Lease is how long a claimed message stays hidden from other dispatchers.
+	Lease time.Duration
+}
+
+type Dispatcher struct {
+	store    OutboxStore
+	config   DispatcherConfig
+	handlers map[string]Handler
+}
+
+func NewDispatcher(store OutboxStore, config DispatcherConfig) *Dispatcher {
+	return &Dispatcher{
+		store:    store,
+		config:   config,
+		handlers: make(map[string]Handler),
+	}
+}
+
+// Handle registers the handler delivering the messages of topic.
+func (d *Dispatcher) Handle(topic string, handler Handler) {
+	d.handlers[topic] = handler
+}
+
+// backoff returns the delay before retrying a message that failed attempts times.
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	delay := d.config.Backoff
+	for i := 1; i < attempts && delay < d.config.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > d.config.MaxBackoff {
+		delay = d.config.MaxBackoff
+	}
+
+	return delay
+}
+
+// Dispatch delivers the messages that are due and returns how many were delivered.
+func (d *Dispatcher) Dispatch(now time.Time) (int, error) {
+	messages, err := d.store.Claim(now, d.config.Lease, d.config.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	delivered := 0
+	for _, message := range messages {
+		err := d.deliver(message)
+		if err == nil {
+			if err := d.store.MarkDelivered(message.Id, time.Now()); err != nil {
+				return delivered, err
+			}
+			delivered++
+			continue
+		}
+
+		var retryAt *time.Time
+		if message.Attempts < d.config.MaxAttempts {
+			at := time.Now().Add(d.backoff(message.Attempts))
+			retryAt = &at
+		}
+		log.Printf("❌ Error delivering outbox message %d (%s), attempt %d: %v", message.Id, message.Topic, message.Attempts, err)
+
+		if err := d.store.MarkFailed(message.Id, err.Error(), retryAt); err != nil {
+			return delivered, err
+		}
+	}
+
+	return delivered, nil
+}
+
+func (d *Dispatcher) deliver(message Message) error {
+	handler, ok := d.handlers[message.Topic]
+	if !ok {
+		return fmt.Errorf("no handler for topic %s", message.Topic)
+	}
+
+	return handler(message)
+}
+
+// Run dispatches due messages every interval until ctx is done.
+func (d *Dispatcher) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := d.Dispatch(time.Now()); err != nil {
+				log.Printf("❌ Error dispatching outbox messages: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package outbox
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/cinema-booker/internal/constants"
+	"github.com/jmoiron/sqlx"
+)
+
+type OutboxStore interface {
+	Claim(now time.Time, lease time.Duration, limit int) ([]Message, error)
+	MarkDelivered(id int, deliveredAt time.Time) error
+	MarkFailed(id int, lastError string, retryAt *time.Time) error
+}
+
+type Store struct {
+	db *sqlx.DB
+}
+
+func NewStore(db *sqlx.DB) *Store {
+	return &Store{
+		db: db,
+	}
+}
+
+// Add records a message in the transaction of the state change that caused it, so
+// the message exists if and only if the change was committed.
+func Add(tx *sqlx.Tx, topic string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("INSERT INTO outbox (topic, payload) VALUES ($1, $2)", topic, data)
+
+	return err
+}
+
+// Claim returns up to limit pending messages that are due and hides them from other
+// dispatchers for the duration of the lease.
+func (s *Store) Claim(now time.Time, lease time.Duration, limit int) ([]Message, error) {
+	messages := []Message{}
+	query := `
+		UPDATE outbox
+		SET attempts = attempts + 1, available_at = $1
+		WHERE id IN (
+			SELECT id
+			FROM outbox
+			WHERE status = $2 AND available_at <= $3
+			ORDER BY id
+			LIMIT $4
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *
+	`
+	err := s.db.Select(&messages, query, now.Add(lease), constants.OutboxStatusPending, now, limit)
+
+	return messages, err
+}
+
+func (s *Store) MarkDelivered(id int, deliveredAt time.Time) error {
+	_, err := s.db.Exec(
+		"UPDATE outbox SET status=$1, delivered_at=$2, last_error=NULL WHERE id=$3",
+		constants.OutboxStatusDelivered, deliveredAt, id,
+	)
+
+	return err
+}
+
+// MarkFailed records a failed delivery. The message is retried at retryAt, or given
+// up on when retryAt is nil.
+func (s *Store) MarkFailed(id int, lastError string, retryAt *time.Time) error {
+	if retryAt == nil {
+		_, err := s.db.Exec(
+			"UPDATE outbox SET status=$1, last_error=$2 WHERE id=$3",
+			constants.OutboxStatusFailed, lastError, id,
+		)
+		return err
+	}
+
+	_, err := s.db.Exec(
+		"UPDATE outbox SET last_error=$1, available_at=$2 WHERE id=$3",
+		lastError, *retryAt, id,
+	)
+
+	return err
+}
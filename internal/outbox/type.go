@@ -0,0 +1,25 @@
+package outbox
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Message is a side effect recorded in the same transaction as the state change
+// that caused it, and delivered later by the Dispatcher.
+type Message struct {
+	Id          int             `json:"id" db:"id"`
+	Topic       string          `json:"topic" db:"topic"`
+	Payload     json.RawMessage `json:"payload" db:"payload"`
+	Status      string          `json:"status" db:"status"`
+	Attempts    int             `json:"attempts" db:"attempts"`
+	LastError   *string         `json:"last_error" db:"last_error"`
+	AvailableAt time.Time       `json:"available_at" db:"available_at"`
+	CreatedAt   time.Time       `json:"created_at" db:"created_at"`
+	DeliveredAt *time.Time      `json:"delivered_at" db:"delivered_at"`
+}
+
+// Decode unmarshals the payload of the message into v.
+func (m Message) Decode(v interface{}) error {
+	return json.Unmarshal(m.Payload, v)
+}
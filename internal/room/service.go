@@ -10,7 +10,9 @@ import (
 )
 
 type RoomService interface {
+	Get(ctx context.Context, cinemaId int, id int) (Room, error)
 	Create(ctx context.Context, cinemaId int, input map[string]interface{}) error
+	UpdateLayout(ctx context.Context, cinemaId int, id int, input map[string]interface{}) error
 	Delete(ctx context.Context, cinemaId int, id int) error
 }
 
@@ -24,9 +26,90 @@ func NewService(store RoomStore) *Service {
 	}
 }
 
+func (s *Service) Get(ctx context.Context, cinemaId int, id int) (Room, error) {
+	room, err := s.store.FindById(cinemaId, id)
+	if err != nil {
+		if goErrors.Is(err, sql.ErrNoRows) {
+			return room, errors.CustomError{
+				Key: errors.NotFound,
+				Err: err,
+			}
+		}
+		return room, errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+
+	return room, nil
+}
+
 func (s *Service) Create(ctx context.Context, cinemaId int, input map[string]interface{}) error {
+	if input["layout"] == nil {
+		return errors.CustomError{
+			Key: errors.BadRequest,
+			Err: goErrors.New("layout is required"),
+		}
+	}
+	layout, err := ParseLayout(input["layout"])
+	if err != nil {
+		return errors.CustomError{
+			Key: errors.BadRequest,
+			Err: err,
+		}
+	}
+	if err := layout.Validate(); err != nil {
+		return errors.CustomError{
+			Key: errors.BadRequest,
+			Err: err,
+		}
+	}
+
 	input["cinema_id"] = cinemaId
-	err := s.store.Create(input)
+	input["layout"] = layout
+	err = s.store.Create(input)
+	if err != nil {
+		return errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+
+	return nil
+}
+
+func (s *Service) UpdateLayout(ctx context.Context, cinemaId int, id int, input map[string]interface{}) error {
+	_, err := s.store.FindById(cinemaId, id)
+	if err != nil {
+		if goErrors.Is(err, sql.ErrNoRows) {
+			return errors.CustomError{
+				Key: errors.NotFound,
+				Err: err,
+			}
+		}
+		return errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+
+	layout, err := ParseLayout(input)
+	if err != nil {
+		return errors.CustomError{
+			Key: errors.BadRequest,
+			Err: err,
+		}
+	}
+	if err := layout.Validate(); err != nil {
+		return errors.CustomError{
+			Key: errors.BadRequest,
+			Err: err,
+		}
+	}
+
+	err = s.store.Update(id, map[string]interface{}{
+		"layout": layout,
+	})
 	if err != nil {
 		return errors.CustomError{
 			Key: errors.InternalServerError,
@@ -38,7 +121,7 @@ func (s *Service) Create(ctx context.Context, cinemaId int, input map[string]int
 }
 
 func (s *Service) Delete(ctx context.Context, cinemaId int, id int) error {
-	_, err := s.store.FindById(id)
+	_, err := s.store.FindById(cinemaId, id)
 	if err != nil {
 		if goErrors.Is(err, sql.ErrNoRows) {
 			return errors.CustomError{
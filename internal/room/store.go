@@ -8,7 +8,7 @@ import (
 )
 
 type RoomStore interface {
-	FindById(id int) (Room, error)
+	FindById(cinemaId int, id int) (Room, error)
 	Create(input map[string]interface{}) error
 	Update(id int, input map[string]interface{}) error
 }
@@ -23,17 +23,17 @@ func NewStore(db *sqlx.DB) *Store {
 	}
 }
 
-func (s *Store) FindById(id int) (Room, error) {
+func (s *Store) FindById(cinemaId int, id int) (Room, error) {
 	room := Room{}
-	query := "SELECT id, number, type FROM rooms WHERE id=$1"
-	err := s.db.Get(&room, query, id)
+	query := "SELECT id, number, type, layout FROM rooms WHERE id=$1 AND cinema_id=$2"
+	err := s.db.Get(&room, query, id, cinemaId)
 
 	return room, err
 }
 
 func (s *Store) Create(input map[string]interface{}) error {
-	query := "INSERT INTO rooms (cinema_id, number, type) VALUES ($1, $2, $3)"
-	_, err := s.db.Exec(query, input["cinema_id"], input["number"], input["type"])
+	query := "INSERT INTO rooms (cinema_id, number, type, layout) VALUES ($1, $2, $3, $4)"
+	_, err := s.db.Exec(query, input["cinema_id"], input["number"], input["type"], input["layout"])
 
 	return err
 }
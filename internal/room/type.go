@@ -4,12 +4,154 @@ import (
 	"database/sql/driver"
 	"encoding/json"
 	"fmt"
+
+	"github.com/cinema-booker/internal/constants"
 )
 
+type Seat struct {
+	Number   int    `json:"number"`
+	Category string `json:"category"`
+	Blocked  bool   `json:"blocked"`
+}
+
+type Row struct {
+	Label string `json:"label"`
+	Seats []Seat `json:"seats"`
+	// Aisles holds the seat numbers directly followed by an aisle.
+	Aisles []int `json:"aisles"`
+}
+
+type Layout struct {
+	Rows []Row `json:"rows"`
+}
+
+// ParseLayout converts a decoded JSON request value into a Layout.
+func ParseLayout(v interface{}) (Layout, error) {
+	layout := Layout{}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return layout, err
+	}
+	if err := json.Unmarshal(data, &layout); err != nil {
+		return layout, err
+	}
+
+	return layout, nil
+}
+
+// SeatCode returns the identifier stored in bookings.place, e.g. "A12".
+func SeatCode(row string, number int) string {
+	return fmt.Sprintf("%s%d", row, number)
+}
+
+func (l Layout) Validate() error {
+	if len(l.Rows) == 0 {
+		return fmt.Errorf("layout must have at least one row")
+	}
+
+	rows := make(map[string]bool, len(l.Rows))
+	for _, row := range l.Rows {
+		if row.Label == "" {
+			return fmt.Errorf("row label is required")
+		}
+		if rows[row.Label] {
+			return fmt.Errorf("duplicate row %s", row.Label)
+		}
+		rows[row.Label] = true
+
+		numbers := make(map[int]bool, len(row.Seats))
+		for _, seat := range row.Seats {
+			if seat.Number <= 0 {
+				return fmt.Errorf("invalid seat number %d in row %s", seat.Number, row.Label)
+			}
+			if numbers[seat.Number] {
+				return fmt.Errorf("duplicate seat %s", SeatCode(row.Label, seat.Number))
+			}
+			numbers[seat.Number] = true
+
+			switch seat.Category {
+			case constants.SeatCategoryStandard, constants.SeatCategoryPremium, constants.SeatCategoryAccessible:
+			default:
+				return fmt.Errorf("invalid category %q for seat %s", seat.Category, SeatCode(row.Label, seat.Number))
+			}
+		}
+
+		for _, aisle := range row.Aisles {
+			if !numbers[aisle] {
+				return fmt.Errorf("aisle after unknown seat %s", SeatCode(row.Label, aisle))
+			}
+		}
+	}
+
+	if l.Capacity() == 0 {
+		return fmt.Errorf("layout must have at least one bookable seat")
+	}
+
+	return nil
+}
+
+func (l Layout) Seat(code string) (Seat, bool) {
+	for _, row := range l.Rows {
+		for _, seat := range row.Seats {
+			if SeatCode(row.Label, seat.Number) == code {
+				return seat, true
+			}
+		}
+	}
+
+	return Seat{}, false
+}
+
+// UnavailableSeats returns the codes that do not exist in the layout or are blocked.
+func (l Layout) UnavailableSeats(codes []string) []string {
+	unavailable := []string{}
+	for _, code := range codes {
+		seat, ok := l.Seat(code)
+		if !ok || seat.Blocked {
+			unavailable = append(unavailable, code)
+		}
+	}
+
+	return unavailable
+}
+
+func (l Layout) Capacity() int {
+	capacity := 0
+	for _, row := range l.Rows {
+		for _, seat := range row.Seats {
+			if !seat.Blocked {
+				capacity++
+			}
+		}
+	}
+
+	return capacity
+}
+
+func (l *Layout) Scan(src interface{}) error {
+	if src == nil {
+		*l = Layout{}
+		return nil
+	}
+	if data, ok := src.([]byte); ok {
+		return json.Unmarshal(data, l)
+	}
+	return fmt.Errorf("unsupported data type: %T", src)
+}
+
+func (l Layout) Value() (driver.Value, error) {
+	if l.Rows == nil {
+		l.Rows = []Row{}
+	}
+	return json.Marshal(l)
+}
+
 type Room struct {
 	Id     int    `json:"id" db:"id"`
 	Number string `json:"number" db:"number"`
 	Type   string `json:"type" db:"type"`
+	Layout Layout `json:"layout" db:"layout"`
 }
 
 type RoomArray []Room
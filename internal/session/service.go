@@ -4,30 +4,59 @@ import (
 	"context"
 	"database/sql"
 	goErrors "errors"
-	"time"
+	"fmt"
 
+	"github.com/cinema-booker/internal/constants"
 	"github.com/cinema-booker/pkg/errors"
 )
 
 type SessionService interface {
 	Create(ctx context.Context, eventId int, input map[string]interface{}) error
 	Delete(ctx context.Context, eventId int, id int) error
-	GetDashboardData() (FlatDashboardResponse, error)
+	GetDashboardData(ctx context.Context) (FlatDashboardResponse, error)
+}
+
+// SessionCanceler cancels and refunds the orders of a session canceled by its cinema.
+type SessionCanceler interface {
+	CancelSession(id int) error
 }
 
 type Service struct {
-	store SessionStore
+	store    SessionStore
+	bookings SessionCanceler
 }
 
-func NewService(store SessionStore) *Service {
+func NewService(store SessionStore, bookings SessionCanceler) *Service {
 	return &Service{
-		store: store,
+		store:    store,
+		bookings: bookings,
 	}
 }
 
 func (s *Service) Create(ctx context.Context, eventId int, input map[string]interface{}) error {
+	roomId, ok := input["room_id"].(float64)
+	if !ok {
+		return errors.CustomError{
+			Key: errors.BadRequest,
+			Err: goErrors.New("room_id is required"),
+		}
+	}
+	hasRoom, err := s.store.HasRoom(eventId, int(roomId))
+	if err != nil {
+		return errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+	if !hasRoom {
+		return errors.CustomError{
+			Key: errors.BadRequest,
+			Err: fmt.Errorf("room %d is not in the cinema of event %d", int(roomId), eventId),
+		}
+	}
+
 	input["event_id"] = eventId
-	err := s.store.Create(input)
+	err = s.store.Create(input)
 	if err != nil {
 		return errors.CustomError{
 			Key: errors.InternalServerError,
@@ -39,7 +68,10 @@ func (s *Service) Create(ctx context.Context, eventId int, input map[string]inte
 }
 
 func (s *Service) Delete(ctx context.Context, eventId int, id int) error {
-	_, err := s.store.FindById(id)
+	sessionEventId, err := s.store.FindEventId(id)
+	if err == nil && sessionEventId != eventId {
+		err = sql.ErrNoRows
+	}
 	if err != nil {
 		if goErrors.Is(err, sql.ErrNoRows) {
 			return errors.CustomError{
@@ -53,19 +85,33 @@ func (s *Service) Delete(ctx context.Context, eventId int, id int) error {
 		}
 	}
 
-	err = s.store.Update(id, map[string]interface{}{
-		"deleted_at": time.Now(),
-	})
-	if err != nil {
-		return errors.CustomError{
-			Key: errors.InternalServerError,
-			Err: err,
+	return s.bookings.CancelSession(id)
+}
+
+// GetDashboardData sums up the activity of every cinema for admins, and of the cinemas
+// where they manage bookings for the others.
+func (s *Service) GetDashboardData(ctx context.Context) (FlatDashboardResponse, error) {
+	userId, ok := ctx.Value(constants.UserIDKey).(int)
+	if !ok {
+		return FlatDashboardResponse{}, errors.CustomError{
+			Key: errors.Unauthorized,
+			Err: goErrors.New("user id not authenticated"),
 		}
 	}
+	if userRole, _ := ctx.Value(constants.UserRoleKey).(string); userRole == constants.UserRoleAdmin {
+		userId = 0
+	}
 
-	return nil
-}
+	response, err := s.store.GetDashboardData(userId)
+	if err != nil {
+		return response, err
+	}
+	if userId != 0 && response.TotalCinemas == 0 {
+		return FlatDashboardResponse{}, errors.CustomError{
+			Key: errors.Forbidden,
+			Err: fmt.Errorf("permission %s required in a cinema", constants.PermissionBookingsManage),
+		}
+	}
 
-func (s *Service) GetDashboardData() (FlatDashboardResponse, error) {
-	return s.store.GetDashboardData()
+	return response, nil
 }
@@ -5,14 +5,17 @@ import (
 	"github.com/cinema-booker/pkg/errors"
 	"strings"
 
+	"github.com/cinema-booker/internal/constants"
 	"github.com/jmoiron/sqlx"
 )
 
 type SessionStore interface {
 	FindById(id int) (Session, error)
+	FindEventId(id int) (int, error)
+	HasRoom(eventId int, roomId int) (bool, error)
 	Create(input map[string]interface{}) error
 	Update(id int, input map[string]interface{}) error
-	GetDashboardData() (FlatDashboardResponse, error)
+	GetDashboardData(userId int) (FlatDashboardResponse, error)
 }
 
 type Store struct {
@@ -35,7 +38,8 @@ func (s *Store) FindById(id int) (Session, error) {
 			s.deleted_at AS deleted_at,
       r.id AS "room.id",
       r.number AS "room.number",
-      r.type AS "room.type"
+      r.type AS "room.type",
+      r.layout AS "room.layout"
     FROM sessions s
     LEFT JOIN rooms r ON s.room_id = r.id
 		WHERE s.id=$1
@@ -45,6 +49,29 @@ func (s *Store) FindById(id int) (Session, error) {
 	return session, err
 }
 
+func (s *Store) FindEventId(id int) (int, error) {
+	var eventId int
+	err := s.db.Get(&eventId, "SELECT event_id FROM sessions WHERE id=$1", id)
+
+	return eventId, err
+}
+
+// HasRoom reports whether a room belongs to the cinema showing an event.
+func (s *Store) HasRoom(eventId int, roomId int) (bool, error) {
+	var exists bool
+	query := `
+		SELECT EXISTS (
+			SELECT 1
+			FROM rooms r
+			JOIN events e ON e.cinema_id = r.cinema_id
+			WHERE e.id=$1 AND r.id=$2 AND r.deleted_at IS NULL
+		)
+	`
+	err := s.db.Get(&exists, query, eventId, roomId)
+
+	return exists, err
+}
+
 func (s *Store) Create(input map[string]interface{}) error {
 	query := "INSERT INTO sessions (event_id, room_id, price, starts_at) VALUES ($1, $2, $3, $4)"
 	_, err := s.db.Exec(query, input["event_id"], input["room_id"], input["price"], input["starts_at"])
@@ -66,12 +93,31 @@ func (s *Store) Update(id int, input map[string]interface{}) error {
 	return err
 }
 
-func (s *Store) GetDashboardData() (FlatDashboardResponse, error) {
+// memberCinemas restricts a dashboard query to the cinemas c where the user $1 has the
+// permission to manage bookings. $1 is 0 for admins, who see every cinema.
+const memberCinemas = `($1 = 0 OR c.id IN (
+	SELECT m.cinema_id
+	FROM cinema_members m
+	JOIN staff_roles r ON m.role_id = r.id
+	WHERE m.user_id = $1 AND '` + constants.PermissionBookingsManage + `' = ANY(r.permissions)
+))`
+
+// cinemaBookings joins the bookings b to their cinema c.
+const cinemaBookings = `
+	FROM bookings b
+	JOIN sessions s ON b.session_id = s.id
+	JOIN events e ON s.event_id = e.id
+	JOIN cinemas c ON e.cinema_id = c.id
+`
+
+// GetDashboardData sums up the activity of the cinemas where a user manages bookings, or
+// of every cinema when userId is 0.
+func (s *Store) GetDashboardData(userId int) (FlatDashboardResponse, error) {
 	var response FlatDashboardResponse
 	var err error
 
 	// Total Bookings
-	err = s.db.Get(&response.TotalBookings, "SELECT COUNT(*) FROM bookings")
+	err = s.db.Get(&response.TotalBookings, "SELECT COUNT(*)"+cinemaBookings+"WHERE "+memberCinemas, userId)
 	if err != nil {
 		return response, errors.CustomError{
 			Key: errors.InternalServerError,
@@ -80,7 +126,7 @@ func (s *Store) GetDashboardData() (FlatDashboardResponse, error) {
 	}
 
 	// Total Cinemas
-	err = s.db.Get(&response.TotalCinemas, "SELECT COUNT(*) FROM cinemas")
+	err = s.db.Get(&response.TotalCinemas, "SELECT COUNT(*) FROM cinemas c WHERE "+memberCinemas, userId)
 	if err != nil {
 		return response, errors.CustomError{
 			Key: errors.InternalServerError,
@@ -89,7 +135,11 @@ func (s *Store) GetDashboardData() (FlatDashboardResponse, error) {
 	}
 
 	// Total Revenue
-	err = s.db.Get(&response.TotalRevenue, "SELECT SUM(price) FROM sessions")
+	err = s.db.Get(
+		&response.TotalRevenue,
+		"SELECT COALESCE(SUM(s.price), 0) FROM sessions s JOIN events e ON s.event_id = e.id JOIN cinemas c ON e.cinema_id = c.id WHERE "+memberCinemas,
+		userId,
+	)
 	if err != nil {
 		return response, errors.CustomError{
 			Key: errors.InternalServerError,
@@ -98,7 +148,7 @@ func (s *Store) GetDashboardData() (FlatDashboardResponse, error) {
 	}
 
 	// Total Events
-	err = s.db.Get(&response.TotalEvents, "SELECT COUNT(*) FROM events")
+	err = s.db.Get(&response.TotalEvents, "SELECT COUNT(*) FROM events e JOIN cinemas c ON e.cinema_id = c.id WHERE "+memberCinemas, userId)
 	if err != nil {
 		return response, errors.CustomError{
 			Key: errors.InternalServerError,
@@ -107,7 +157,7 @@ func (s *Store) GetDashboardData() (FlatDashboardResponse, error) {
 	}
 
 	// Total Confirmed Bookings
-	err = s.db.Get(&response.TotalConfirmedBookings, "SELECT COUNT(*) FROM bookings WHERE status = 'CONFIRMED'")
+	err = s.db.Get(&response.TotalConfirmedBookings, "SELECT COUNT(*)"+cinemaBookings+"WHERE b.status = 'CONFIRMED' AND "+memberCinemas, userId)
 	if err != nil {
 		return response, errors.CustomError{
 			Key: errors.InternalServerError,
@@ -115,8 +165,8 @@ func (s *Store) GetDashboardData() (FlatDashboardResponse, error) {
 		}
 	}
 
-	// Total Confirmed Bookings
-	err = s.db.Get(&response.TotalPendingBookings, "SELECT COUNT(*) FROM bookings WHERE status = 'PENDING'")
+	// Total Pending Bookings
+	err = s.db.Get(&response.TotalPendingBookings, "SELECT COUNT(*)"+cinemaBookings+"WHERE b.status = 'PENDING' AND "+memberCinemas, userId)
 	if err != nil {
 		return response, errors.CustomError{
 			Key: errors.InternalServerError,
@@ -124,8 +174,15 @@ func (s *Store) GetDashboardData() (FlatDashboardResponse, error) {
 		}
 	}
 
-	// Total Managers
-	err = s.db.Get(&response.TotalManagers, "SELECT COUNT(*) FROM users WHERE role = 'MANAGER'")
+	// Total Managers, of the same cinemas
+	err = s.db.Get(&response.TotalManagers, `
+		SELECT COUNT(*) FROM users u
+		WHERE u.role = 'MANAGER' AND (
+			$1 = 0 OR u.id IN (
+				SELECT m.user_id FROM cinema_members m JOIN cinemas c ON m.cinema_id = c.id WHERE `+memberCinemas+`
+			)
+		)
+	`, userId)
 	if err != nil {
 		return response, errors.CustomError{
 			Key: errors.InternalServerError,
@@ -133,8 +190,13 @@ func (s *Store) GetDashboardData() (FlatDashboardResponse, error) {
 		}
 	}
 
-	// Total Viewers
-	err = s.db.Get(&response.TotalViewers, "SELECT COUNT(*) FROM users WHERE role = 'VIEWER'")
+	// Total Viewers, who booked in the same cinemas
+	err = s.db.Get(&response.TotalViewers, `
+		SELECT COUNT(*) FROM users u
+		WHERE u.role = 'VIEWER' AND (
+			$1 = 0 OR u.id IN (SELECT b.user_id`+cinemaBookings+`WHERE `+memberCinemas+`)
+		)
+	`, userId)
 	if err != nil {
 		return response, errors.CustomError{
 			Key: errors.InternalServerError,
@@ -0,0 +1,503 @@
+package staff
+
+import (
+	"context"
+	"database/sql"
+	goErrors "errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/cinema-booker/internal/constants"
+	"github.com/cinema-booker/internal/email"
+	"github.com/cinema-booker/pkg/errors"
+	"github.com/cinema-booker/pkg/generator"
+	"github.com/cinema-booker/third_party/mailer"
+	"github.com/lib/pq"
+)
+
+const foreignKeyViolation = "23503"
+
+type StaffService interface {
+	GetRoles(ctx context.Context, cinemaId int) ([]Role, error)
+	CreateRole(ctx context.Context, input map[string]interface{}) (Role, error)
+	UpdateRole(ctx context.Context, id int, input map[string]interface{}) error
+	DeleteRole(ctx context.Context, id int) error
+	GetMembers(ctx context.Context, cinemaId int) ([]Member, error)
+	UpdateMember(ctx context.Context, cinemaId int, userId int, input map[string]interface{}) error
+	RemoveMember(ctx context.Context, cinemaId int, userId int) error
+	GetInvitations(ctx context.Context, cinemaId int) ([]Invitation, error)
+	Invite(ctx context.Context, cinemaId int, input map[string]interface{}) (Invitation, error)
+	RevokeInvitation(ctx context.Context, cinemaId int, id int) error
+	AcceptInvitation(ctx context.Context, input map[string]interface{}) (Invitation, error)
+}
+
+// invitationValidity is how long an invitation to join a cinema can be accepted.
+const invitationValidity = 7 * 24 * time.Hour
+
+type Service struct {
+	store  StaffStore
+	mailer mailer.Mailer
+	emails *email.Registry
+}
+
+func NewService(store StaffStore, mailer mailer.Mailer, emails *email.Registry) *Service {
+	return &Service{
+		store:  store,
+		mailer: mailer,
+		emails: emails,
+	}
+}
+
+func (s *Service) GetRoles(ctx context.Context, cinemaId int) ([]Role, error) {
+	roles, err := s.store.FindRoles(cinemaId)
+	if err != nil {
+		return roles, errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+
+	return roles, nil
+}
+
+func (s *Service) CreateRole(ctx context.Context, input map[string]interface{}) (Role, error) {
+	role, err := ParseRole(input)
+	if err != nil {
+		return role, errors.CustomError{
+			Key: errors.BadRequest,
+			Err: err,
+		}
+	}
+	if err := role.Validate(); err != nil {
+		return role, errors.CustomError{
+			Key: errors.BadRequest,
+			Err: err,
+		}
+	}
+
+	role, err = s.store.CreateRole(role)
+	if err != nil {
+		var pqErr *pq.Error
+		if goErrors.As(err, &pqErr) && pqErr.Code == foreignKeyViolation {
+			return role, errors.CustomError{
+				Key: errors.NotFound,
+				Err: goErrors.New("cinema not found"),
+			}
+		}
+		return role, errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+
+	return role, nil
+}
+
+// UpdateRole renames a role or changes its permissions; the cinema of a role cannot change.
+func (s *Service) UpdateRole(ctx context.Context, id int, input map[string]interface{}) error {
+	role, err := s.getRole(id)
+	if err != nil {
+		return err
+	}
+
+	update, err := ParseRole(input)
+	if err != nil {
+		return errors.CustomError{
+			Key: errors.BadRequest,
+			Err: err,
+		}
+	}
+	if _, ok := input["name"]; ok {
+		role.Name = update.Name
+	}
+	if _, ok := input["permissions"]; ok {
+		role.Permissions = update.Permissions
+	}
+	if err := role.Validate(); err != nil {
+		return errors.CustomError{
+			Key: errors.BadRequest,
+			Err: err,
+		}
+	}
+
+	err = s.store.UpdateRole(role)
+	if err != nil {
+		if goErrors.Is(err, ErrLastManager) {
+			return errors.CustomError{
+				Key: errors.Conflict,
+				Err: err,
+			}
+		}
+		return errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+
+	return nil
+}
+
+// DeleteRole refuses to delete roles still assigned to some staff.
+func (s *Service) DeleteRole(ctx context.Context, id int) error {
+	if _, err := s.getRole(id); err != nil {
+		return err
+	}
+
+	err := s.store.DeleteRole(id)
+	if err != nil {
+		var pqErr *pq.Error
+		if goErrors.As(err, &pqErr) && pqErr.Code == foreignKeyViolation {
+			return errors.CustomError{
+				Key: errors.Conflict,
+				Err: fmt.Errorf("role %d is still assigned", id),
+			}
+		}
+		return errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+
+	return nil
+}
+
+func (s *Service) GetMembers(ctx context.Context, cinemaId int) ([]Member, error) {
+	members, err := s.store.FindMembers(cinemaId)
+	if err != nil {
+		return members, errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+
+	return members, nil
+}
+
+// UpdateMember gives a member of a cinema the role_id of input.
+func (s *Service) UpdateMember(ctx context.Context, cinemaId int, userId int, input map[string]interface{}) error {
+	role, err := s.parseRole(cinemaId, input)
+	if err != nil {
+		return err
+	}
+	if err := s.checkGrantable(ctx, cinemaId, role); err != nil {
+		return err
+	}
+	if err := s.checkManageable(ctx, cinemaId, userId); err != nil {
+		return err
+	}
+
+	err = s.store.UpdateMember(cinemaId, userId, role.Id)
+	if err != nil {
+		return memberError(err, cinemaId, userId)
+	}
+
+	return nil
+}
+
+// RemoveMember removes a member from the staff of a cinema.
+func (s *Service) RemoveMember(ctx context.Context, cinemaId int, userId int) error {
+	if err := s.checkManageable(ctx, cinemaId, userId); err != nil {
+		return err
+	}
+
+	err := s.store.RemoveMember(cinemaId, userId)
+	if err != nil {
+		return memberError(err, cinemaId, userId)
+	}
+
+	return nil
+}
+
+// GetInvitations returns the invitations of a cinema waiting to be accepted.
+func (s *Service) GetInvitations(ctx context.Context, cinemaId int) ([]Invitation, error) {
+	invitations, err := s.store.FindInvitations(cinemaId, time.Now())
+	if err != nil {
+		return invitations, errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+
+	return invitations, nil
+}
+
+// Invite emails the email of input an invitation to join a cinema with the role_id of
+// input. The user does not need to have an account yet.
+func (s *Service) Invite(ctx context.Context, cinemaId int, input map[string]interface{}) (Invitation, error) {
+	userId, ok := ctx.Value(constants.UserIDKey).(int)
+	if !ok {
+		return Invitation{}, errors.CustomError{
+			Key: errors.Unauthorized,
+			Err: goErrors.New("user id not authenticated"),
+		}
+	}
+
+	address, ok := input["email"].(string)
+	if !ok || !strings.Contains(address, "@") {
+		return Invitation{}, errors.CustomError{
+			Key: errors.BadRequest,
+			Err: goErrors.New("a valid email is required"),
+		}
+	}
+	role, err := s.parseRole(cinemaId, input)
+	if err != nil {
+		return Invitation{}, err
+	}
+	if err := s.checkGrantable(ctx, cinemaId, role); err != nil {
+		return Invitation{}, err
+	}
+
+	token, err := generator.GenerateToken(32)
+	if err != nil {
+		return Invitation{}, errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+
+	invitation, err := s.store.CreateInvitation(Invitation{
+		CinemaId:  cinemaId,
+		Email:     strings.TrimSpace(address),
+		Role:      role,
+		InvitedBy: Inviter{Id: userId},
+		ExpiresAt: time.Now().Add(invitationValidity),
+	}, generator.HashToken(token))
+	if err != nil {
+		return invitation, errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+
+	message, err := s.emails.Render(email.TemplateCinemaInvitation, constants.DefaultLocale, email.CinemaInvitationData{
+		InvitedBy: invitation.InvitedBy.Name,
+		Cinema:    invitation.CinemaName,
+		Role:      invitation.Role.Name,
+		Token:     token,
+		ExpiresIn: int(invitationValidity / (24 * time.Hour)),
+	})
+	if err == nil {
+		err = s.mailer.Send(mailer.Email{
+			To:      []string{invitation.Email},
+			Subject: message.Subject,
+			HTML:    message.HTML,
+			Text:    message.Text,
+		})
+	}
+	if err != nil {
+		return invitation, errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+
+	return invitation, nil
+}
+
+// RevokeInvitation deletes an invitation of a cinema which was not accepted yet.
+func (s *Service) RevokeInvitation(ctx context.Context, cinemaId int, id int) error {
+	deleted, err := s.store.DeleteInvitation(cinemaId, id)
+	if err != nil {
+		return errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+	if !deleted {
+		return errors.CustomError{
+			Key: errors.NotFound,
+			Err: fmt.Errorf("invitation %d not found in cinema %d", id, cinemaId),
+		}
+	}
+
+	return nil
+}
+
+// AcceptInvitation makes the authenticated user a member of the cinema they were
+// invited to with the token of input.
+func (s *Service) AcceptInvitation(ctx context.Context, input map[string]interface{}) (Invitation, error) {
+	userId, ok := ctx.Value(constants.UserIDKey).(int)
+	if !ok {
+		return Invitation{}, errors.CustomError{
+			Key: errors.Unauthorized,
+			Err: goErrors.New("user id not authenticated"),
+		}
+	}
+
+	token, ok := input["token"].(string)
+	if !ok || token == "" {
+		return Invitation{}, errors.CustomError{
+			Key: errors.BadRequest,
+			Err: goErrors.New("token is required"),
+		}
+	}
+
+	invitation, err := s.store.AcceptInvitation(generator.HashToken(token), userId, time.Now())
+	if err != nil {
+		switch {
+		case goErrors.Is(err, sql.ErrNoRows):
+			return invitation, errors.CustomError{
+				Key: errors.NotFound,
+				Err: goErrors.New("invitation not found"),
+			}
+		case goErrors.Is(err, ErrInvitationOtherEmail):
+			return invitation, errors.CustomError{
+				Key: errors.Forbidden,
+				Err: err,
+			}
+		case goErrors.Is(err, ErrInvitationAccepted), goErrors.Is(err, ErrInvitationExpired), goErrors.Is(err, ErrLastManager):
+			return invitation, errors.CustomError{
+				Key:     errors.Conflict,
+				Err:     err,
+				Details: map[string]string{"reason": err.Error()},
+			}
+		}
+		return invitation, errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+
+	return invitation, nil
+}
+
+// parseRole returns the role of the role_id of input, which must be assignable in the cinema.
+func (s *Service) parseRole(cinemaId int, input map[string]interface{}) (Role, error) {
+	roleId, ok := input["role_id"].(float64)
+	if !ok {
+		return Role{}, errors.CustomError{
+			Key: errors.BadRequest,
+			Err: goErrors.New("role_id is required"),
+		}
+	}
+
+	role, err := s.getRole(int(roleId))
+	if err != nil {
+		return role, err
+	}
+	if !role.AvailableIn(cinemaId) {
+		return role, errors.CustomError{
+			Key: errors.BadRequest,
+			Err: fmt.Errorf("role %d belongs to another cinema", role.Id),
+		}
+	}
+
+	return role, nil
+}
+
+// checkGrantable makes sure the authenticated user has every permission of a role they
+// give in a cinema, so that nobody grants more than they have. Admins give any role.
+func (s *Service) checkGrantable(ctx context.Context, cinemaId int, role Role) error {
+	missing, err := s.missingPermission(ctx, cinemaId, role.Permissions)
+	if err != nil {
+		return err
+	}
+	if missing != "" {
+		return errors.CustomError{
+			Key: errors.Forbidden,
+			Err: fmt.Errorf("role %d grants the permission %s you do not have", role.Id, missing),
+		}
+	}
+
+	return nil
+}
+
+// checkManageable makes sure the authenticated user has every permission a member of a
+// cinema has before changing their role or removing them, so that nobody demotes a member
+// with more permissions than their own. Admins manage anyone.
+func (s *Service) checkManageable(ctx context.Context, cinemaId int, userId int) error {
+	if userRole, _ := ctx.Value(constants.UserRoleKey).(string); userRole == constants.UserRoleAdmin {
+		return nil
+	}
+
+	permissions, err := s.store.FindMemberPermissions(cinemaId, userId)
+	if err != nil {
+		return errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+
+	missing, err := s.missingPermission(ctx, cinemaId, permissions)
+	if err != nil {
+		return err
+	}
+	if missing != "" {
+		return errors.CustomError{
+			Key: errors.Forbidden,
+			Err: fmt.Errorf("user %d has the permission %s you do not have", userId, missing),
+		}
+	}
+
+	return nil
+}
+
+// missingPermission returns one of permissions the authenticated user does not have in a
+// cinema, or "" when they have them all. Admins have every permission.
+func (s *Service) missingPermission(ctx context.Context, cinemaId int, permissions []string) (string, error) {
+	userId, ok := ctx.Value(constants.UserIDKey).(int)
+	if !ok {
+		return "", errors.CustomError{
+			Key: errors.Unauthorized,
+			Err: goErrors.New("user id not authenticated"),
+		}
+	}
+	if userRole, _ := ctx.Value(constants.UserRoleKey).(string); userRole == constants.UserRoleAdmin {
+		return "", nil
+	}
+
+	held, err := s.store.FindMemberPermissions(cinemaId, userId)
+	if err != nil {
+		return "", errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+	for _, permission := range permissions {
+		if !slices.Contains(held, permission) {
+			return permission, nil
+		}
+	}
+
+	return "", nil
+}
+
+func memberError(err error, cinemaId int, userId int) error {
+	switch {
+	case goErrors.Is(err, sql.ErrNoRows):
+		return errors.CustomError{
+			Key: errors.NotFound,
+			Err: fmt.Errorf("user %d is not a member of cinema %d", userId, cinemaId),
+		}
+	case goErrors.Is(err, ErrLastManager):
+		return errors.CustomError{
+			Key: errors.Conflict,
+			Err: err,
+		}
+	}
+
+	return errors.CustomError{
+		Key: errors.InternalServerError,
+		Err: err,
+	}
+}
+
+func (s *Service) getRole(id int) (Role, error) {
+	role, err := s.store.FindRoleById(id)
+	if err != nil {
+		if goErrors.Is(err, sql.ErrNoRows) {
+			return role, errors.CustomError{
+				Key: errors.NotFound,
+				Err: err,
+			}
+		}
+		return role, errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+
+	return role, nil
+}
@@ -0,0 +1,352 @@
+package staff
+
+import (
+	"database/sql"
+	goErrors "errors"
+	"time"
+
+	"github.com/cinema-booker/internal/constants"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type StaffStore interface {
+	FindRoles(cinemaId int) ([]Role, error)
+	FindRoleById(id int) (Role, error)
+	CreateRole(role Role) (Role, error)
+	UpdateRole(role Role) error
+	DeleteRole(id int) error
+	FindMembers(cinemaId int) ([]Member, error)
+	FindMemberPermissions(cinemaId int, userId int) ([]string, error)
+	UpdateMember(cinemaId int, userId int, roleId int) error
+	RemoveMember(cinemaId int, userId int) error
+	FindInvitations(cinemaId int, now time.Time) ([]Invitation, error)
+	CreateInvitation(invitation Invitation, tokenHash string) (Invitation, error)
+	DeleteInvitation(cinemaId int, id int) (bool, error)
+	AcceptInvitation(tokenHash string, userId int, now time.Time) (Invitation, error)
+}
+
+var (
+	ErrLastManager          = goErrors.New("the cinema would have nobody left to manage its staff")
+	ErrInvitationAccepted   = goErrors.New("invitation already accepted")
+	ErrInvitationExpired    = goErrors.New("invitation expired")
+	ErrInvitationOtherEmail = goErrors.New("invitation sent to another email")
+)
+
+const invitationColumns = `
+	i.id AS id,
+	i.cinema_id AS cinema_id,
+	c.name AS cinema_name,
+	i.email AS email,
+	i.expires_at AS expires_at,
+	i.accepted_at AS accepted_at,
+	i.created_at AS created_at,
+	r.id AS "role.id",
+	r.cinema_id AS "role.cinema_id",
+	r.name AS "role.name",
+	r.permissions AS "role.permissions",
+	r.created_at AS "role.created_at",
+	u.id AS "invited_by.id",
+	u.name AS "invited_by.name"
+	FROM cinema_invitations i
+	JOIN cinemas c ON i.cinema_id = c.id
+	JOIN staff_roles r ON i.role_id = r.id
+	JOIN users u ON i.invited_by = u.id
+`
+
+type Store struct {
+	db *sqlx.DB
+}
+
+func NewStore(db *sqlx.DB) *Store {
+	return &Store{
+		db: db,
+	}
+}
+
+// FindRoles returns the roles which can be assigned in a cinema, or every role when
+// cinemaId is 0.
+func (s *Store) FindRoles(cinemaId int) ([]Role, error) {
+	roles := []Role{}
+	query := "SELECT * FROM staff_roles WHERE $1 = 0 OR cinema_id IS NULL OR cinema_id = $1 ORDER BY id"
+	err := s.db.Select(&roles, query, cinemaId)
+
+	return roles, err
+}
+
+func (s *Store) FindRoleById(id int) (Role, error) {
+	role := Role{}
+	err := s.db.Get(&role, "SELECT * FROM staff_roles WHERE id=$1", id)
+
+	return role, err
+}
+
+func (s *Store) CreateRole(role Role) (Role, error) {
+	created := Role{}
+	err := s.db.Get(
+		&created,
+		"INSERT INTO staff_roles (cinema_id, name, permissions) VALUES ($1, $2, $3) RETURNING *",
+		role.CinemaId, role.Name, role.Permissions,
+	)
+
+	return created, err
+}
+
+// UpdateRole changes the name and permissions of a role. It returns ErrLastManager when
+// a cinema using the role, any cinema for a global role, would have nobody left to manage
+// its staff.
+func (s *Store) UpdateRole(role Role) error {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(
+		"UPDATE staff_roles SET name=$1, permissions=$2 WHERE id=$3",
+		role.Name, role.Permissions, role.Id,
+	)
+	if err != nil {
+		return err
+	}
+
+	cinemaIds := []int{}
+	err = tx.Select(&cinemaIds, "SELECT DISTINCT cinema_id FROM cinema_members WHERE role_id=$1 ORDER BY cinema_id", role.Id)
+	if err != nil {
+		return err
+	}
+	for _, cinemaId := range cinemaIds {
+		if err := checkManaged(tx, cinemaId); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// DeleteRole deletes a role. Roles still assigned to members cannot be deleted, so no
+// cinema can lose the members managing its staff this way.
+func (s *Store) DeleteRole(id int) error {
+	_, err := s.db.Exec("DELETE FROM staff_roles WHERE id=$1", id)
+
+	return err
+}
+
+func (s *Store) FindMembers(cinemaId int) ([]Member, error) {
+	members := []Member{}
+	query := `
+		SELECT
+			u.id AS user_id,
+			u.name AS name,
+			u.email AS email,
+			m.created_at AS created_at,
+			r.id AS "role.id",
+			r.cinema_id AS "role.cinema_id",
+			r.name AS "role.name",
+			r.permissions AS "role.permissions",
+			r.created_at AS "role.created_at"
+		FROM cinema_members m
+		JOIN users u ON m.user_id = u.id
+		JOIN staff_roles r ON m.role_id = r.id
+		WHERE m.cinema_id=$1
+		ORDER BY u.name
+	`
+	err := s.db.Select(&members, query, cinemaId)
+
+	return members, err
+}
+
+// FindMemberPermissions returns the permissions of a user as a member of a cinema, none
+// when they are not a member.
+func (s *Store) FindMemberPermissions(cinemaId int, userId int) ([]string, error) {
+	permissions := pq.StringArray{}
+	query := `
+		SELECT r.permissions
+		FROM cinema_members m
+		JOIN staff_roles r ON m.role_id = r.id
+		WHERE m.cinema_id=$1 AND m.user_id=$2
+	`
+	err := s.db.Get(&permissions, query, cinemaId, userId)
+	if goErrors.Is(err, sql.ErrNoRows) {
+		return []string{}, nil
+	}
+
+	return permissions, err
+}
+
+// UpdateMember changes the role of a member of a cinema. It returns sql.ErrNoRows when
+// the user is not a member and ErrLastManager when nobody could manage the staff anymore.
+func (s *Store) UpdateMember(cinemaId int, userId int, roleId int) error {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("UPDATE cinema_members SET role_id=$1 WHERE cinema_id=$2 AND user_id=$3", roleId, cinemaId, userId)
+	if err != nil {
+		return err
+	}
+	if count, err := result.RowsAffected(); err != nil || count == 0 {
+		if err == nil {
+			err = sql.ErrNoRows
+		}
+		return err
+	}
+
+	if err := checkManaged(tx, cinemaId); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// RemoveMember removes a user from the members of a cinema. It returns sql.ErrNoRows
+// when the user is not a member and ErrLastManager when nobody could manage the staff
+// anymore.
+func (s *Store) RemoveMember(cinemaId int, userId int) error {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("DELETE FROM cinema_members WHERE cinema_id=$1 AND user_id=$2", cinemaId, userId)
+	if err != nil {
+		return err
+	}
+	if count, err := result.RowsAffected(); err != nil || count == 0 {
+		if err == nil {
+			err = sql.ErrNoRows
+		}
+		return err
+	}
+
+	if err := checkManaged(tx, cinemaId); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// checkManaged makes sure some member of a cinema can still manage its staff.
+func checkManaged(tx *sqlx.Tx, cinemaId int) error {
+	var managed bool
+	query := `
+		SELECT EXISTS (
+			SELECT 1
+			FROM cinema_members m
+			JOIN staff_roles r ON m.role_id = r.id
+			WHERE m.cinema_id=$1 AND $2 = ANY(r.permissions)
+		)
+	`
+	err := tx.Get(&managed, query, cinemaId, constants.PermissionStaffManage)
+	if err != nil {
+		return err
+	}
+	if !managed {
+		return ErrLastManager
+	}
+
+	return nil
+}
+
+func (s *Store) FindInvitations(cinemaId int, now time.Time) ([]Invitation, error) {
+	invitations := []Invitation{}
+	query := "SELECT " + invitationColumns + " WHERE i.cinema_id=$1 AND i.accepted_at IS NULL AND i.expires_at > $2 ORDER BY i.id"
+	err := s.db.Select(&invitations, query, cinemaId, now)
+
+	return invitations, err
+}
+
+func (s *Store) CreateInvitation(invitation Invitation, tokenHash string) (Invitation, error) {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return invitation, err
+	}
+	defer tx.Rollback()
+
+	var id int
+	err = tx.Get(
+		&id,
+		`INSERT INTO cinema_invitations (cinema_id, email, role_id, invited_by, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`,
+		invitation.CinemaId, invitation.Email, invitation.Role.Id, invitation.InvitedBy.Id, tokenHash, invitation.ExpiresAt,
+	)
+	if err != nil {
+		return invitation, err
+	}
+
+	created := Invitation{}
+	err = tx.Get(&created, "SELECT "+invitationColumns+" WHERE i.id=$1", id)
+	if err != nil {
+		return created, err
+	}
+
+	return created, tx.Commit()
+}
+
+// DeleteInvitation revokes an invitation which was not accepted yet and reports whether
+// there was one.
+func (s *Store) DeleteInvitation(cinemaId int, id int) (bool, error) {
+	result, err := s.db.Exec("DELETE FROM cinema_invitations WHERE id=$1 AND cinema_id=$2 AND accepted_at IS NULL", id, cinemaId)
+	if err != nil {
+		return false, err
+	}
+	count, err := result.RowsAffected()
+
+	return count > 0, err
+}
+
+// AcceptInvitation makes a user a member of the cinema they were invited to, with the
+// role of the invitation. The invitation must have been sent to the email of the user.
+// A member accepting it changes role, which fails with ErrLastManager when nobody could
+// manage the staff anymore.
+func (s *Store) AcceptInvitation(tokenHash string, userId int, now time.Time) (Invitation, error) {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return Invitation{}, err
+	}
+	defer tx.Rollback()
+
+	invitation := Invitation{}
+	err = tx.Get(&invitation, "SELECT "+invitationColumns+" WHERE i.token_hash=$1 FOR UPDATE OF i", tokenHash)
+	if err != nil {
+		return invitation, err
+	}
+	if invitation.AcceptedAt != nil {
+		return invitation, ErrInvitationAccepted
+	}
+	if now.After(invitation.ExpiresAt) {
+		return invitation, ErrInvitationExpired
+	}
+
+	var sameEmail bool
+	err = tx.Get(&sameEmail, "SELECT LOWER(email) = LOWER($1) FROM users WHERE id=$2", invitation.Email, userId)
+	if err != nil {
+		return invitation, err
+	}
+	if !sameEmail {
+		return invitation, ErrInvitationOtherEmail
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO cinema_members (cinema_id, user_id, role_id) VALUES ($1, $2, $3)
+		ON CONFLICT (cinema_id, user_id) DO UPDATE SET role_id = EXCLUDED.role_id
+	`, invitation.CinemaId, userId, invitation.Role.Id)
+	if err != nil {
+		return invitation, err
+	}
+
+	if err := checkManaged(tx, invitation.CinemaId); err != nil {
+		return invitation, err
+	}
+
+	_, err = tx.Exec("UPDATE cinema_invitations SET accepted_at=$1 WHERE id=$2", now, invitation.Id)
+	if err != nil {
+		return invitation, err
+	}
+	invitation.AcceptedAt = &now
+
+	return invitation, tx.Commit()
+}
@@ -0,0 +1,85 @@
+package staff
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/cinema-booker/internal/constants"
+	"github.com/lib/pq"
+)
+
+// Role is a named set of permissions given to cinema staff. Roles without a cinema
+// can be assigned in every cinema, the others only in theirs.
+type Role struct {
+	Id          int            `json:"id" db:"id"`
+	CinemaId    *int           `json:"cinema_id" db:"cinema_id"`
+	Name        string         `json:"name" db:"name"`
+	Permissions pq.StringArray `json:"permissions" db:"permissions"`
+	CreatedAt   time.Time      `json:"created_at" db:"created_at"`
+}
+
+// ParseRole converts a decoded JSON request value into a Role.
+func ParseRole(v interface{}) (Role, error) {
+	role := Role{}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return role, err
+	}
+	if err := json.Unmarshal(data, &role); err != nil {
+		return role, err
+	}
+	role.Name = strings.TrimSpace(role.Name)
+
+	return role, nil
+}
+
+func (r Role) Validate() error {
+	if r.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	for _, permission := range r.Permissions {
+		if !slices.Contains(constants.Permissions, permission) {
+			return fmt.Errorf("unknown permission %s", permission)
+		}
+	}
+
+	return nil
+}
+
+// AvailableIn reports whether the role can be assigned in a cinema.
+func (r Role) AvailableIn(cinemaId int) bool {
+	return r.CinemaId == nil || *r.CinemaId == cinemaId
+}
+
+// Member is a user working in a cinema with a staff role.
+type Member struct {
+	UserId    int       `json:"user_id" db:"user_id"`
+	Name      string    `json:"name" db:"name"`
+	Email     string    `json:"email" db:"email"`
+	Role      Role      `json:"role" db:"role"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// Inviter is the member who sent an invitation.
+type Inviter struct {
+	Id   int    `json:"id" db:"id"`
+	Name string `json:"name" db:"name"`
+}
+
+// Invitation asks the user with Email to join a cinema with a role. It is accepted with
+// the token sent by email; only the hash of the token is stored.
+type Invitation struct {
+	Id         int        `json:"id" db:"id"`
+	CinemaId   int        `json:"cinema_id" db:"cinema_id"`
+	CinemaName string     `json:"cinema_name" db:"cinema_name"`
+	Email      string     `json:"email" db:"email"`
+	Role       Role       `json:"role" db:"role"`
+	InvitedBy  Inviter    `json:"invited_by" db:"invited_by"`
+	ExpiresAt  time.Time  `json:"expires_at" db:"expires_at"`
+	AcceptedAt *time.Time `json:"accepted_at" db:"accepted_at"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+}
@@ -4,17 +4,19 @@ import (
 	"context"
 	"database/sql"
 	goErrors "errors"
+	"fmt"
+	"log"
 	"os"
 	"strconv"
 	"time"
 
 	"github.com/cinema-booker/internal/constants"
+	"github.com/cinema-booker/internal/email"
 	"github.com/cinema-booker/pkg/errors"
 	"github.com/cinema-booker/pkg/generator"
 	"github.com/cinema-booker/pkg/hasher"
 	"github.com/cinema-booker/pkg/jwt"
-	"github.com/cinema-booker/third_party/resend"
-	goJwt "github.com/golang-jwt/jwt/v5"
+	"github.com/cinema-booker/third_party/mailer"
 )
 
 type UserService interface {
@@ -27,20 +29,47 @@ type UserService interface {
 
 	SignUp(ctx context.Context, input map[string]interface{}) error
 	SignIn(ctx context.Context, input map[string]interface{}) (map[string]interface{}, error)
+	Refresh(ctx context.Context, input map[string]interface{}) (map[string]interface{}, error)
+	SignOut(ctx context.Context) error
+	SignOutEverywhere(ctx context.Context) error
 	SendPasswordReset(ctx context.Context, input map[string]interface{}) error
 	ResetPassword(ctx context.Context, input map[string]interface{}) error
 	EditPassword(ctx context.Context, id int, input map[string]interface{}) error
 	GetMe(ctx context.Context) (map[string]interface{}, error)
 }
 
+// passwordResetCodeValidity is how long the temporary password sent by email can be used.
+const passwordResetCodeValidity = time.Minute
+
 type Service struct {
-	store UserStore
+	store  UserStore
+	mailer mailer.Mailer
+	emails *email.Registry
+	keys   *jwt.KeySet
 }
 
-func NewService(store UserStore) *Service {
+func NewService(store UserStore, mailer mailer.Mailer, emails *email.Registry, keys *jwt.KeySet) *Service {
 	return &Service{
-		store: store,
+		store:  store,
+		mailer: mailer,
+		emails: emails,
+		keys:   keys,
+	}
+}
+
+// sendEmail renders the template name in the locale of the user and sends it to them.
+func (s *Service) sendEmail(user User, name string, data interface{}) error {
+	message, err := s.emails.Render(name, user.Locale, data)
+	if err != nil {
+		return err
 	}
+
+	return s.mailer.Send(mailer.Email{
+		To:      []string{user.Email},
+		Subject: message.Subject,
+		HTML:    message.HTML,
+		Text:    message.Text,
+	})
 }
 
 func (s *Service) GetAll(ctx context.Context, pagination map[string]int, search string) ([]User, error) {
@@ -177,6 +206,18 @@ func (s *Service) SignUp(ctx context.Context, input map[string]interface{}) erro
 		}
 	}
 
+	locale, ok := input["locale"].(string)
+	if !ok || locale == "" {
+		locale = constants.DefaultLocale
+	}
+	if !s.emails.HasLocale(locale) {
+		return errors.CustomError{
+			Key: errors.BadRequest,
+			Err: fmt.Errorf("unsupported locale %s", locale),
+		}
+	}
+	input["locale"] = locale
+
 	hashedPassword, err := hasher.Hash(input["password"].(string))
 	if err != nil {
 		return err
@@ -191,6 +232,12 @@ func (s *Service) SignUp(ctx context.Context, input map[string]interface{}) erro
 		}
 	}
 
+	// The account exists even if the welcome email could not be sent.
+	user := User{Name: input["name"].(string), Email: input["email"].(string), Locale: locale}
+	if err := s.sendEmail(user, email.TemplateWelcome, email.WelcomeData{Name: user.Name}); err != nil {
+		log.Printf("❌ Error sending welcome email to %s: %v", user.Email, err)
+	}
+
 	return nil
 }
 
@@ -216,7 +263,54 @@ func (s *Service) SignIn(ctx context.Context, input map[string]interface{}) (map
 		}
 	}
 
-	expiresIn, err := strconv.Atoi(os.Getenv("JWT_EXPIRES_IN"))
+	sessionId, err := generator.GenerateToken(16)
+	if err != nil {
+		return nil, errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+	refreshToken, refreshExpiresAt, err := newRefreshToken()
+	if err != nil {
+		return nil, errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+
+	session := AuthSession{Id: sessionId, UserId: user.Id}
+	err = s.store.CreateSession(session, generator.HashToken(refreshToken), refreshExpiresAt)
+	if err != nil {
+		return nil, errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+
+	tokens, err := s.newTokens(user, session, refreshToken)
+	if err != nil {
+		return nil, err
+	}
+	tokens["id"] = user.Id
+	tokens["name"] = user.Name
+	tokens["email"] = user.Email
+	tokens["role"] = user.Role
+
+	return tokens, nil
+}
+
+// Refresh exchanges a refresh token for a new access token and a new refresh token,
+// the one used being no longer valid.
+func (s *Service) Refresh(ctx context.Context, input map[string]interface{}) (map[string]interface{}, error) {
+	token, ok := input["refresh_token"].(string)
+	if !ok || token == "" {
+		return nil, errors.CustomError{
+			Key: errors.BadRequest,
+			Err: goErrors.New("refresh_token is required"),
+		}
+	}
+
+	refreshToken, refreshExpiresAt, err := newRefreshToken()
 	if err != nil {
 		return nil, errors.CustomError{
 			Key: errors.InternalServerError,
@@ -224,9 +318,15 @@ func (s *Service) SignIn(ctx context.Context, input map[string]interface{}) (map
 		}
 	}
 
-	token, err := jwt.Create(os.Getenv("JWT_SECRET"), expiresIn, user.Id)
+	session, err := s.store.RotateRefreshToken(generator.HashToken(token), generator.HashToken(refreshToken), refreshExpiresAt, time.Now())
 	if err != nil {
-		if goErrors.Is(err, goJwt.ErrTokenExpired) || goErrors.Is(err, goJwt.ErrSignatureInvalid) {
+		if goErrors.Is(err, sql.ErrNoRows) {
+			return nil, errors.CustomError{
+				Key: errors.Unauthorized,
+				Err: goErrors.New("invalid refresh token"),
+			}
+		}
+		if goErrors.Is(err, ErrRefreshTokenExpired) || goErrors.Is(err, ErrRefreshTokenReused) || goErrors.Is(err, ErrSessionRevoked) {
 			return nil, errors.CustomError{
 				Key: errors.Unauthorized,
 				Err: err,
@@ -238,12 +338,103 @@ func (s *Service) SignIn(ctx context.Context, input map[string]interface{}) (map
 		}
 	}
 
+	user, err := s.store.FindById(session.UserId)
+	if err != nil {
+		return nil, errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+
+	return s.newTokens(user, session, refreshToken)
+}
+
+// SignOut revokes the session of the access token used, along with its refresh tokens.
+func (s *Service) SignOut(ctx context.Context) error {
+	userId, ok := ctx.Value(constants.UserIDKey).(int)
+	if !ok {
+		return errors.CustomError{
+			Key: errors.Unauthorized,
+			Err: goErrors.New("user id not authenticated"),
+		}
+	}
+	sessionId, ok := ctx.Value(constants.SessionIDKey).(string)
+	if !ok {
+		return errors.CustomError{
+			Key: errors.Unauthorized,
+			Err: goErrors.New("session not authenticated"),
+		}
+	}
+
+	err := s.store.RevokeSession(userId, sessionId, time.Now())
+	if err != nil {
+		return errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+
+	return nil
+}
+
+// SignOutEverywhere revokes every session of the authenticated user, e.g. after losing a device.
+func (s *Service) SignOutEverywhere(ctx context.Context) error {
+	userId, ok := ctx.Value(constants.UserIDKey).(int)
+	if !ok {
+		return errors.CustomError{
+			Key: errors.Unauthorized,
+			Err: goErrors.New("user id not authenticated"),
+		}
+	}
+
+	err := s.store.RevokeAllSessions(userId, time.Now())
+	if err != nil {
+		return errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+
+	return nil
+}
+
+// newRefreshToken returns a refresh token and when it expires, as set by REFRESH_TOKEN_EXPIRES_IN.
+func newRefreshToken() (string, time.Time, error) {
+	expiresIn, err := strconv.Atoi(os.Getenv("REFRESH_TOKEN_EXPIRES_IN"))
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("invalid REFRESH_TOKEN_EXPIRES_IN: %w", err)
+	}
+
+	token, err := generator.GenerateToken(32)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return token, time.Now().Add(time.Duration(expiresIn) * time.Second), nil
+}
+
+// newTokens returns an access token for the session of user along with its refresh token.
+func (s *Service) newTokens(user User, session AuthSession, refreshToken string) (map[string]interface{}, error) {
+	expiresIn, err := strconv.Atoi(os.Getenv("JWT_EXPIRES_IN"))
+	if err != nil {
+		return nil, errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+
+	token, err := jwt.Create(s.keys, expiresIn, user.Id, user.Role, session.Id)
+	if err != nil {
+		return nil, errors.CustomError{
+			Key: errors.InternalServerError,
+			Err: err,
+		}
+	}
+
 	return map[string]interface{}{
-		"id":    user.Id,
-		"name":  user.Name,
-		"email": user.Email,
-		"role":  user.Role,
-		"token": token,
+		"token":         token,
+		"refresh_token": refreshToken,
+		"expires_in":    expiresIn,
 	}, nil
 }
 
@@ -265,7 +456,7 @@ func (s *Service) SendPasswordReset(ctx context.Context, input map[string]interf
 	generatedCode := generator.GenerateRandomCode(8)
 	err = s.store.Update(user.Id, map[string]interface{}{
 		"code":            generatedCode,
-		"code_expires_at": time.Now().Add(time.Minute * 1),
+		"code_expires_at": time.Now().Add(passwordResetCodeValidity),
 	})
 	if err != nil {
 		return errors.CustomError{
@@ -274,11 +465,11 @@ func (s *Service) SendPasswordReset(ctx context.Context, input map[string]interf
 		}
 	}
 
-	resend := resend.NewResendService(
-		os.Getenv("RESEND_API_KEY"),
-		os.Getenv("RESEND_FROM_EMAIL"),
-	)
-	_, err = resend.SendPasswordResetEmail([]string{user.Email}, generatedCode)
+	err = s.sendEmail(user, email.TemplatePasswordReset, email.PasswordResetData{
+		Name:      user.Name,
+		Code:      generatedCode,
+		ExpiresIn: int(passwordResetCodeValidity / time.Minute),
+	})
 	if err != nil {
 		return errors.CustomError{
 			Key: errors.InternalServerError,
@@ -408,11 +599,20 @@ func (s *Service) GetMe(ctx context.Context) (map[string]interface{}, error) {
 		}
 	}
 
+	// cinema_id predates users joining several cinemas; it stays the first one for the
+	// clients still reading it.
+	var cinemaId *int
+	if len(user.CinemaIds) > 0 {
+		id := int(user.CinemaIds[0])
+		cinemaId = &id
+	}
+
 	return map[string]interface{}{
-		"id":        user.Id,
-		"name":      user.Name,
-		"email":     user.Email,
-		"role":      user.Role,
-		"cinema_id": user.CinemaId,
+		"id":         user.Id,
+		"name":       user.Name,
+		"email":      user.Email,
+		"role":       user.Role,
+		"cinema_id":  cinemaId,
+		"cinema_ids": user.CinemaIds,
 	}, nil
 }
@@ -1,8 +1,10 @@
 package user
 
 import (
+	goErrors "errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 )
@@ -14,8 +16,20 @@ type UserStore interface {
 	FindByEmail(email string) (User, error)
 	Create(input map[string]interface{}) error
 	Update(id int, input map[string]interface{}) error
+
+	CreateSession(session AuthSession, refreshTokenHash string, expiresAt time.Time) error
+	RotateRefreshToken(tokenHash string, newTokenHash string, expiresAt time.Time, now time.Time) (AuthSession, error)
+	IsSessionActive(id string) (bool, error)
+	RevokeSession(userId int, id string, now time.Time) error
+	RevokeAllSessions(userId int, now time.Time) error
 }
 
+var (
+	ErrRefreshTokenExpired = goErrors.New("refresh token expired")
+	ErrRefreshTokenReused  = goErrors.New("refresh token already used")
+	ErrSessionRevoked      = goErrors.New("session revoked")
+)
+
 type Store struct {
 	db *sqlx.DB
 }
@@ -53,14 +67,15 @@ func (s *Store) FindMeById(id int) (UserBasic, error) {
 	user := UserBasic{}
 	query := `
 		SELECT 
-      u.id AS id,
+			u.id AS id,
 			u.name AS name,
 			u.email AS email,
 			u.role AS role,
-      c.id AS cinema_id
-    FROM users u
-    LEFT JOIN cinemas c ON c.user_id = u.id
-    WHERE u.id=$1
+			ARRAY(
+				SELECT m.cinema_id FROM cinema_members m WHERE m.user_id = u.id ORDER BY m.cinema_id
+			) AS cinema_ids
+		FROM users u
+		WHERE u.id=$1
 	`
 	err := s.db.Get(&user, query, id)
 
@@ -104,3 +119,110 @@ func (s *Store) Update(id int, input map[string]interface{}) error {
 
 	return err
 }
+
+// CreateSession signs a user in, with a first refresh token.
+func (s *Store) CreateSession(session AuthSession, refreshTokenHash string, expiresAt time.Time) error {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec("INSERT INTO auth_sessions (id, user_id) VALUES ($1, $2)", session.Id, session.UserId)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(
+		"INSERT INTO refresh_tokens (session_id, token_hash, expires_at) VALUES ($1, $2, $3)",
+		session.Id, refreshTokenHash, expiresAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// RotateRefreshToken exchanges a refresh token for a new one of the same session. A
+// refresh token can only be used once: using it again means it was stolen, so the
+// whole session is revoked.
+func (s *Store) RotateRefreshToken(tokenHash string, newTokenHash string, expiresAt time.Time, now time.Time) (AuthSession, error) {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return AuthSession{}, err
+	}
+	defer tx.Rollback()
+
+	token := struct {
+		Id        int        `db:"id"`
+		ExpiresAt time.Time  `db:"expires_at"`
+		UsedAt    *time.Time `db:"used_at"`
+	}{}
+	err = tx.Get(&token, "SELECT id, expires_at, used_at FROM refresh_tokens WHERE token_hash=$1 FOR UPDATE", tokenHash)
+	if err != nil {
+		return AuthSession{}, err
+	}
+
+	session := AuthSession{}
+	err = tx.Get(&session, `
+		SELECT s.*
+		FROM auth_sessions s
+		JOIN refresh_tokens t ON t.session_id = s.id
+		WHERE t.id=$1
+		FOR UPDATE OF s
+	`, token.Id)
+	if err != nil {
+		return session, err
+	}
+	if session.RevokedAt != nil {
+		return session, ErrSessionRevoked
+	}
+	if token.UsedAt != nil {
+		if _, err := tx.Exec("UPDATE auth_sessions SET revoked_at=$1 WHERE id=$2", now, session.Id); err != nil {
+			return session, err
+		}
+		if err := tx.Commit(); err != nil {
+			return session, err
+		}
+		return session, ErrRefreshTokenReused
+	}
+	if now.After(token.ExpiresAt) {
+		return session, ErrRefreshTokenExpired
+	}
+
+	if _, err := tx.Exec("UPDATE refresh_tokens SET used_at=$1 WHERE id=$2", now, token.Id); err != nil {
+		return session, err
+	}
+	_, err = tx.Exec(
+		"INSERT INTO refresh_tokens (session_id, token_hash, expires_at) VALUES ($1, $2, $3)",
+		session.Id, newTokenHash, expiresAt,
+	)
+	if err != nil {
+		return session, err
+	}
+
+	return session, tx.Commit()
+}
+
+func (s *Store) IsSessionActive(id string) (bool, error) {
+	active := false
+	query := "SELECT EXISTS (SELECT 1 FROM auth_sessions WHERE id=$1 AND revoked_at IS NULL)"
+	err := s.db.Get(&active, query, id)
+
+	return active, err
+}
+
+func (s *Store) RevokeSession(userId int, id string, now time.Time) error {
+	query := "UPDATE auth_sessions SET revoked_at=$1 WHERE id=$2 AND user_id=$3 AND revoked_at IS NULL"
+	_, err := s.db.Exec(query, now, id, userId)
+
+	return err
+}
+
+func (s *Store) RevokeAllSessions(userId int, now time.Time) error {
+	query := "UPDATE auth_sessions SET revoked_at=$1 WHERE user_id=$2 AND revoked_at IS NULL"
+	_, err := s.db.Exec(query, now, userId)
+
+	return err
+}
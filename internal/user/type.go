@@ -1,6 +1,10 @@
 package user
 
-import "time"
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
 
 type User struct {
 	Id            int        `json:"id" db:"id"`
@@ -8,15 +12,26 @@ type User struct {
 	Email         string     `json:"email" db:"email"`
 	Password      string     `json:"password" db:"password"`
 	Role          string     `json:"role" db:"role"`
+	Locale        string     `json:"locale" db:"locale"`
 	Code          string     `json:"code" db:"code"`
 	CodeExpiresAt *time.Time `json:"code_expires_at" db:"code_expires_at"`
 	DeletedAt     *time.Time `json:"deleted_at" db:"deleted_at"`
 }
 
 type UserBasic struct {
-	Id       int    `json:"id" db:"id"`
-	Name     string `json:"name" db:"name"`
-	Email    string `json:"email" db:"email"`
-	Role     string `json:"role" db:"role"`
-	CinemaId *int   `json:"cinema_id" db:"cinema_id"`
+	Id    int    `json:"id" db:"id"`
+	Name  string `json:"name" db:"name"`
+	Email string `json:"email" db:"email"`
+	Role  string `json:"role" db:"role"`
+	// CinemaIds are the cinemas the user is a member of.
+	CinemaIds pq.Int64Array `json:"cinema_ids" db:"cinema_ids"`
+}
+
+// AuthSession is a sign in of a user, which lasts as long as its refresh tokens are
+// rotated and until it is revoked by signing out.
+type AuthSession struct {
+	Id        string     `json:"id" db:"id"`
+	UserId    int        `json:"user_id" db:"user_id"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at" db:"revoked_at"`
 }
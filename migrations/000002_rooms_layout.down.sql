@@ -0,0 +1,2 @@
+-- Table: rooms
+ALTER TABLE "rooms" DROP COLUMN IF EXISTS "layout";
@@ -0,0 +1,22 @@
+-- Table: rooms
+
+ALTER TABLE "rooms" ADD COLUMN "layout" JSONB NOT NULL DEFAULT '{"rows": []}';
+
+-- Existing rooms get a grid of standard seats sized by their type, with rows A, B, ...
+-- numbered from 1, so that their seats can be booked until a manager draws the real layout.
+UPDATE "rooms" r SET "layout" = jsonb_build_object('rows', (
+  SELECT jsonb_agg(jsonb_build_object(
+    'label', chr(64 + row_index),
+    'seats', (
+      SELECT jsonb_agg(jsonb_build_object('number', seat_number, 'category', 'STANDARD', 'blocked', false) ORDER BY seat_number)
+      FROM generate_series(1, size.seats) seat_number
+    ),
+    'aisles', '[]'::jsonb
+  ) ORDER BY row_index)
+  FROM generate_series(1, size.rows) row_index
+))
+FROM (VALUES ('SMALL', 5, 10), ('MEDIUM', 10, 15), ('LARGE', 15, 20)) AS size(type, rows, seats)
+WHERE r."type"::text = size.type;
+
+-- New rooms must be created with their layout.
+ALTER TABLE "rooms" ALTER COLUMN "layout" DROP DEFAULT;
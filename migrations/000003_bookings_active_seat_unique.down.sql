@@ -0,0 +1,3 @@
+-- Table: bookings
+DROP INDEX IF EXISTS "bookings_session_id_place_active_key";
+ALTER TABLE "bookings" ADD CONSTRAINT "bookings_user_id_session_id_place_key" UNIQUE ("user_id", "session_id", "place");
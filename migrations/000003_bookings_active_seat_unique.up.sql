@@ -0,0 +1,7 @@
+-- Table: bookings
+
+ALTER TABLE "bookings" DROP CONSTRAINT IF EXISTS "bookings_user_id_session_id_place_key";
+
+CREATE UNIQUE INDEX "bookings_session_id_place_active_key"
+  ON "bookings" ("session_id", "place")
+  WHERE "status" IN ('PENDING', 'CONFIRMED');
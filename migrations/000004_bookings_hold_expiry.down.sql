@@ -0,0 +1,17 @@
+-- Table: bookings
+DROP INDEX IF EXISTS "bookings_pending_expires_at_idx";
+DROP INDEX IF EXISTS "bookings_session_id_place_active_key";
+ALTER TABLE "bookings" DROP COLUMN IF EXISTS "expires_at";
+
+UPDATE "bookings" SET "status" = 'CANCELED' WHERE "status" = 'EXPIRED';
+ALTER TYPE bookings_status_enum RENAME TO bookings_status_enum_old;
+CREATE TYPE bookings_status_enum AS ENUM ('PENDING', 'CONFIRMED', 'CANCELED');
+ALTER TABLE "bookings"
+  ALTER COLUMN "status" DROP DEFAULT,
+  ALTER COLUMN "status" TYPE bookings_status_enum USING "status"::text::bookings_status_enum,
+  ALTER COLUMN "status" SET DEFAULT 'PENDING';
+DROP TYPE bookings_status_enum_old;
+
+CREATE UNIQUE INDEX "bookings_session_id_place_active_key"
+  ON "bookings" ("session_id", "place")
+  WHERE "status" IN ('PENDING', 'CONFIRMED');
@@ -0,0 +1,10 @@
+-- Table: bookings
+
+ALTER TYPE bookings_status_enum ADD VALUE IF NOT EXISTS 'EXPIRED';
+
+ALTER TABLE "bookings" ADD COLUMN "expires_at" TIMESTAMP;
+
+CREATE INDEX "bookings_pending_expires_at_idx" ON "bookings" ("expires_at") WHERE "status" = 'PENDING';
+
+-- Holds taken before expiry existed have no end: release them on the next reaper run.
+UPDATE "bookings" SET "expires_at" = NOW() WHERE "status" = 'PENDING' AND "expires_at" IS NULL;
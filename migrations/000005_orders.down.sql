@@ -0,0 +1,7 @@
+-- Table: bookings
+DROP INDEX IF EXISTS "bookings_order_id_idx";
+ALTER TABLE "bookings" DROP COLUMN IF EXISTS "order_id";
+
+-- Table: orders
+DROP TABLE IF EXISTS "orders";
+DROP TYPE IF EXISTS orders_status_enum;
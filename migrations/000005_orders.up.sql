@@ -0,0 +1,20 @@
+-- Table: orders
+
+CREATE TYPE orders_status_enum AS ENUM ('PENDING', 'CONFIRMED', 'CANCELED', 'EXPIRED');
+
+CREATE TABLE "orders" (
+  "id" SERIAL PRIMARY KEY,
+  "user_id" INTEGER NOT NULL REFERENCES "users"("id"),
+  "session_id" INTEGER NOT NULL REFERENCES "sessions"("id"),
+  "amount" INTEGER NOT NULL DEFAULT 0,
+  "payment_reference" VARCHAR(255),
+  "status" orders_status_enum NOT NULL DEFAULT 'PENDING',
+  "expires_at" TIMESTAMP,
+  "created_at" TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- Table: bookings
+
+ALTER TABLE "bookings" ADD COLUMN "order_id" INTEGER REFERENCES "orders"("id");
+
+CREATE INDEX "bookings_order_id_idx" ON "bookings" ("order_id");
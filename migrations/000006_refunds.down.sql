@@ -0,0 +1,3 @@
+-- Table: refunds
+DROP TABLE IF EXISTS "refunds";
+DROP TYPE IF EXISTS refunds_status_enum;
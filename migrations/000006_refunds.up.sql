@@ -0,0 +1,16 @@
+-- Table: refunds
+
+CREATE TYPE refunds_status_enum AS ENUM ('REQUESTED', 'PENDING', 'SUCCEEDED');
+
+CREATE TABLE "refunds" (
+  "id" SERIAL PRIMARY KEY,
+  "order_id" INTEGER NOT NULL REFERENCES "orders"("id"),
+  "booking_id" INTEGER REFERENCES "bookings"("id"),
+  "payment_refund_id" VARCHAR(255),
+  "amount" INTEGER NOT NULL,
+  "status" refunds_status_enum NOT NULL DEFAULT 'REQUESTED',
+  "created_at" TIMESTAMP NOT NULL DEFAULT NOW(),
+  "refunded_at" TIMESTAMP
+);
+
+CREATE INDEX "refunds_order_id_idx" ON "refunds" ("order_id");
@@ -0,0 +1,2 @@
+-- Table: cinemas
+ALTER TABLE "cinemas" DROP COLUMN IF EXISTS "cancellation_policy";
@@ -0,0 +1,3 @@
+-- Table: cinemas
+
+ALTER TABLE "cinemas" ADD COLUMN "cancellation_policy" JSONB NOT NULL DEFAULT '{"rules": [{"hours_before": 0, "refund_percent": 100}]}';
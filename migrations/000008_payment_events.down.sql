@@ -0,0 +1,2 @@
+-- Table: payment_events
+DROP TABLE IF EXISTS "payment_events";
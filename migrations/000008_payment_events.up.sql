@@ -0,0 +1,8 @@
+-- Table: payment_events
+
+CREATE TABLE "payment_events" (
+  "id" VARCHAR(255) PRIMARY KEY,
+  "type" VARCHAR(255) NOT NULL,
+  "order_id" INTEGER REFERENCES "orders"("id"),
+  "processed_at" TIMESTAMP NOT NULL DEFAULT NOW()
+);
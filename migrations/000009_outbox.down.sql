@@ -0,0 +1,3 @@
+-- Table: outbox
+DROP TABLE IF EXISTS "outbox";
+DROP TYPE IF EXISTS outbox_status_enum;
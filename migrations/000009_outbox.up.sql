@@ -0,0 +1,17 @@
+-- Table: outbox
+
+CREATE TYPE outbox_status_enum AS ENUM ('PENDING', 'DELIVERED', 'FAILED');
+
+CREATE TABLE "outbox" (
+  "id" SERIAL PRIMARY KEY,
+  "topic" VARCHAR(255) NOT NULL,
+  "payload" JSONB NOT NULL,
+  "status" outbox_status_enum NOT NULL DEFAULT 'PENDING',
+  "attempts" INTEGER NOT NULL DEFAULT 0,
+  "last_error" TEXT,
+  "available_at" TIMESTAMP NOT NULL DEFAULT NOW(),
+  "created_at" TIMESTAMP NOT NULL DEFAULT NOW(),
+  "delivered_at" TIMESTAMP
+);
+
+CREATE INDEX "outbox_pending_idx" ON "outbox" ("available_at") WHERE "status" = 'PENDING';
@@ -0,0 +1,4 @@
+-- Table: bookings
+DROP INDEX IF EXISTS "bookings_ticket_id_key";
+ALTER TABLE "bookings" DROP COLUMN IF EXISTS "used_at";
+ALTER TABLE "bookings" DROP COLUMN IF EXISTS "ticket_id";
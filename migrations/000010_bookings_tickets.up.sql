@@ -0,0 +1,6 @@
+-- Table: bookings
+
+ALTER TABLE "bookings" ADD COLUMN "ticket_id" UUID NOT NULL DEFAULT gen_random_uuid();
+ALTER TABLE "bookings" ADD COLUMN "used_at" TIMESTAMP;
+
+CREATE UNIQUE INDEX "bookings_ticket_id_key" ON "bookings" ("ticket_id");
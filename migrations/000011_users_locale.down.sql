@@ -0,0 +1,2 @@
+-- Table: users
+ALTER TABLE "users" DROP COLUMN IF EXISTS "locale";
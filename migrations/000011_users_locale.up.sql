@@ -0,0 +1,3 @@
+-- Table: users
+
+ALTER TABLE "users" ADD COLUMN "locale" VARCHAR(10) NOT NULL DEFAULT 'en';
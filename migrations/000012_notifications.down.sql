@@ -0,0 +1,2 @@
+-- Table: notifications
+DROP TABLE IF EXISTS "notifications";
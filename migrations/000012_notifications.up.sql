@@ -0,0 +1,11 @@
+-- Table: notifications
+
+CREATE TABLE "notifications" (
+  "id" BIGSERIAL PRIMARY KEY,
+  "user_id" INTEGER NOT NULL REFERENCES "users"("id"),
+  "type" VARCHAR(255) NOT NULL,
+  "message" TEXT NOT NULL,
+  "created_at" TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX "notifications_user_id_idx" ON "notifications" ("user_id", "id");
@@ -0,0 +1,3 @@
+-- Table: notifications
+DROP INDEX IF EXISTS "notifications_unread_idx";
+ALTER TABLE "notifications" DROP COLUMN IF EXISTS "read_at";
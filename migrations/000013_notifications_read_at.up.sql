@@ -0,0 +1,5 @@
+-- Table: notifications
+
+ALTER TABLE "notifications" ADD COLUMN "read_at" TIMESTAMP;
+
+CREATE INDEX "notifications_unread_idx" ON "notifications" ("user_id") WHERE "read_at" IS NULL;
@@ -0,0 +1,3 @@
+-- Table: notifications
+DROP INDEX IF EXISTS "notifications_type_idx";
+ALTER TABLE "notifications" DROP COLUMN IF EXISTS "event";
@@ -0,0 +1,14 @@
+-- Table: notifications
+
+ALTER TABLE "notifications" ADD COLUMN "event" JSONB;
+
+UPDATE "notifications" SET "event" = jsonb_build_object(
+  'version', 1,
+  'type', "type",
+  'occurred_at', to_char("created_at", 'YYYY-MM-DD"T"HH24:MI:SS.US"Z"'),
+  'payload', jsonb_build_object('message', "message")
+);
+
+ALTER TABLE "notifications" ALTER COLUMN "event" SET NOT NULL;
+
+CREATE INDEX "notifications_type_idx" ON "notifications" ("user_id", "type");
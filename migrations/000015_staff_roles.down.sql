@@ -0,0 +1,5 @@
+-- Table: cinema_staff
+DROP TABLE IF EXISTS "cinema_staff";
+
+-- Table: staff_roles
+DROP TABLE IF EXISTS "staff_roles";
@@ -0,0 +1,29 @@
+-- Table: staff_roles
+
+CREATE TABLE "staff_roles" (
+  "id" SERIAL PRIMARY KEY,
+  "cinema_id" INTEGER REFERENCES "cinemas"("id"),
+  "name" VARCHAR(255) NOT NULL,
+  "permissions" TEXT[] NOT NULL DEFAULT '{}',
+  "created_at" TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- Roles without a cinema can be assigned in every cinema.
+CREATE UNIQUE INDEX "staff_roles_name_idx" ON "staff_roles" (COALESCE("cinema_id", 0), "name");
+
+INSERT INTO "staff_roles" ("name", "permissions") VALUES
+  ('Manager', '{cinema.manage,rooms.manage,events.manage,tickets.validate,staff.manage,bookings.manage}'),
+  ('Programmer', '{rooms.manage,events.manage}'),
+  ('Ticket scanner', '{tickets.validate}');
+
+-- Table: cinema_staff
+
+CREATE TABLE "cinema_staff" (
+  "cinema_id" INTEGER NOT NULL REFERENCES "cinemas"("id"),
+  "user_id" INTEGER NOT NULL REFERENCES "users"("id"),
+  "role_id" INTEGER NOT NULL REFERENCES "staff_roles"("id"),
+  "created_at" TIMESTAMP NOT NULL DEFAULT NOW(),
+  PRIMARY KEY ("cinema_id", "user_id")
+);
+
+CREATE INDEX "cinema_staff_user_id_idx" ON "cinema_staff" ("user_id");
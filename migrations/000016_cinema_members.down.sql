@@ -0,0 +1,7 @@
+-- Table: cinema_invitations
+DROP TABLE IF EXISTS "cinema_invitations";
+
+-- Table: cinema_members
+ALTER INDEX "cinema_members_user_id_idx" RENAME TO "cinema_staff_user_id_idx";
+ALTER TABLE "cinema_members" RENAME CONSTRAINT "cinema_members_pkey" TO "cinema_staff_pkey";
+ALTER TABLE "cinema_members" RENAME TO "cinema_staff";
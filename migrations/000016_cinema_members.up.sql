@@ -0,0 +1,28 @@
+-- Table: cinema_members
+
+ALTER TABLE "cinema_staff" RENAME TO "cinema_members";
+ALTER TABLE "cinema_members" RENAME CONSTRAINT "cinema_staff_pkey" TO "cinema_members_pkey";
+ALTER INDEX "cinema_staff_user_id_idx" RENAME TO "cinema_members_user_id_idx";
+
+-- The user who created a cinema is its first manager.
+INSERT INTO "cinema_members" ("cinema_id", "user_id", "role_id")
+SELECT c."id", c."user_id", r."id"
+FROM "cinemas" c
+JOIN "staff_roles" r ON r."cinema_id" IS NULL AND r."name" = 'Manager'
+ON CONFLICT ("cinema_id", "user_id") DO UPDATE SET "role_id" = EXCLUDED."role_id";
+
+-- Table: cinema_invitations
+
+CREATE TABLE "cinema_invitations" (
+  "id" SERIAL PRIMARY KEY,
+  "cinema_id" INTEGER NOT NULL REFERENCES "cinemas"("id"),
+  "email" VARCHAR(255) NOT NULL,
+  "role_id" INTEGER NOT NULL REFERENCES "staff_roles"("id"),
+  "invited_by" INTEGER NOT NULL REFERENCES "users"("id"),
+  "token_hash" VARCHAR(64) UNIQUE NOT NULL,
+  "expires_at" TIMESTAMP NOT NULL,
+  "accepted_at" TIMESTAMP,
+  "created_at" TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX "cinema_invitations_cinema_id_idx" ON "cinema_invitations" ("cinema_id") WHERE "accepted_at" IS NULL;
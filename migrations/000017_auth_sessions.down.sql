@@ -0,0 +1,5 @@
+-- Table: refresh_tokens
+DROP TABLE IF EXISTS "refresh_tokens";
+
+-- Table: auth_sessions
+DROP TABLE IF EXISTS "auth_sessions";
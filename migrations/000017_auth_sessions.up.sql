@@ -0,0 +1,21 @@
+-- Table: auth_sessions
+
+CREATE TABLE "auth_sessions" (
+  "id" VARCHAR(64) PRIMARY KEY,
+  "user_id" INTEGER NOT NULL REFERENCES "users"("id"),
+  "created_at" TIMESTAMP NOT NULL DEFAULT NOW(),
+  "revoked_at" TIMESTAMP
+);
+
+CREATE INDEX "auth_sessions_user_id_idx" ON "auth_sessions" ("user_id") WHERE "revoked_at" IS NULL;
+
+-- Table: refresh_tokens
+
+CREATE TABLE "refresh_tokens" (
+  "id" BIGSERIAL PRIMARY KEY,
+  "session_id" VARCHAR(64) NOT NULL REFERENCES "auth_sessions"("id"),
+  "token_hash" VARCHAR(64) UNIQUE NOT NULL,
+  "expires_at" TIMESTAMP NOT NULL,
+  "used_at" TIMESTAMP,
+  "created_at" TIMESTAMP NOT NULL DEFAULT NOW()
+);
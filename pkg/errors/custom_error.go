@@ -5,20 +5,23 @@ import "net/http"
 type CustomError struct {
 	Key string
 	Err error
+	// Details is optional extra information sent back to the client, e.g. the seats that were already taken.
+	Details interface{}
 }
 
 func (ce CustomError) Error() string {
 	return ce.Err.Error()
 }
 
+func (ce CustomError) Unwrap() error {
+	return ce.Err
+}
+
 func (ce CustomError) StatusCode() int {
 	switch ce.Key {
 	case BadRequest:
 		return http.StatusBadRequest
-	case Unauthorized:
-	case InvalidCredentials:
-	case InvalidCode:
-	case ExpiredCode:
+	case Unauthorized, InvalidCredentials, InvalidCode, ExpiredCode:
 		return http.StatusUnauthorized
 	case Forbidden:
 		return http.StatusForbidden
@@ -26,8 +29,7 @@ func (ce CustomError) StatusCode() int {
 		return http.StatusNotFound
 	case MethodNotAllowed:
 		return http.StatusMethodNotAllowed
-	case Conflict:
-	case EmailAlreadyExists:
+	case Conflict, EmailAlreadyExists:
 		return http.StatusConflict
 	case UnsupportedMediaType:
 		return http.StatusUnsupportedMediaType
@@ -42,9 +44,8 @@ func (ce CustomError) StatusCode() int {
 	case GatewayTimeout:
 		return http.StatusGatewayTimeout
 	case InternalServerError:
+		return http.StatusInternalServerError
 	default:
 		return http.StatusInternalServerError
 	}
-
-	return http.StatusInternalServerError
 }
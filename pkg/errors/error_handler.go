@@ -1,6 +1,7 @@
 package errors
 
 import (
+	"encoding/json"
 	"net/http"
 )
 
@@ -9,6 +10,16 @@ type ErrorHandler func(w http.ResponseWriter, r *http.Request) error
 func (f ErrorHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if err := f(w, r); err != nil {
 		if e, ok := err.(CustomError); ok {
+			if e.Details != nil {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(e.StatusCode())
+				json.NewEncoder(w).Encode(map[string]interface{}{
+					"error":   e.Key,
+					"details": e.Details,
+				})
+				return
+			}
+
 			http.Error(w, e.Key, e.StatusCode())
 			return
 		}
@@ -1,13 +1,16 @@
 package generator
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
-	"math/rand"
+	mathRand "math/rand"
 	"time"
 )
 
 func GenerateRandomCode(length int) string {
-	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+	r := mathRand.New(mathRand.NewSource(time.Now().UnixNano()))
 
 	code := ""
 	for i := 0; i < length; i++ {
@@ -16,3 +19,20 @@ func GenerateRandomCode(length int) string {
 
 	return code
 }
+
+// GenerateToken returns size random bytes hex encoded, to be given to a user as a secret.
+func GenerateToken(size int) (string, error) {
+	bytes := make([]byte, size)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(bytes), nil
+}
+
+// HashToken returns the SHA-256 of a token generated by GenerateToken, which is what
+// gets stored so that a leaked database does not leak usable tokens.
+func HashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
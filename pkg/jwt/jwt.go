@@ -5,50 +5,86 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/cinema-booker/pkg/generator"
 	"github.com/golang-jwt/jwt/v5"
 )
 
-func Create(secret string, expirationInSec int, userId int) (string, error) {
-	expiration := time.Second * time.Duration(expirationInSec)
-	claims := jwt.MapClaims{
-		"userId":    strconv.Itoa(int(userId)),
-		"expiresAt": time.Now().Add(expiration).Unix(),
-	}
+// Claims are what an access token says about its bearer. The user id is the subject.
+type Claims struct {
+	jwt.RegisteredClaims
+	Role string `json:"role"`
+	// SessionId is the sign in the token was issued for, so that it can be revoked.
+	SessionId string `json:"sid"`
+}
+
+// UserId returns the user the token was issued to.
+func (c Claims) UserId() (int, error) {
+	return strconv.Atoi(c.Subject)
+}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(secret))
+// Create returns an access token for a user, signed with the signing key of keys.
+func Create(keys *KeySet, expirationInSec int, userId int, role string, sessionId string) (string, error) {
+	jti, err := generator.GenerateToken(16)
 	if err != nil {
 		return "", err
 	}
 
-	return tokenString, nil
+	now := time.Now()
+	claims := Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    keys.issuer,
+			Subject:   strconv.Itoa(userId),
+			Audience:  jwt.ClaimStrings{keys.audience},
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Second * time.Duration(expirationInSec))),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ID:        jti,
+		},
+		Role:      role,
+		SessionId: sessionId,
+	}
+
+	token := jwt.NewWithClaims(keys.signing.Method, claims)
+	token.Header["kid"] = keys.signing.Id
+
+	return token.SignedString(keys.signing.private)
 }
 
-func Validate(token, secret string) (*jwt.Token, error) {
-	return jwt.Parse(token, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+// Parse validates an access token issued by Create with one of keys, current or retired,
+// and returns its claims.
+func Parse(keys *KeySet, tokenString string) (Claims, error) {
+	claims := Claims{}
+	token, err := jwt.ParseWithClaims(tokenString, &claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, ok := keys.keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown key %q", kid)
+		}
+		// The algorithm of the token must be the one of the key, or a public key
+		// could be used as an HMAC secret.
+		if token.Method.Alg() != key.Method.Alg() {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
 
-		return []byte(secret), nil
-	})
-}
-
-func GetTokenUserId(tokenString, secret string) (int, error) {
-	token, err := Validate(tokenString, secret)
+		return key.public, nil
+	},
+		jwt.WithIssuer(keys.issuer),
+		jwt.WithAudience(keys.audience),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	)
 	if err != nil {
-		return -1, err
+		return Claims{}, err
 	}
 
 	if !token.Valid {
-		return -1, fmt.Errorf("invalid token")
+		return Claims{}, fmt.Errorf("invalid token")
 	}
-
-	claims := token.Claims.(jwt.MapClaims)
-	userId, err := strconv.Atoi(claims["userId"].(string))
-	if err != nil {
-		return -1, err
+	if _, err := claims.UserId(); err != nil {
+		return Claims{}, fmt.Errorf("invalid subject: %w", err)
+	}
+	if claims.SessionId == "" {
+		return Claims{}, fmt.Errorf("token has no session")
 	}
 
-	return userId, nil
+	return claims, nil
 }
@@ -0,0 +1,196 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Key is a key tokens are signed or verified with, told apart by its id, the "kid"
+// header of the tokens.
+type Key struct {
+	Id     string
+	Method jwt.SigningMethod
+	// private is nil for retired keys, which only verify the tokens they signed
+	// until those expire.
+	private interface{}
+	public  interface{}
+}
+
+// NewHMACKey returns a key signing tokens with HS256. Its secret cannot be published,
+// so only this API can verify the tokens.
+func NewHMACKey(id string, secret []byte) Key {
+	return Key{Id: id, Method: jwt.SigningMethodHS256, private: secret, public: secret}
+}
+
+// ParseKey reads a PEM encoded RSA or Ed25519 key. A private key signs with RS256 or
+// EdDSA, a public key can only verify.
+func ParseKey(id string, data []byte) (Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return Key{}, fmt.Errorf("key %s is not PEM encoded", id)
+	}
+
+	var parsed interface{}
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return Key{}, fmt.Errorf("key %s has unsupported PEM type %s", id, block.Type)
+	}
+	if err != nil {
+		return Key{}, fmt.Errorf("key %s: %w", id, err)
+	}
+
+	key := Key{Id: id}
+	if signer, ok := parsed.(crypto.Signer); ok {
+		key.private = signer
+		parsed = signer.Public()
+	}
+	switch public := parsed.(type) {
+	case *rsa.PublicKey:
+		key.Method = jwt.SigningMethodRS256
+		key.public = public
+	case ed25519.PublicKey:
+		key.Method = jwt.SigningMethodEdDSA
+		key.public = public
+	default:
+		return Key{}, fmt.Errorf("key %s has unsupported type %T", id, public)
+	}
+
+	return key, nil
+}
+
+// KeySet signs tokens with one key and verifies them with any of its keys, so that
+// the signing key can be rotated without signing everybody out.
+type KeySet struct {
+	issuer   string
+	audience string
+	signing  Key
+	keys     map[string]Key
+}
+
+func NewKeySet(issuer string, audience string, signing Key, retired ...Key) (*KeySet, error) {
+	if signing.private == nil {
+		return nil, fmt.Errorf("signing key %s has no private key", signing.Id)
+	}
+
+	keys := map[string]Key{signing.Id: signing}
+	for _, key := range retired {
+		if _, ok := keys[key.Id]; ok {
+			return nil, fmt.Errorf("duplicate key id %s", key.Id)
+		}
+		keys[key.Id] = key
+	}
+
+	return &KeySet{
+		issuer:   issuer,
+		audience: audience,
+		signing:  signing,
+		keys:     keys,
+	}, nil
+}
+
+// LoadKeySet reads the keys from the environment. Every <kid>.pem file of JWT_KEYS_DIR
+// is a key, JWT_SIGNING_KEY_ID being the one to sign with. Without JWT_KEYS_DIR, tokens
+// are signed with JWT_SECRET.
+func LoadKeySet() (*KeySet, error) {
+	issuer := os.Getenv("JWT_ISSUER")
+	audience := os.Getenv("JWT_AUDIENCE")
+	signingId := os.Getenv("JWT_SIGNING_KEY_ID")
+
+	dir := os.Getenv("JWT_KEYS_DIR")
+	if dir == "" {
+		secret := os.Getenv("JWT_SECRET")
+		if secret == "" {
+			return nil, fmt.Errorf("JWT_SECRET or JWT_KEYS_DIR is required")
+		}
+		return NewKeySet(issuer, audience, NewHMACKey(signingId, []byte(secret)))
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+
+	var signing Key
+	retired := []Key{}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		key, err := ParseKey(strings.TrimSuffix(filepath.Base(path), ".pem"), data)
+		if err != nil {
+			return nil, err
+		}
+
+		if key.Id == signingId {
+			signing = key
+		} else {
+			retired = append(retired, key)
+		}
+	}
+	if signing.Id == "" {
+		return nil, fmt.Errorf("signing key %s not found in %s", signingId, dir)
+	}
+
+	return NewKeySet(issuer, audience, signing, retired...)
+}
+
+// JWK is a public key as published in a JSON Web Key Set (RFC 7517).
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Ed25519
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKS returns the public keys of the set, for other services to verify tokens.
+// HMAC keys are secret and left out.
+func (s *KeySet) JWKS() map[string][]JWK {
+	jwks := []JWK{}
+	for _, key := range s.keys {
+		jwk := JWK{Kid: key.Id, Use: "sig", Alg: key.Method.Alg()}
+		switch public := key.public.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(public)
+		default:
+			continue
+		}
+		jwks = append(jwks, jwk)
+	}
+	slices.SortFunc(jwks, func(a, b JWK) int {
+		return strings.Compare(a.Kid, b.Kid)
+	})
+
+	return map[string][]JWK{"keys": jwks}
+}
@@ -0,0 +1,36 @@
+package ticket
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/skip2/go-qrcode"
+)
+
+// PNG renders content as a QR code image of size x size pixels.
+func PNG(content string, size int) ([]byte, error) {
+	return qrcode.Encode(content, qrcode.Medium, size)
+}
+
+// SVG renders content as a scalable QR code, one square per dark module.
+func SVG(content string) ([]byte, error) {
+	code, err := qrcode.New(content, qrcode.Medium)
+	if err != nil {
+		return nil, err
+	}
+	bitmap := code.Bitmap()
+
+	var svg strings.Builder
+	fmt.Fprintf(&svg, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" shape-rendering="crispEdges">`, len(bitmap), len(bitmap))
+	fmt.Fprintf(&svg, `<rect width="%d" height="%d" fill="#ffffff"/><path fill="#000000" d="`, len(bitmap), len(bitmap))
+	for y, row := range bitmap {
+		for x, dark := range row {
+			if dark {
+				fmt.Fprintf(&svg, "M%d %dh1v1h-1z", x, y)
+			}
+		}
+	}
+	svg.WriteString(`"/></svg>`)
+
+	return []byte(svg.String()), nil
+}
@@ -0,0 +1,53 @@
+package ticket
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// MinSecretLength is the minimum length of the secret signing tickets, in bytes.
+const MinSecretLength = 32
+
+// LoadSecret reads the secret signing tickets from TICKET_SECRET, which can be generated
+// with `openssl rand -base64 32`.
+func LoadSecret() (string, error) {
+	secret := os.Getenv("TICKET_SECRET")
+	if secret == "" {
+		return "", fmt.Errorf("TICKET_SECRET is required")
+	}
+	if len(secret) < MinSecretLength {
+		return "", fmt.Errorf("TICKET_SECRET must be at least %d bytes long", MinSecretLength)
+	}
+
+	return secret, nil
+}
+
+// Sign returns the token printed on a ticket: its id followed by an HMAC-SHA256
+// signature of the id, so that tokens cannot be forged without the secret.
+func Sign(secret string, ticketId string) string {
+	return ticketId + "." + signature(secret, ticketId)
+}
+
+// Verify checks the signature of a token and returns the ticket id it carries.
+func Verify(secret string, token string) (string, error) {
+	ticketId, sig, ok := strings.Cut(token, ".")
+	if !ok || ticketId == "" {
+		return "", fmt.Errorf("malformed ticket token")
+	}
+	if !hmac.Equal([]byte(sig), []byte(signature(secret, ticketId))) {
+		return "", fmt.Errorf("invalid ticket signature")
+	}
+
+	return ticketId, nil
+}
+
+func signature(secret string, ticketId string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ticketId))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,52 @@
+package analytics_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cinema-booker/internal/analytics"
+	"github.com/cinema-booker/internal/constants"
+	"github.com/cinema-booker/internal/outbox"
+	"github.com/stretchr/testify/require"
+)
+
+// TestDeliver
+func TestDeliver(t *testing.T) {
+	tracker := analytics.NewMemory()
+	analyticsService := analytics.NewService(tracker)
+
+	payload, err := json.Marshal(analytics.Event{
+		Name:       constants.AnalyticsEventOrderConfirmed,
+		Properties: map[string]interface{}{"order_id": 7},
+	})
+	require.NoError(t, err)
+
+	queuedAt := time.Date(2030, time.March, 8, 20, 30, 0, 0, time.UTC)
+	err = analyticsService.Deliver(outbox.Message{
+		Topic:     constants.OutboxTopicAnalytics,
+		Payload:   payload,
+		CreatedAt: queuedAt,
+	})
+	require.NoError(t, err)
+
+	tracked := tracker.Tracked()
+	require.Len(t, tracked, 1)
+	require.Equal(t, constants.AnalyticsEventOrderConfirmed, tracked[0].Name)
+	require.Equal(t, float64(7), tracked[0].Properties["order_id"])
+	require.Equal(t, queuedAt, tracked[0].OccurredAt)
+}
+
+// TestWebhookRejectedEvent
+func TestWebhookRejectedEvent(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	// Failing lets the outbox dispatcher retry the event later.
+	err := analytics.NewWebhook(server.URL).Track(analytics.Event{Name: constants.AnalyticsEventOrderConfirmed})
+	require.Error(t, err)
+}
@@ -0,0 +1,101 @@
+package booking
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/cinema-booker/internal/booking"
+	"github.com/cinema-booker/internal/cinema"
+	"github.com/cinema-booker/internal/constants"
+	"github.com/cinema-booker/internal/event"
+	"github.com/cinema-booker/internal/notification"
+	"github.com/cinema-booker/internal/outbox"
+	"github.com/cinema-booker/internal/room"
+	"github.com/cinema-booker/third_party/mailer"
+	"github.com/cinema-booker/third_party/payment"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSendConfirmationEmail
+func TestSendConfirmationEmail(t *testing.T) {
+	mockStore := new(MockBookingStore)
+	mails := mailer.NewMemory()
+	bookingService := booking.NewService(mockStore, new(MockSessionStore), new(MockCinemaAuthorizer), payment.NewFake("whsec_test"), mails, emails, notification.NewBroker(8), config)
+
+	mockStore.On("FindOrderById", 0, constants.UserRoleAdmin, 7).Return(booking.Order{
+		Id:     7,
+		Seats:  []string{"A1", "A2"},
+		Amount: 1600,
+		Status: constants.OrderStatusConfirmed,
+		User:   booking.User{Id: 1, Name: "Jane", Email: "jane@example.com"},
+		Session: booking.SessionWithEvent{
+			StartsAt: time.Date(2030, time.March, 8, 20, 30, 0, 0, time.UTC),
+			Room:     room.Room{Number: "3"},
+			Event: booking.EventBasic{
+				Cinema: cinema.Cinema{
+					Name:    "Le Grand Rex",
+					Address: cinema.Address{Address: "1 Boulevard Poissonnière, Paris"},
+				},
+				Movie: event.Movie{Title: "Dune"},
+			},
+		},
+	}, nil)
+
+	payload, err := json.Marshal(booking.ConfirmationEmailPayload{OrderId: 7})
+	require.NoError(t, err)
+
+	err = bookingService.SendConfirmationEmail(outbox.Message{
+		Topic:   constants.OutboxTopicConfirmationEmail,
+		Payload: payload,
+	})
+	require.NoError(t, err)
+
+	sent := mails.Sent()
+	require.Len(t, sent, 1)
+	require.Equal(t, []string{"jane@example.com"}, sent[0].To)
+	require.Contains(t, sent[0].Subject, "Dune")
+	for _, content := range []string{sent[0].HTML, sent[0].Text} {
+		require.Contains(t, content, "Le Grand Rex")
+		require.Contains(t, content, "1 Boulevard Poissonnière, Paris")
+		require.Contains(t, content, "A1, A2")
+		require.Contains(t, content, "Friday 8 March 2030 at 20:30")
+		require.Contains(t, content, "16.00 EUR")
+	}
+}
+
+// TestSendCancellationEmail
+func TestSendCancellationEmail(t *testing.T) {
+	mockStore := new(MockBookingStore)
+	mails := mailer.NewMemory()
+	bookingService := booking.NewService(mockStore, new(MockSessionStore), new(MockCinemaAuthorizer), payment.NewFake("whsec_test"), mails, emails, notification.NewBroker(8), config)
+
+	// The order no longer lists the seats once they are canceled.
+	mockStore.On("FindOrderById", 0, constants.UserRoleAdmin, 7).Return(booking.Order{
+		Id:     7,
+		Seats:  []string{},
+		Amount: 1600,
+		Status: constants.OrderStatusCanceled,
+		User:   booking.User{Id: 1, Name: "Jane", Email: "jane@example.com"},
+		Session: booking.SessionWithEvent{
+			StartsAt: time.Date(2030, time.March, 8, 20, 30, 0, 0, time.UTC),
+			Event: booking.EventBasic{
+				Cinema: cinema.Cinema{Name: "Le Grand Rex"},
+				Movie:  event.Movie{Title: "Dune"},
+			},
+		},
+	}, nil)
+
+	payload, err := json.Marshal(booking.CancellationEmailPayload{OrderId: 7, Seats: []string{"A1", "A2"}, Refund: 1600})
+	require.NoError(t, err)
+
+	err = bookingService.SendCancellationEmail(outbox.Message{
+		Topic:   constants.OutboxTopicCancellationEmail,
+		Payload: payload,
+	})
+	require.NoError(t, err)
+
+	sent := mails.Sent()
+	require.Len(t, sent, 1)
+	require.Contains(t, sent[0].Text, "A1, A2")
+}
@@ -0,0 +1,573 @@
+package booking
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cinema-booker/api/handler"
+	"github.com/cinema-booker/internal/booking"
+	"github.com/cinema-booker/internal/cinema"
+	"github.com/cinema-booker/internal/constants"
+	"github.com/cinema-booker/internal/email"
+	"github.com/cinema-booker/internal/notification"
+	"github.com/cinema-booker/internal/outbox"
+	"github.com/cinema-booker/internal/room"
+	"github.com/cinema-booker/internal/session"
+	"github.com/cinema-booker/pkg/errors"
+	"github.com/cinema-booker/third_party/mailer"
+	"github.com/cinema-booker/third_party/payment"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockBookingStore struct {
+	mock.Mock
+}
+
+// FindAll implements booking.BookingStore.
+func (m *MockBookingStore) FindAll(userId int, userRole string, pagination map[string]int, search string) ([]booking.Booking, error) {
+	args := m.Called(userId, userRole, pagination, search)
+	return args.Get(0).([]booking.Booking), args.Error(1)
+}
+
+// FindById implements booking.BookingStore.
+func (m *MockBookingStore) FindById(userId int, userRole string, id int) (booking.Booking, error) {
+	args := m.Called(userId, userRole, id)
+	return args.Get(0).(booking.Booking), args.Error(1)
+}
+
+// FindByOrderId implements booking.BookingStore.
+func (m *MockBookingStore) FindByOrderId(userId int, userRole string, orderId int) ([]booking.Booking, error) {
+	args := m.Called(userId, userRole, orderId)
+	return args.Get(0).([]booking.Booking), args.Error(1)
+}
+
+// FindSessionSeats implements booking.BookingStore.
+func (m *MockBookingStore) FindSessionSeats(sessionId int, now time.Time) (booking.SessionSeats, error) {
+	args := m.Called(sessionId, now)
+	return args.Get(0).(booking.SessionSeats), args.Error(1)
+}
+
+// Reserve implements booking.BookingStore.
+func (m *MockBookingStore) Reserve(userId int, sessionId int, seats []string, expiresAt time.Time) (booking.Order, []string, error) {
+	args := m.Called(userId, sessionId, seats, expiresAt)
+	return args.Get(0).(booking.Order), args.Get(1).([]string), args.Error(2)
+}
+
+// ExpirePending implements booking.BookingStore.
+func (m *MockBookingStore) ExpirePending(now time.Time) ([]booking.SeatChange, error) {
+	args := m.Called(now)
+	return args.Get(0).([]booking.SeatChange), args.Error(1)
+}
+
+// Update implements booking.BookingStore.
+func (m *MockBookingStore) Update(id int, input map[string]interface{}) error {
+	return m.Called(id, input).Error(0)
+}
+
+// Cancel implements booking.BookingStore.
+func (m *MockBookingStore) Cancel(id int, status string, refundPercent int) error {
+	return m.Called(id, status, refundPercent).Error(0)
+}
+
+// FindAllOrders implements booking.BookingStore.
+func (m *MockBookingStore) FindAllOrders(userId int, userRole string, pagination map[string]int) ([]booking.Order, error) {
+	args := m.Called(userId, userRole, pagination)
+	return args.Get(0).([]booking.Order), args.Error(1)
+}
+
+// FindOrderById implements booking.BookingStore.
+func (m *MockBookingStore) FindOrderById(userId int, userRole string, id int) (booking.Order, error) {
+	args := m.Called(userId, userRole, id)
+	return args.Get(0).(booking.Order), args.Error(1)
+}
+
+// CancelOrder implements booking.BookingStore.
+func (m *MockBookingStore) CancelOrder(id int, status string, refundPercent int) ([]string, error) {
+	args := m.Called(id, status, refundPercent)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+// CancelSession implements booking.BookingStore.
+func (m *MockBookingStore) CancelSession(id int, canceledAt time.Time) error {
+	return m.Called(id, canceledAt).Error(0)
+}
+
+// FindRefundById implements booking.BookingStore.
+func (m *MockBookingStore) FindRefundById(id int) (booking.Refund, error) {
+	args := m.Called(id)
+	return args.Get(0).(booking.Refund), args.Error(1)
+}
+
+// SetRefundPaymentId implements booking.BookingStore.
+func (m *MockBookingStore) SetRefundPaymentId(id int, paymentRefundId string) error {
+	return m.Called(id, paymentRefundId).Error(0)
+}
+
+// CompleteRefunds implements booking.BookingStore.
+func (m *MockBookingStore) CompleteRefunds(orderId int, eventId string, paymentRefundIds []string, refundedAt time.Time) error {
+	return m.Called(orderId, eventId, paymentRefundIds, refundedAt).Error(0)
+}
+
+// SetOrderPaymentReference implements booking.BookingStore.
+func (m *MockBookingStore) SetOrderPaymentReference(id int, reference string) error {
+	return m.Called(id, reference).Error(0)
+}
+
+// ConfirmOrder implements booking.BookingStore.
+func (m *MockBookingStore) ConfirmOrder(id int, eventId string) (booking.OrderWithUsers, error) {
+	args := m.Called(id, eventId)
+	return args.Get(0).(booking.OrderWithUsers), args.Error(1)
+}
+
+// UseTicket implements booking.BookingStore.
+func (m *MockBookingStore) UseTicket(ticketId string, userId int, userRole string, usedAt time.Time) (booking.Booking, error) {
+	args := m.Called(ticketId, userId, userRole, usedAt)
+	return args.Get(0).(booking.Booking), args.Error(1)
+}
+
+// ExpireOrder implements booking.BookingStore.
+func (m *MockBookingStore) ExpireOrder(id int, eventId string) (booking.SeatChange, error) {
+	args := m.Called(id, eventId)
+	return args.Get(0).(booking.SeatChange), args.Error(1)
+}
+
+type MockCinemaAuthorizer struct {
+	mock.Mock
+}
+
+// Authorize implements booking.CinemaAuthorizer.
+func (m *MockCinemaAuthorizer) Authorize(ctx context.Context, id int, permission string) error {
+	return m.Called(ctx, id, permission).Error(0)
+}
+
+type MockSessionStore struct {
+	mock.Mock
+}
+
+// FindById implements session.SessionStore.
+func (m *MockSessionStore) FindById(id int) (session.Session, error) {
+	args := m.Called(id)
+	return args.Get(0).(session.Session), args.Error(1)
+}
+
+// FindEventId implements session.SessionStore.
+func (m *MockSessionStore) FindEventId(id int) (int, error) {
+	args := m.Called(id)
+	return args.Int(0), args.Error(1)
+}
+
+// HasRoom implements session.SessionStore.
+func (m *MockSessionStore) HasRoom(eventId int, roomId int) (bool, error) {
+	args := m.Called(eventId, roomId)
+	return args.Bool(0), args.Error(1)
+}
+
+// Create implements session.SessionStore.
+func (m *MockSessionStore) Create(input map[string]interface{}) error {
+	return m.Called(input).Error(0)
+}
+
+// Update implements session.SessionStore.
+func (m *MockSessionStore) Update(id int, input map[string]interface{}) error {
+	return m.Called(id, input).Error(0)
+}
+
+// GetDashboardData implements session.SessionStore.
+func (m *MockSessionStore) GetDashboardData(userId int) (session.FlatDashboardResponse, error) {
+	args := m.Called(userId)
+	return args.Get(0).(session.FlatDashboardResponse), args.Error(1)
+}
+
+func newSession() session.Session {
+	return session.Session{
+		Id:    1,
+		Price: 800,
+		Room: room.Room{
+			Id:     1,
+			Number: "1",
+			Layout: room.Layout{
+				Rows: []room.Row{
+					{
+						Label: "A",
+						Seats: []room.Seat{
+							{Number: 1, Category: constants.SeatCategoryStandard},
+							{Number: 2, Category: constants.SeatCategoryStandard},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func newSessionWithEvent(startsIn time.Duration) booking.SessionWithEvent {
+	return booking.SessionWithEvent{
+		Id:       1,
+		Price:    800,
+		StartsAt: time.Now().Add(startsIn),
+		Event: booking.EventBasic{
+			Cinema: cinema.Cinema{
+				CancellationPolicy: cinema.CancellationPolicy{
+					Rules: []cinema.CancellationRule{
+						{HoursBefore: 24, RefundPercent: 100},
+						{HoursBefore: 2, RefundPercent: 50},
+					},
+				},
+			},
+		},
+	}
+}
+
+var emails, _ = email.NewRegistry(constants.DefaultLocale)
+
+var config = booking.Config{
+	HoldDuration: 30 * time.Minute,
+	Currency:     "eur",
+	TicketSecret: "ticket_secret",
+}
+
+func authenticated() context.Context {
+	ctx := context.WithValue(context.Background(), constants.UserIDKey, 1)
+	return context.WithValue(ctx, constants.UserRoleKey, constants.UserRoleViewer)
+}
+
+// TestBookingPaymentConfirmation
+func TestBookingPaymentConfirmation(t *testing.T) {
+	mockStore := new(MockBookingStore)
+	mockSessionStore := new(MockSessionStore)
+	payments := payment.NewFake("whsec_test")
+	bookingService := booking.NewService(mockStore, mockSessionStore, new(MockCinemaAuthorizer), payments, mailer.NewMemory(), emails, notification.NewBroker(8), config)
+
+	mockSessionStore.On("FindById", 1).Return(newSession(), nil)
+	mockStore.On("Reserve", 1, 1, []string{"A1", "A2"}, mock.Anything).Return(booking.Order{Id: 7, Amount: 1600}, []string{}, nil)
+	mockStore.On("SetOrderPaymentReference", 7, "cs_fake_1").Return(nil)
+	mockStore.On("ConfirmOrder", 7, "evt_fake_1").Return(booking.OrderWithUsers{OrderId: 7, Status: constants.OrderStatusConfirmed, Seats: []string{"A1", "A2"}}, nil)
+
+	response, err := bookingService.Create(authenticated(), map[string]interface{}{
+		"session_id": float64(1),
+		"seats":      []interface{}{"A1", "A2"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, 7, response["order_id"])
+	require.Equal(t, "https://payments.fake/checkout/cs_fake_1", response["checkout_url"])
+
+	checkout, ok := payments.Checkout("cs_fake_1")
+	require.True(t, ok)
+	require.Equal(t, 7, checkout.OrderId)
+	require.Equal(t, int64(1600), checkout.Amount())
+
+	payload, signature, err := payments.Webhook("cs_fake_1", payment.EventCheckoutCompleted)
+	require.NoError(t, err)
+
+	req, err := http.NewRequest(http.MethodPost, "/webhook", bytes.NewReader(payload))
+	require.NoError(t, err)
+	req.Header.Set("Stripe-Signature", signature)
+
+	rr := httptest.NewRecorder()
+	errors.ErrorHandler(handler.NewWebhookHandler(bookingService, payments).Handle).ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	mockStore.AssertExpectations(t)
+
+	status, err := payments.PaymentStatus("cs_fake_1")
+	require.NoError(t, err)
+	require.Equal(t, payment.StatusPaid, status)
+}
+
+// TestBookingUnknownSeat
+func TestBookingUnknownSeat(t *testing.T) {
+	mockStore := new(MockBookingStore)
+	mockSessionStore := new(MockSessionStore)
+	bookingService := booking.NewService(mockStore, mockSessionStore, new(MockCinemaAuthorizer), payment.NewFake("whsec_test"), mailer.NewMemory(), emails, notification.NewBroker(8), config)
+
+	mockSessionStore.On("FindById", 1).Return(newSession(), nil)
+
+	_, err := bookingService.Create(authenticated(), map[string]interface{}{
+		"session_id": float64(1),
+		"seats":      []interface{}{"ZZ999"},
+	})
+	require.Error(t, err)
+	mockStore.AssertNotCalled(t, "Reserve", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+// TestWebhookInvalidSignature
+func TestWebhookInvalidSignature(t *testing.T) {
+	payments := payment.NewFake("whsec_test")
+	bookingService := booking.NewService(new(MockBookingStore), new(MockSessionStore), new(MockCinemaAuthorizer), payments, mailer.NewMemory(), emails, notification.NewBroker(8), config)
+
+	req, err := http.NewRequest(http.MethodPost, "/webhook", bytes.NewReader([]byte(`{}`)))
+	require.NoError(t, err)
+	req.Header.Set("Stripe-Signature", "forged")
+
+	rr := httptest.NewRecorder()
+	errors.ErrorHandler(handler.NewWebhookHandler(bookingService, payments).Handle).ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+// TestCancelConfirmedOrderRefunds
+func TestCancelConfirmedOrderRefunds(t *testing.T) {
+	mockStore := new(MockBookingStore)
+	cinemas := new(MockCinemaAuthorizer)
+	payments := payment.NewFake("whsec_test")
+	bookingService := booking.NewService(mockStore, new(MockSessionStore), cinemas, payments, mailer.NewMemory(), emails, notification.NewBroker(8), config)
+
+	reference := "cs_fake_1"
+	cinemas.On("Authorize", mock.Anything, 0, constants.PermissionBookingsManage).Return(errors.CustomError{Key: errors.Forbidden})
+	mockStore.On("FindOrderById", 1, constants.UserRoleViewer, 7).Return(booking.Order{
+		Id:               7,
+		Amount:           1600,
+		RefundedAmount:   800,
+		Status:           constants.OrderStatusConfirmed,
+		PaymentReference: &reference,
+		Session:          newSessionWithEvent(5 * time.Hour),
+	}, nil)
+	mockStore.On("CancelOrder", 7, constants.OrderStatusConfirmed, 50).Return([]string{"A2"}, nil)
+
+	require.NoError(t, bookingService.CancelOrder(authenticated(), 7))
+	mockStore.AssertExpectations(t)
+}
+
+// TestSendRefund
+func TestSendRefund(t *testing.T) {
+	mockStore := new(MockBookingStore)
+	payments := payment.NewFake("whsec_test")
+	bookingService := booking.NewService(mockStore, new(MockSessionStore), new(MockCinemaAuthorizer), payments, mailer.NewMemory(), emails, notification.NewBroker(8), config)
+
+	checkout, err := payments.CreateCheckout(payment.CheckoutRequest{
+		OrderId: 7,
+		Items:   []payment.LineItem{{Name: "Ticket", UnitAmount: 800, Quantity: 2}},
+	})
+	require.NoError(t, err)
+	_, _, err = payments.Webhook(checkout.Id, payment.EventCheckoutCompleted)
+	require.NoError(t, err)
+
+	mockStore.On("FindRefundById", 3).Return(booking.Refund{
+		Id:      3,
+		OrderId: 7,
+		Amount:  1600,
+		Status:  constants.RefundStatusRequested,
+	}, nil)
+	mockStore.On("FindOrderById", 0, constants.UserRoleAdmin, 7).Return(booking.Order{
+		Id:               7,
+		Amount:           1600,
+		PaymentReference: &checkout.Id,
+	}, nil)
+	mockStore.On("SetRefundPaymentId", 3, "re_fake_1").Return(nil)
+
+	payload, err := json.Marshal(booking.RefundPayload{RefundId: 3})
+	require.NoError(t, err)
+
+	// A message delivered again, e.g. after the refund id failed to be stored, refunds only once.
+	for i := 0; i < 2; i++ {
+		err = bookingService.SendRefund(outbox.Message{
+			Topic:   constants.OutboxTopicRefund,
+			Payload: payload,
+		})
+		require.NoError(t, err)
+	}
+	mockStore.AssertNumberOfCalls(t, "SetRefundPaymentId", 2)
+
+	status, err := payments.PaymentStatus(checkout.Id)
+	require.NoError(t, err)
+	require.Equal(t, payment.StatusRefunded, status)
+}
+
+// TestCancelAfterCancellationPeriod
+func TestCancelAfterCancellationPeriod(t *testing.T) {
+	mockStore := new(MockBookingStore)
+	cinemas := new(MockCinemaAuthorizer)
+	bookingService := booking.NewService(mockStore, new(MockSessionStore), cinemas, payment.NewFake("whsec_test"), mailer.NewMemory(), emails, notification.NewBroker(8), config)
+
+	reference := "cs_fake_1"
+	cinemas.On("Authorize", mock.Anything, 0, constants.PermissionBookingsManage).Return(errors.CustomError{Key: errors.Forbidden})
+	mockStore.On("FindOrderById", 1, constants.UserRoleViewer, 7).Return(booking.Order{
+		Id:               7,
+		Amount:           1600,
+		Status:           constants.OrderStatusConfirmed,
+		PaymentReference: &reference,
+		Session:          newSessionWithEvent(time.Hour),
+	}, nil)
+
+	err := bookingService.CancelOrder(authenticated(), 7)
+	require.Error(t, err)
+
+	customErr, ok := err.(errors.CustomError)
+	require.True(t, ok)
+	require.Equal(t, errors.Forbidden, customErr.Key)
+	mockStore.AssertNotCalled(t, "CancelOrder", mock.Anything, mock.Anything, mock.Anything)
+}
+
+// TestCancelAfterCancellationPeriodWithPermission
+func TestCancelAfterCancellationPeriodWithPermission(t *testing.T) {
+	mockStore := new(MockBookingStore)
+	cinemas := new(MockCinemaAuthorizer)
+	bookingService := booking.NewService(mockStore, new(MockSessionStore), cinemas, payment.NewFake("whsec_test"), mailer.NewMemory(), emails, notification.NewBroker(8), config)
+
+	reference := "cs_fake_1"
+	cinemas.On("Authorize", mock.Anything, 0, constants.PermissionBookingsManage).Return(nil)
+	mockStore.On("FindOrderById", 1, constants.UserRoleViewer, 7).Return(booking.Order{
+		Id:               7,
+		Amount:           1600,
+		Status:           constants.OrderStatusConfirmed,
+		PaymentReference: &reference,
+		Session:          newSessionWithEvent(time.Hour),
+	}, nil)
+	mockStore.On("CancelOrder", 7, constants.OrderStatusConfirmed, 100).Return([]string{"A1", "A2"}, nil)
+
+	require.NoError(t, bookingService.CancelOrder(authenticated(), 7))
+	mockStore.AssertExpectations(t)
+}
+
+// TestReleaseHoldAfterCancellationPeriod
+func TestReleaseHoldAfterCancellationPeriod(t *testing.T) {
+	mockStore := new(MockBookingStore)
+	cinemas := new(MockCinemaAuthorizer)
+	bookingService := booking.NewService(mockStore, new(MockSessionStore), cinemas, payment.NewFake("whsec_test"), mailer.NewMemory(), emails, notification.NewBroker(8), config)
+
+	mockStore.On("FindOrderById", 1, constants.UserRoleViewer, 7).Return(booking.Order{
+		Id:      7,
+		Amount:  1600,
+		Status:  constants.OrderStatusPending,
+		Session: newSessionWithEvent(time.Hour),
+	}, nil)
+	mockStore.On("CancelOrder", 7, constants.OrderStatusPending, 0).Return([]string{"A1", "A2"}, nil)
+
+	require.NoError(t, bookingService.CancelOrder(authenticated(), 7))
+	mockStore.AssertExpectations(t)
+	cinemas.AssertNotCalled(t, "Authorize", mock.Anything, mock.Anything, mock.Anything)
+}
+
+// TestCancelOrderConcurrently
+func TestCancelOrderConcurrently(t *testing.T) {
+	mockStore := new(MockBookingStore)
+	cinemas := new(MockCinemaAuthorizer)
+	bookingService := booking.NewService(mockStore, new(MockSessionStore), cinemas, payment.NewFake("whsec_test"), mailer.NewMemory(), emails, notification.NewBroker(8), config)
+
+	reference := "cs_fake_1"
+	cinemas.On("Authorize", mock.Anything, 0, constants.PermissionBookingsManage).Return(nil)
+	mockStore.On("FindOrderById", 1, constants.UserRoleViewer, 7).Return(booking.Order{
+		Id:               7,
+		Amount:           1600,
+		Status:           constants.OrderStatusConfirmed,
+		PaymentReference: &reference,
+		Session:          newSessionWithEvent(48 * time.Hour),
+	}, nil)
+	// Another request canceled the order between the read and the lock.
+	mockStore.On("CancelOrder", 7, constants.OrderStatusConfirmed, 100).Return([]string(nil), booking.ErrStatusChanged)
+
+	err := bookingService.CancelOrder(authenticated(), 7)
+	require.Error(t, err)
+	require.Equal(t, http.StatusConflict, err.(errors.CustomError).StatusCode())
+}
+
+// TestCancelSessionTwice
+func TestCancelSessionTwice(t *testing.T) {
+	mockStore := new(MockBookingStore)
+	bookingService := booking.NewService(mockStore, new(MockSessionStore), new(MockCinemaAuthorizer), payment.NewFake("whsec_test"), mailer.NewMemory(), emails, notification.NewBroker(8), config)
+
+	mockStore.On("CancelSession", 1, mock.Anything).Return(nil).Once()
+	mockStore.On("CancelSession", 1, mock.Anything).Return(booking.ErrSessionCanceled).Once()
+
+	require.NoError(t, bookingService.CancelSession(1))
+
+	err := bookingService.CancelSession(1)
+	require.Error(t, err)
+	require.Equal(t, http.StatusConflict, err.(errors.CustomError).StatusCode())
+	mockStore.AssertExpectations(t)
+}
+
+// TestWebhookDuplicateEvent
+func TestWebhookDuplicateEvent(t *testing.T) {
+	mockStore := new(MockBookingStore)
+	payments := payment.NewFake("whsec_test")
+	bookingService := booking.NewService(mockStore, new(MockSessionStore), new(MockCinemaAuthorizer), payments, mailer.NewMemory(), emails, notification.NewBroker(8), config)
+
+	checkout, err := payments.CreateCheckout(payment.CheckoutRequest{
+		OrderId: 7,
+		Items:   []payment.LineItem{{Name: "Ticket", UnitAmount: 800, Quantity: 1}},
+	})
+	require.NoError(t, err)
+
+	payload, signature, err := payments.Webhook(checkout.Id, payment.EventCheckoutExpired)
+	require.NoError(t, err)
+
+	mockStore.On("ExpireOrder", 7, "evt_fake_1").Return(booking.SeatChange{SessionId: 1, Seats: []string{"A1"}}, nil).Once()
+	mockStore.On("ExpireOrder", 7, "evt_fake_1").Return(booking.SeatChange{}, booking.ErrPaymentEventProcessed).Once()
+
+	for i := 0; i < 2; i++ {
+		req, err := http.NewRequest(http.MethodPost, "/webhook", bytes.NewReader(payload))
+		require.NoError(t, err)
+		req.Header.Set("Stripe-Signature", signature)
+
+		rr := httptest.NewRecorder()
+		errors.ErrorHandler(handler.NewWebhookHandler(bookingService, payments).Handle).ServeHTTP(rr, req)
+
+		require.Equal(t, http.StatusOK, rr.Code)
+	}
+	mockStore.AssertExpectations(t)
+}
+
+// TestWebhookRefundedCompletesItsRefunds
+func TestWebhookRefundedCompletesItsRefunds(t *testing.T) {
+	mockStore := new(MockBookingStore)
+	payments := payment.NewFake("whsec_test")
+	bookingService := booking.NewService(mockStore, new(MockSessionStore), new(MockCinemaAuthorizer), payments, mailer.NewMemory(), emails, notification.NewBroker(8), config)
+
+	checkout, err := payments.CreateCheckout(payment.CheckoutRequest{
+		OrderId: 7,
+		Items:   []payment.LineItem{{Name: "Ticket", UnitAmount: 800, Quantity: 2}},
+	})
+	require.NoError(t, err)
+	_, _, err = payments.Webhook(checkout.Id, payment.EventCheckoutCompleted)
+	require.NoError(t, err)
+	_, err = payments.Refund(checkout.Id, 800, "refund-3")
+	require.NoError(t, err)
+
+	payload, signature, err := payments.Webhook(checkout.Id, payment.EventRefunded)
+	require.NoError(t, err)
+
+	// Only the refund settled by the provider succeeds, not every pending refund of the order.
+	mockStore.On("CompleteRefunds", 7, "evt_fake_2", []string{"re_fake_1"}, mock.Anything).Return(nil)
+
+	req, err := http.NewRequest(http.MethodPost, "/webhook", bytes.NewReader(payload))
+	require.NoError(t, err)
+	req.Header.Set("Stripe-Signature", signature)
+
+	rr := httptest.NewRecorder()
+	errors.ErrorHandler(handler.NewWebhookHandler(bookingService, payments).Handle).ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	mockStore.AssertExpectations(t)
+}
+
+// TestLatePaymentIsNotConfirmed
+func TestLatePaymentIsNotConfirmed(t *testing.T) {
+	mockStore := new(MockBookingStore)
+	publisher := notification.NewBroker(8)
+	bookingService := booking.NewService(mockStore, new(MockSessionStore), new(MockCinemaAuthorizer), payment.NewFake("whsec_test"), mailer.NewMemory(), emails, publisher, config)
+
+	subscription := publisher.Subscribe(booking.SeatsTopic(1))
+	defer subscription.Close()
+
+	// The store refunds the payment in the transaction that records the event.
+	mockStore.On("ConfirmOrder", 7, "evt_fake_2").Return(booking.OrderWithUsers{
+		OrderId:   7,
+		SessionId: 1,
+		Status:    constants.OrderStatusExpired,
+		Amount:    800,
+		Seats:     []string{"A1"},
+	}, nil)
+
+	order, err := bookingService.ConfirmOrder(7, "evt_fake_2")
+	require.NoError(t, err)
+	require.Equal(t, constants.OrderStatusExpired, order.Status)
+	require.Empty(t, subscription.C)
+	mockStore.AssertExpectations(t)
+}
@@ -0,0 +1,136 @@
+package booking
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/cinema-booker/internal/booking"
+	"github.com/cinema-booker/internal/constants"
+	"github.com/cinema-booker/internal/notification"
+	"github.com/cinema-booker/third_party/mailer"
+	"github.com/cinema-booker/third_party/payment"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type seatEvent struct {
+	Type      string
+	SessionId int
+	Seats     []string
+}
+
+func nextSeatEvent(t *testing.T, subscription *notification.Subscription) seatEvent {
+	select {
+	case message, ok := <-subscription.C:
+		require.True(t, ok)
+		var event notification.Event
+		require.NoError(t, json.Unmarshal(message, &event))
+		require.Equal(t, notification.EventVersion, event.Version)
+		var payload booking.SeatsPayload
+		require.NoError(t, event.Decode(&payload))
+		return seatEvent{Type: event.Type, SessionId: event.SessionId, Seats: payload.Seats}
+	case <-time.After(time.Second):
+		t.Fatal("no seat event published")
+		return seatEvent{}
+	}
+}
+
+// TestSeatEventsFollowOrder
+func TestSeatEventsFollowOrder(t *testing.T) {
+	mockStore := new(MockBookingStore)
+	mockSessionStore := new(MockSessionStore)
+	broker := notification.NewBroker(8)
+	bookingService := booking.NewService(mockStore, mockSessionStore, new(MockCinemaAuthorizer), payment.NewFake("whsec_test"), mailer.NewMemory(), emails, broker, config)
+
+	subscription := broker.Subscribe(booking.SeatsTopic(1))
+	defer subscription.Close()
+
+	mockSessionStore.On("FindById", 1).Return(newSession(), nil)
+	mockStore.On("Reserve", 1, 1, []string{"A1"}, mock.Anything).Return(booking.Order{Id: 7, Amount: 800}, []string{"A2"}, nil)
+	mockStore.On("SetOrderPaymentReference", 7, "cs_fake_1").Return(nil)
+	mockStore.On("ConfirmOrder", 7, "evt_1").Return(booking.OrderWithUsers{OrderId: 7, SessionId: 1, Status: constants.OrderStatusConfirmed, Seats: []string{"A1"}}, nil)
+
+	_, err := bookingService.Create(authenticated(), map[string]interface{}{
+		"session_id": float64(1),
+		"seats":      []interface{}{"A1"},
+	})
+	require.NoError(t, err)
+
+	event := nextSeatEvent(t, subscription)
+	require.Equal(t, constants.SeatEventReleased, event.Type)
+	require.Equal(t, []string{"A2"}, event.Seats)
+
+	event = nextSeatEvent(t, subscription)
+	require.Equal(t, constants.SeatEventHeld, event.Type)
+	require.Equal(t, 1, event.SessionId)
+	require.Equal(t, []string{"A1"}, event.Seats)
+
+	_, err = bookingService.ConfirmOrder(7, "evt_1")
+	require.NoError(t, err)
+
+	event = nextSeatEvent(t, subscription)
+	require.Equal(t, constants.SeatEventBooked, event.Type)
+	require.Equal(t, []string{"A1"}, event.Seats)
+}
+
+// TestCancelOrderPublishesReleasedSeats
+func TestCancelOrderPublishesReleasedSeats(t *testing.T) {
+	mockStore := new(MockBookingStore)
+	cinemas := new(MockCinemaAuthorizer)
+	broker := notification.NewBroker(8)
+	bookingService := booking.NewService(mockStore, new(MockSessionStore), cinemas, payment.NewFake("whsec_test"), mailer.NewMemory(), emails, broker, config)
+
+	subscription := broker.Subscribe(booking.SeatsTopic(1))
+	defer subscription.Close()
+
+	reference := "cs_fake_1"
+	cinemas.On("Authorize", mock.Anything, 0, constants.PermissionBookingsManage).Return(nil)
+	mockStore.On("FindOrderById", 1, constants.UserRoleViewer, 7).Return(booking.Order{
+		Id:               7,
+		Amount:           1600,
+		RefundedAmount:   800,
+		Status:           constants.OrderStatusConfirmed,
+		PaymentReference: &reference,
+		Seats:            []string{"A2"},
+		Session:          newSessionWithEvent(48 * time.Hour),
+	}, nil)
+	// A1 was canceled on its own before, and may be held by someone else by now.
+	mockStore.On("CancelOrder", 7, constants.OrderStatusConfirmed, 100).Return([]string{"A2"}, nil)
+
+	require.NoError(t, bookingService.CancelOrder(authenticated(), 7))
+
+	event := nextSeatEvent(t, subscription)
+	require.Equal(t, constants.SeatEventReleased, event.Type)
+	require.Equal(t, []string{"A2"}, event.Seats)
+}
+
+// TestReleaseExpiredHoldsPublishesPerSession
+func TestReleaseExpiredHoldsPublishesPerSession(t *testing.T) {
+	mockStore := new(MockBookingStore)
+	broker := notification.NewBroker(8)
+	bookingService := booking.NewService(mockStore, new(MockSessionStore), new(MockCinemaAuthorizer), payment.NewFake("whsec_test"), mailer.NewMemory(), emails, broker, config)
+
+	first := broker.Subscribe(booking.SeatsTopic(1))
+	defer first.Close()
+	second := broker.Subscribe(booking.SeatsTopic(2))
+	defer second.Close()
+
+	mockStore.On("ExpirePending", mock.Anything).Return([]booking.SeatChange{
+		{SessionId: 1, Seats: []string{"A1", "A2"}},
+		{SessionId: 2, Seats: []string{"C4"}},
+	}, nil)
+
+	count, err := bookingService.ReleaseExpiredHolds()
+	require.NoError(t, err)
+	require.Equal(t, 3, count)
+
+	event := nextSeatEvent(t, first)
+	require.Equal(t, constants.SeatEventReleased, event.Type)
+	require.Equal(t, []string{"A1", "A2"}, event.Seats)
+
+	event = nextSeatEvent(t, second)
+	require.Equal(t, constants.SeatEventReleased, event.Type)
+	require.Equal(t, 2, event.SessionId)
+	require.Equal(t, []string{"C4"}, event.Seats)
+}
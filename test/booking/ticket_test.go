@@ -0,0 +1,128 @@
+package booking
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/cinema-booker/internal/booking"
+	"github.com/cinema-booker/internal/constants"
+	"github.com/cinema-booker/internal/notification"
+	"github.com/cinema-booker/pkg/errors"
+	"github.com/cinema-booker/pkg/ticket"
+	"github.com/cinema-booker/third_party/mailer"
+	"github.com/cinema-booker/third_party/payment"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const ticketId = "4f1d2a6e-8c0b-4f3e-9a57-0d6b3c2e1f90"
+
+func manager() context.Context {
+	ctx := context.WithValue(context.Background(), constants.UserIDKey, 2)
+	return context.WithValue(ctx, constants.UserRoleKey, constants.UserRoleManager)
+}
+
+// TestTicketSignature
+func TestTicketSignature(t *testing.T) {
+	token := ticket.Sign("ticket_secret", ticketId)
+
+	id, err := ticket.Verify("ticket_secret", token)
+	require.NoError(t, err)
+	require.Equal(t, ticketId, id)
+
+	_, err = ticket.Verify("other_secret", token)
+	require.Error(t, err)
+
+	_, err = ticket.Verify("ticket_secret", ticketId+".forged")
+	require.Error(t, err)
+}
+
+// TestLoadTicketSecret
+func TestLoadTicketSecret(t *testing.T) {
+	for _, secret := range []string{"", "secret"} {
+		t.Setenv("TICKET_SECRET", secret)
+		_, err := ticket.LoadSecret()
+		require.Error(t, err)
+	}
+
+	t.Setenv("TICKET_SECRET", "6yY0Qx3Jf8ZkP2sLwV9mN4cR7tB1hD5g")
+	secret, err := ticket.LoadSecret()
+	require.NoError(t, err)
+	require.Equal(t, "6yY0Qx3Jf8ZkP2sLwV9mN4cR7tB1hD5g", secret)
+}
+
+// TestGetTicketRequiresConfirmedBooking
+func TestGetTicketRequiresConfirmedBooking(t *testing.T) {
+	mockStore := new(MockBookingStore)
+	bookingService := booking.NewService(mockStore, new(MockSessionStore), new(MockCinemaAuthorizer), payment.NewFake("whsec_test"), mailer.NewMemory(), emails, notification.NewBroker(8), config)
+
+	mockStore.On("FindById", 1, constants.UserRoleViewer, 3).Return(booking.Booking{Id: 3, Status: constants.BookingStatusConfirmed, TicketId: ticketId}, nil)
+	mockStore.On("FindById", 1, constants.UserRoleViewer, 4).Return(booking.Booking{Id: 4, Status: constants.BookingStatusPending, TicketId: ticketId}, nil)
+
+	token, err := bookingService.GetTicket(authenticated(), 3)
+	require.NoError(t, err)
+	require.Equal(t, ticket.Sign("ticket_secret", ticketId), token)
+
+	image, err := ticket.SVG(token)
+	require.NoError(t, err)
+	require.Contains(t, string(image), "<svg")
+
+	_, err = bookingService.GetTicket(authenticated(), 4)
+	require.Error(t, err)
+	require.Equal(t, http.StatusBadRequest, err.(errors.CustomError).StatusCode())
+}
+
+// TestValidateTicket
+func TestValidateTicket(t *testing.T) {
+	mockStore := new(MockBookingStore)
+	bookingService := booking.NewService(mockStore, new(MockSessionStore), new(MockCinemaAuthorizer), payment.NewFake("whsec_test"), mailer.NewMemory(), emails, notification.NewBroker(8), config)
+	token := ticket.Sign("ticket_secret", ticketId)
+
+	mockStore.On("UseTicket", ticketId, 2, constants.UserRoleManager, mock.Anything).Return(booking.Booking{Id: 3}, nil).Once()
+	mockStore.On("UseTicket", ticketId, 2, constants.UserRoleManager, mock.Anything).Return(booking.Booking{}, booking.ErrTicketUsed).Once()
+	// Viewers who are not part of the staff of the cinema.
+	mockStore.On("UseTicket", ticketId, 1, constants.UserRoleViewer, mock.Anything).Return(booking.Booking{}, booking.ErrTicketOtherCinema).Once()
+
+	validated, err := bookingService.ValidateTicket(manager(), token)
+	require.NoError(t, err)
+	require.Equal(t, 3, validated.Id)
+
+	_, err = bookingService.ValidateTicket(manager(), token)
+	require.Error(t, err)
+	require.Equal(t, http.StatusConflict, err.(errors.CustomError).StatusCode())
+
+	_, err = bookingService.ValidateTicket(authenticated(), token)
+	require.Error(t, err)
+	require.Equal(t, http.StatusForbidden, err.(errors.CustomError).StatusCode())
+
+	_, err = bookingService.ValidateTicket(manager(), ticketId+".forged")
+	require.Error(t, err)
+	require.Equal(t, http.StatusBadRequest, err.(errors.CustomError).StatusCode())
+	mockStore.AssertExpectations(t)
+}
+
+// TestOrderTicketsPDF
+func TestOrderTicketsPDF(t *testing.T) {
+	mockStore := new(MockBookingStore)
+	bookingService := booking.NewService(mockStore, new(MockSessionStore), new(MockCinemaAuthorizer), payment.NewFake("whsec_test"), mailer.NewMemory(), emails, notification.NewBroker(8), config)
+
+	session := newSessionWithEvent(48 * time.Hour)
+	session.Event.Movie.Title = "Dune"
+	session.Event.Cinema.Address.Address = "1 Boulevard Poissonnière, Paris"
+	mockStore.On("FindByOrderId", 1, constants.UserRoleViewer, 7).Return([]booking.Booking{
+		{Id: 3, Place: "A1", Status: constants.BookingStatusConfirmed, TicketId: ticketId, Session: session},
+		{Id: 4, Place: "A2", Status: constants.BookingStatusCanceled, TicketId: ticketId, Session: session},
+	}, nil)
+	mockStore.On("FindByOrderId", 1, constants.UserRoleViewer, 8).Return([]booking.Booking{}, nil)
+
+	content, err := bookingService.GetOrderTicketsPDF(authenticated(), 7)
+	require.NoError(t, err)
+	require.True(t, bytes.HasPrefix(content, []byte("%PDF-")))
+
+	_, err = bookingService.GetOrderTicketsPDF(authenticated(), 8)
+	require.Error(t, err)
+	require.Equal(t, http.StatusNotFound, err.(errors.CustomError).StatusCode())
+}
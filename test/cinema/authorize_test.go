@@ -0,0 +1,91 @@
+package cinema
+
+import (
+	"context"
+	"database/sql"
+	goErrors "errors"
+	"net/http"
+	"testing"
+
+	"github.com/cinema-booker/internal/cinema"
+	"github.com/cinema-booker/internal/constants"
+	"github.com/cinema-booker/pkg/errors"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockCinemaStore struct {
+	mock.Mock
+}
+
+// FindAll implements cinema.CinemaStore.
+func (m *MockCinemaStore) FindAll(pagination map[string]int, search string) ([]cinema.Cinema, error) {
+	args := m.Called(pagination, search)
+	return args.Get(0).([]cinema.Cinema), args.Error(1)
+}
+
+// FindById implements cinema.CinemaStore.
+func (m *MockCinemaStore) FindById(id int) (cinema.CinemaWithRooms, error) {
+	args := m.Called(id)
+	return args.Get(0).(cinema.CinemaWithRooms), args.Error(1)
+}
+
+// FindPermissions implements cinema.CinemaStore.
+func (m *MockCinemaStore) FindPermissions(id int, userId int) ([]string, error) {
+	args := m.Called(id, userId)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+// Create implements cinema.CinemaStore.
+func (m *MockCinemaStore) Create(input map[string]interface{}) error {
+	return m.Called(input).Error(0)
+}
+
+// Update implements cinema.CinemaStore.
+func (m *MockCinemaStore) Update(id int, input map[string]interface{}) error {
+	return m.Called(id, input).Error(0)
+}
+
+func withUser(userId int, role string) context.Context {
+	ctx := context.WithValue(context.Background(), constants.UserIDKey, userId)
+	return context.WithValue(ctx, constants.UserRoleKey, role)
+}
+
+func statusOf(t *testing.T, err error) int {
+	var customError errors.CustomError
+	require.True(t, goErrors.As(err, &customError))
+	return customError.StatusCode()
+}
+
+// TestAuthorize
+func TestAuthorize(t *testing.T) {
+	mockStore := new(MockCinemaStore)
+	cinemaService := cinema.NewService(mockStore)
+
+	mockStore.On("FindPermissions", 1, 1).Return([]string{}, nil)
+	mockStore.On("FindPermissions", 1, 2).Return(constants.Permissions, nil)
+	mockStore.On("FindPermissions", 1, 3).Return([]string{}, nil)
+	mockStore.On("FindPermissions", 9, 2).Return([]string(nil), sql.ErrNoRows)
+
+	require.NoError(t, cinemaService.Authorize(withUser(1, constants.UserRoleAdmin), 1, constants.PermissionCinemaManage))
+	require.NoError(t, cinemaService.Authorize(withUser(2, constants.UserRoleManager), 1, constants.PermissionCinemaManage))
+
+	err := cinemaService.Authorize(withUser(3, constants.UserRoleManager), 1, constants.PermissionCinemaManage)
+	require.Equal(t, http.StatusForbidden, statusOf(t, err))
+
+	err = cinemaService.Authorize(withUser(2, constants.UserRoleManager), 9, constants.PermissionCinemaManage)
+	require.Equal(t, http.StatusNotFound, statusOf(t, err))
+}
+
+// TestAuthorizeStaff
+func TestAuthorizeStaff(t *testing.T) {
+	mockStore := new(MockCinemaStore)
+	cinemaService := cinema.NewService(mockStore)
+
+	mockStore.On("FindPermissions", 1, 4).Return([]string{constants.PermissionTicketsValidate}, nil)
+
+	require.NoError(t, cinemaService.Authorize(withUser(4, constants.UserRoleViewer), 1, constants.PermissionTicketsValidate))
+
+	err := cinemaService.Authorize(withUser(4, constants.UserRoleViewer), 1, constants.PermissionEventsManage)
+	require.Equal(t, http.StatusForbidden, statusOf(t, err))
+}
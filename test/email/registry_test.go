@@ -0,0 +1,49 @@
+package email_test
+
+import (
+	"testing"
+
+	"github.com/cinema-booker/internal/constants"
+	"github.com/cinema-booker/internal/email"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRenderSamples
+func TestRenderSamples(t *testing.T) {
+	registry, err := email.NewRegistry(constants.DefaultLocale)
+	require.NoError(t, err)
+	require.Equal(t, []string{"en", "fr"}, registry.Locales())
+
+	samples := email.Samples()
+	require.Len(t, samples, len(registry.Names()))
+
+	for _, locale := range registry.Locales() {
+		for _, name := range registry.Names() {
+			message, err := registry.Render(name, locale, samples[name])
+			require.NoError(t, err, "%s/%s", locale, name)
+			require.NotEmpty(t, message.Subject, "%s/%s", locale, name)
+			require.Contains(t, message.HTML, "Jane Doe", "%s/%s", locale, name)
+			require.Contains(t, message.Text, "Jane Doe", "%s/%s", locale, name)
+			require.NotContains(t, message.Text, "<p>", "%s/%s", locale, name)
+		}
+	}
+}
+
+// TestRenderLocaleFallback
+func TestRenderLocaleFallback(t *testing.T) {
+	registry, err := email.NewRegistry(constants.DefaultLocale)
+	require.NoError(t, err)
+	data := email.Samples()[email.TemplatePasswordReset]
+
+	message, err := registry.Render(email.TemplatePasswordReset, "fr-FR", data)
+	require.NoError(t, err)
+	require.Equal(t, "Réinitialisez votre mot de passe", message.Subject)
+
+	message, err = registry.Render(email.TemplatePasswordReset, "de", data)
+	require.NoError(t, err)
+	require.Equal(t, "Reset your password", message.Subject)
+	require.Contains(t, message.HTML, "temporary password: <strong>X7K2P9QA</strong>")
+
+	_, err = registry.Render("unknown", "en", data)
+	require.Error(t, err)
+}
@@ -0,0 +1,32 @@
+package errors
+
+import (
+	goErrors "errors"
+	"net/http"
+	"testing"
+
+	"github.com/cinema-booker/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+// TestStatusCode
+func TestStatusCode(t *testing.T) {
+	statuses := map[string]int{
+		errors.BadRequest:          http.StatusBadRequest,
+		errors.Unauthorized:        http.StatusUnauthorized,
+		errors.InvalidCredentials:  http.StatusUnauthorized,
+		errors.InvalidCode:         http.StatusUnauthorized,
+		errors.ExpiredCode:         http.StatusUnauthorized,
+		errors.Forbidden:           http.StatusForbidden,
+		errors.NotFound:            http.StatusNotFound,
+		errors.Conflict:            http.StatusConflict,
+		errors.EmailAlreadyExists:  http.StatusConflict,
+		errors.InternalServerError: http.StatusInternalServerError,
+		"unknown":                  http.StatusInternalServerError,
+	}
+
+	for key, status := range statuses {
+		err := errors.CustomError{Key: key, Err: goErrors.New(key)}
+		require.Equal(t, status, err.StatusCode(), key)
+	}
+}
@@ -0,0 +1,59 @@
+package notification_test
+
+import (
+	"testing"
+
+	"github.com/cinema-booker/internal/notification"
+	"github.com/stretchr/testify/require"
+)
+
+// TestBrokerPublishesToTopicSubscribers
+func TestBrokerPublishesToTopicSubscribers(t *testing.T) {
+	broker := notification.NewBroker(4)
+	first := broker.Subscribe("session.1.seats")
+	second := broker.Subscribe("session.1.seats")
+	other := broker.Subscribe("session.2.seats")
+
+	broker.Publish("session.1.seats", []byte("held"))
+
+	require.Equal(t, []byte("held"), <-first.C)
+	require.Equal(t, []byte("held"), <-second.C)
+	require.Len(t, other.C, 0)
+
+	first.Close()
+	first.Close()
+	_, ok := <-first.C
+	require.False(t, ok)
+	require.Equal(t, 1, broker.Subscribers("session.1.seats"))
+}
+
+// TestBrokerDropsSlowSubscribers
+func TestBrokerDropsSlowSubscribers(t *testing.T) {
+	broker := notification.NewBroker(1)
+	slow := broker.Subscribe("session.1.seats")
+
+	broker.Publish("session.1.seats", []byte("1"))
+	broker.Publish("session.1.seats", []byte("2"))
+
+	require.Equal(t, []byte("1"), <-slow.C)
+	_, ok := <-slow.C
+	require.False(t, ok)
+	require.Equal(t, 0, broker.Subscribers("session.1.seats"))
+	slow.Close()
+}
+
+// TestBrokerClose
+func TestBrokerClose(t *testing.T) {
+	broker := notification.NewBroker(1)
+	subscription := broker.Subscribe("session.1.seats")
+
+	broker.Close()
+
+	_, ok := <-subscription.C
+	require.False(t, ok)
+
+	late := broker.Subscribe("session.1.seats")
+	_, ok = <-late.C
+	require.False(t, ok)
+	late.Close()
+}
@@ -0,0 +1,33 @@
+package notification_test
+
+import (
+	"testing"
+
+	"github.com/cinema-booker/internal/constants"
+	"github.com/cinema-booker/internal/notification"
+	"github.com/stretchr/testify/require"
+)
+
+// TestEventRoundTrip
+func TestEventRoundTrip(t *testing.T) {
+	event, err := notification.NewEvent(constants.NotificationTypeSessionSoldOut, map[string]int{"capacity": 120})
+	require.NoError(t, err)
+	require.Equal(t, notification.EventVersion, event.Version)
+	require.False(t, event.OccurredAt.IsZero())
+	event.Id = 9
+	event.SessionId = 4
+
+	value, err := event.Value()
+	require.NoError(t, err)
+
+	var stored notification.Event
+	require.NoError(t, stored.Scan(value))
+	// the id is the id of the row storing the event
+	require.Zero(t, stored.Id)
+	require.Equal(t, constants.NotificationTypeSessionSoldOut, stored.Type)
+	require.Equal(t, 4, stored.SessionId)
+
+	var payload map[string]int
+	require.NoError(t, stored.Decode(&payload))
+	require.Equal(t, 120, payload["capacity"])
+}
@@ -0,0 +1,84 @@
+package notification_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cinema-booker/internal/notification"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/require"
+)
+
+func newServer(t *testing.T, hub *notification.Hub) string {
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		require.NoError(t, err)
+		hub.Serve(1, conn)
+	}))
+	t.Cleanup(server.Close)
+
+	return "ws" + strings.TrimPrefix(server.URL, "http")
+}
+
+func dial(t *testing.T, url string) *websocket.Conn {
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return conn
+}
+
+func waitConnections(t *testing.T, hub *notification.Hub, userId int, count int) {
+	require.Eventually(t, func() bool {
+		return hub.Connections(userId) == count
+	}, time.Second, 10*time.Millisecond)
+}
+
+// TestHubSendsToEveryConnection
+func TestHubSendsToEveryConnection(t *testing.T) {
+	hub := notification.NewHub(notification.DefaultHubConfig())
+	url := newServer(t, hub)
+
+	first := dial(t, url)
+	second := dial(t, url)
+	waitConnections(t, hub, 1, 2)
+
+	require.Equal(t, 2, hub.Send(1, []byte("hello")))
+	require.Equal(t, 0, hub.Send(2, []byte("nobody")))
+
+	for _, conn := range []*websocket.Conn{first, second} {
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		_, message, err := conn.ReadMessage()
+		require.NoError(t, err)
+		require.Equal(t, "hello", string(message))
+	}
+
+	first.Close()
+	waitConnections(t, hub, 1, 1)
+}
+
+// TestHubClose
+func TestHubClose(t *testing.T) {
+	hub := notification.NewHub(notification.DefaultHubConfig())
+	url := newServer(t, hub)
+
+	conn := dial(t, url)
+	waitConnections(t, hub, 1, 1)
+
+	hub.Close()
+	require.Equal(t, 0, hub.Connections(1))
+
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, _, err := conn.ReadMessage()
+	require.True(t, websocket.IsCloseError(err, websocket.CloseGoingAway))
+
+	// New connections are refused once the hub is closed.
+	conn = dial(t, url)
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, _, err = conn.ReadMessage()
+	require.True(t, websocket.IsCloseError(err, websocket.CloseGoingAway))
+}
@@ -0,0 +1,70 @@
+package notification_test
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/cinema-booker/internal/constants"
+	"github.com/cinema-booker/internal/notification"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func authorizedRequest(t *testing.T, method string, url string, token string) *http.Response {
+	req, err := http.NewRequest(method, url, nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	response, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { response.Body.Close() })
+
+	return response
+}
+
+// TestInboxListsUnreadNotifications
+func TestInboxListsUnreadNotifications(t *testing.T) {
+	store, _, _, url, token := newNotificationServer(t)
+
+	store.On("FindAll", 42, map[string]int{"page": 1, "limit": 10}, true, constants.NotificationTypeBookingCanceled).Return([]notification.Notification{
+		{Id: 2, UserId: 42, Type: constants.NotificationTypeBookingCanceled, Message: "while you were away"},
+	}, nil)
+
+	response := authorizedRequest(t, http.MethodGet, url+"/notifications?unread=true&type="+constants.NotificationTypeBookingCanceled, token)
+	require.Equal(t, http.StatusOK, response.StatusCode)
+
+	var notifications []notification.Notification
+	require.NoError(t, json.NewDecoder(response.Body).Decode(&notifications))
+	require.Len(t, notifications, 1)
+	require.Nil(t, notifications[0].ReadAt)
+	store.AssertExpectations(t)
+}
+
+// TestInboxMarkRead
+func TestInboxMarkRead(t *testing.T) {
+	store, _, _, url, token := newNotificationServer(t)
+
+	readAt := time.Now()
+	store.On("MarkRead", 42, int64(2), mock.Anything).Return(notification.Notification{Id: 2, UserId: 42, ReadAt: &readAt}, nil)
+	// notifications of other users are not found
+	store.On("MarkRead", 42, int64(3), mock.Anything).Return(notification.Notification{}, sql.ErrNoRows)
+	store.On("MarkAllRead", 42, mock.Anything).Return(int64(4), nil)
+
+	response := authorizedRequest(t, http.MethodPatch, url+"/notifications/2/read", token)
+	require.Equal(t, http.StatusOK, response.StatusCode)
+	var read notification.Notification
+	require.NoError(t, json.NewDecoder(response.Body).Decode(&read))
+	require.NotNil(t, read.ReadAt)
+
+	response = authorizedRequest(t, http.MethodPatch, url+"/notifications/3/read", token)
+	require.Equal(t, http.StatusNotFound, response.StatusCode)
+
+	response = authorizedRequest(t, http.MethodPatch, url+"/notifications/read", token)
+	require.Equal(t, http.StatusOK, response.StatusCode)
+	var body map[string]int64
+	require.NoError(t, json.NewDecoder(response.Body).Decode(&body))
+	require.Equal(t, int64(4), body["updated"])
+}
@@ -0,0 +1,223 @@
+package notification_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cinema-booker/api/handler"
+	"github.com/cinema-booker/api/middleware"
+	"github.com/cinema-booker/internal/booking"
+	"github.com/cinema-booker/internal/constants"
+	"github.com/cinema-booker/internal/notification"
+	"github.com/cinema-booker/internal/outbox"
+	"github.com/cinema-booker/internal/user"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockNotificationStore struct {
+	mock.Mock
+}
+
+// Create implements notification.NotificationStore.
+func (m *MockNotificationStore) Create(userId int, message string, event notification.Event) (notification.Notification, error) {
+	args := m.Called(userId, message, event)
+	return args.Get(0).(notification.Notification), args.Error(1)
+}
+
+// FindAfter implements notification.NotificationStore.
+func (m *MockNotificationStore) FindAfter(userId int, afterId int64, limit int) ([]notification.Notification, error) {
+	args := m.Called(userId, afterId, limit)
+	return args.Get(0).([]notification.Notification), args.Error(1)
+}
+
+// FindAll implements notification.NotificationStore.
+func (m *MockNotificationStore) FindAll(userId int, pagination map[string]int, unreadOnly bool, notificationType string) ([]notification.Notification, error) {
+	args := m.Called(userId, pagination, unreadOnly, notificationType)
+	return args.Get(0).([]notification.Notification), args.Error(1)
+}
+
+// CountUnread implements notification.NotificationStore.
+func (m *MockNotificationStore) CountUnread(userId int) (int, error) {
+	args := m.Called(userId)
+	return args.Int(0), args.Error(1)
+}
+
+// MarkRead implements notification.NotificationStore.
+func (m *MockNotificationStore) MarkRead(userId int, id int64, readAt time.Time) (notification.Notification, error) {
+	args := m.Called(userId, id, readAt)
+	return args.Get(0).(notification.Notification), args.Error(1)
+}
+
+// MarkAllRead implements notification.NotificationStore.
+func (m *MockNotificationStore) MarkAllRead(userId int, readAt time.Time) (int64, error) {
+	args := m.Called(userId, readAt)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+type streamEvent struct {
+	id    string
+	event string
+	data  string
+}
+
+func readEvent(t *testing.T, reader *bufio.Reader) streamEvent {
+	event := streamEvent{}
+	for {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		line = strings.TrimSuffix(line, "\n")
+
+		switch {
+		case line == "":
+			if event.data != "" {
+				return event
+			}
+		case strings.HasPrefix(line, "id: "):
+			event.id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			event.event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			event.data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+func newNotificationServer(t *testing.T) (*MockNotificationStore, *notification.Service, *handler.NotificationHandler, string, string) {
+	token := newToken(t)
+
+	userStore := new(MockUserStore)
+	userStore.On("FindById", 42).Return(user.User{Id: 42, Role: constants.UserRoleManager}, nil)
+	userStore.On("IsSessionActive", "session").Return(true, nil)
+
+	store := new(MockNotificationStore)
+	hub := notification.NewHub(notification.DefaultHubConfig())
+	broker := notification.NewBroker(8)
+	service := notification.NewService(store, hub, broker)
+	notificationHandler := handler.NewNotificationHandler(service, broker, notification.NewTickets(time.Minute), middleware.NewAuth(newKeys(t), userStore))
+	router := mux.NewRouter()
+	notificationHandler.RegisterRoutes(router)
+
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	t.Cleanup(broker.Close)
+	t.Cleanup(hub.Close)
+
+	return store, service, notificationHandler, server.URL, token
+}
+
+func confirmedNotification(id int64, message string) notification.Notification {
+	return notification.Notification{
+		Id:      id,
+		UserId:  42,
+		Type:    constants.NotificationTypeBookingConfirmed,
+		Message: message,
+		Event: notification.Event{
+			Id:        id,
+			Version:   notification.EventVersion,
+			Type:      constants.NotificationTypeBookingConfirmed,
+			SessionId: 1,
+			Payload:   json.RawMessage(`{}`),
+		},
+	}
+}
+
+// TestNotificationStreamResumes
+func TestNotificationStreamResumes(t *testing.T) {
+	store, service, _, url, token := newNotificationServer(t)
+
+	store.On("FindAfter", 42, int64(3), 100).Return([]notification.Notification{
+		confirmedNotification(4, "first"),
+		confirmedNotification(5, "second"),
+	}, nil)
+	store.On("Create", 42, "second", mock.Anything).Return(confirmedNotification(5, "second"), nil)
+	store.On("Create", 42, "third", mock.Anything).Return(confirmedNotification(6, "third"), nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url+"/notifications/stream", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Last-Event-ID", "3")
+	response, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer response.Body.Close()
+	require.Equal(t, http.StatusOK, response.StatusCode)
+	require.Equal(t, "text/event-stream", response.Header.Get("Content-Type"))
+
+	reader := bufio.NewReader(response.Body)
+	event := readEvent(t, reader)
+	require.Equal(t, "4", event.id)
+	require.Equal(t, constants.NotificationTypeBookingConfirmed, event.event)
+	event = readEvent(t, reader)
+	require.Equal(t, "5", event.id)
+
+	// already replayed, so it is not sent twice
+	_, err = service.Notify(42, "second", notification.Event{})
+	require.NoError(t, err)
+	_, err = service.Notify(42, "third", notification.Event{})
+	require.NoError(t, err)
+
+	event = readEvent(t, reader)
+	require.Equal(t, "6", event.id)
+	var envelope notification.Event
+	require.NoError(t, json.Unmarshal([]byte(event.data), &envelope))
+	require.Equal(t, int64(6), envelope.Id)
+	require.Equal(t, notification.EventVersion, envelope.Version)
+	require.Equal(t, 1, envelope.SessionId)
+}
+
+// TestNotificationStreamRequiresAuthentication
+func TestNotificationStreamRequiresAuthentication(t *testing.T) {
+	_, _, _, url, _ := newNotificationServer(t)
+
+	response, err := http.Get(url + "/notifications/stream?ticket=unknown")
+	require.NoError(t, err)
+	defer response.Body.Close()
+	require.Equal(t, http.StatusUnauthorized, response.StatusCode)
+}
+
+// TestDeliverQueuedNotification
+func TestDeliverQueuedNotification(t *testing.T) {
+	store, service, _, _, _ := newNotificationServer(t)
+
+	event, err := notification.NewEvent(constants.NotificationTypeBookingConfirmed, booking.BookingConfirmedPayload{
+		User:  booking.User{Id: 1, Name: "Alice"},
+		Seats: []string{"A1", "A2"},
+	})
+	require.NoError(t, err)
+	event.CinemaId = 3
+	event.OrderId = 7
+	event.BookingIds = []int{10, 11}
+	payload, err := json.Marshal(notification.Delivery{
+		UserId:  42,
+		Message: "User Alice reserved 2 seats: [A1 A2]",
+		Event:   event,
+	})
+	require.NoError(t, err)
+
+	// the manager is offline, the notification waits in the inbox
+	store.On("Create", 42, "User Alice reserved 2 seats: [A1 A2]", mock.MatchedBy(func(e notification.Event) bool {
+		var confirmed booking.BookingConfirmedPayload
+		return e.Type == constants.NotificationTypeBookingConfirmed &&
+			e.Version == notification.EventVersion &&
+			e.CinemaId == 3 &&
+			len(e.BookingIds) == 2 &&
+			e.Decode(&confirmed) == nil && confirmed.User.Name == "Alice"
+	})).Return(notification.Notification{Id: 1}, nil)
+
+	err = service.Deliver(outbox.Message{Payload: payload})
+	require.NoError(t, err)
+	store.AssertExpectations(t)
+
+	// deliveries without a recipient are retried, then fail in the outbox
+	err = service.Deliver(outbox.Message{Payload: json.RawMessage(`{"message": "lost"}`)})
+	require.Error(t, err)
+}
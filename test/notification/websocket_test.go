@@ -0,0 +1,198 @@
+package notification_test
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cinema-booker/api/handler"
+	"github.com/cinema-booker/api/middleware"
+	"github.com/cinema-booker/internal/constants"
+	"github.com/cinema-booker/internal/notification"
+	"github.com/cinema-booker/internal/user"
+	"github.com/cinema-booker/pkg/jwt"
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockUserStore struct {
+	mock.Mock
+}
+
+// FindAll implements user.UserStore.
+func (m *MockUserStore) FindAll(pagination map[string]int, search string) ([]user.User, error) {
+	args := m.Called(pagination, search)
+	return args.Get(0).([]user.User), args.Error(1)
+}
+
+// FindById implements user.UserStore.
+func (m *MockUserStore) FindById(id int) (user.User, error) {
+	args := m.Called(id)
+	return args.Get(0).(user.User), args.Error(1)
+}
+
+// FindMeById implements user.UserStore.
+func (m *MockUserStore) FindMeById(id int) (user.UserBasic, error) {
+	args := m.Called(id)
+	return args.Get(0).(user.UserBasic), args.Error(1)
+}
+
+// FindByEmail implements user.UserStore.
+func (m *MockUserStore) FindByEmail(email string) (user.User, error) {
+	args := m.Called(email)
+	return args.Get(0).(user.User), args.Error(1)
+}
+
+// Create implements user.UserStore.
+func (m *MockUserStore) Create(input map[string]interface{}) error {
+	return m.Called(input).Error(0)
+}
+
+// Update implements user.UserStore.
+func (m *MockUserStore) Update(id int, input map[string]interface{}) error {
+	return m.Called(id, input).Error(0)
+}
+
+// CreateSession implements user.UserStore.
+func (m *MockUserStore) CreateSession(session user.AuthSession, refreshTokenHash string, expiresAt time.Time) error {
+	return m.Called(session, refreshTokenHash, expiresAt).Error(0)
+}
+
+// RotateRefreshToken implements user.UserStore.
+func (m *MockUserStore) RotateRefreshToken(tokenHash string, newTokenHash string, expiresAt time.Time, now time.Time) (user.AuthSession, error) {
+	args := m.Called(tokenHash, newTokenHash, expiresAt, now)
+	return args.Get(0).(user.AuthSession), args.Error(1)
+}
+
+// IsSessionActive implements user.UserStore.
+func (m *MockUserStore) IsSessionActive(id string) (bool, error) {
+	args := m.Called(id)
+	return args.Bool(0), args.Error(1)
+}
+
+// RevokeSession implements user.UserStore.
+func (m *MockUserStore) RevokeSession(userId int, id string, now time.Time) error {
+	return m.Called(userId, id, now).Error(0)
+}
+
+// RevokeAllSessions implements user.UserStore.
+func (m *MockUserStore) RevokeAllSessions(userId int, now time.Time) error {
+	return m.Called(userId, now).Error(0)
+}
+
+// newKeys returns the keys the access tokens of the tests are signed and verified with.
+func newKeys(t *testing.T) *jwt.KeySet {
+	keys, err := jwt.NewKeySet("cinema-booker", "cinema-booker", jwt.NewHMACKey("test", []byte("jwt_secret_key")))
+	require.NoError(t, err)
+
+	return keys
+}
+
+// newToken returns an access token of user 42, a manager, verified by the middleware.
+func newToken(t *testing.T) string {
+	token, err := jwt.Create(newKeys(t), 60, 42, constants.UserRoleManager, "session")
+	require.NoError(t, err)
+
+	return token
+}
+
+func newWebSocketServer(t *testing.T) (*notification.Hub, string, string) {
+	token := newToken(t)
+
+	userStore := new(MockUserStore)
+	userStore.On("FindById", 42).Return(user.User{Id: 42, Role: constants.UserRoleManager}, nil)
+	userStore.On("IsSessionActive", "session").Return(true, nil)
+
+	hub := notification.NewHub(notification.DefaultHubConfig())
+	websocketHandler := handler.NewWebSocketHandler(hub, notification.NewTickets(time.Minute), middleware.NewAuth(newKeys(t), userStore), []string{"http://localhost:5173"})
+	router := mux.NewRouter()
+	websocketHandler.RegisterRoutes(router)
+
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	t.Cleanup(hub.Close)
+
+	return hub, server.URL, token
+}
+
+// TestWebSocketRequiresAuthentication
+func TestWebSocketRequiresAuthentication(t *testing.T) {
+	_, url, _ := newWebSocketServer(t)
+
+	_, response, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(url, "http")+"/ws?managerID=42", nil)
+	require.Error(t, err)
+	require.Equal(t, http.StatusUnauthorized, response.StatusCode)
+}
+
+// TestWebSocketSubprotocolToken
+func TestWebSocketSubprotocolToken(t *testing.T) {
+	hub, url, token := newWebSocketServer(t)
+
+	dialer := websocket.Dialer{Subprotocols: []string{"access_token", token}}
+	conn, response, err := dialer.Dial("ws"+strings.TrimPrefix(url, "http")+"/ws", http.Header{"Origin": {"http://localhost:5173"}})
+	require.NoError(t, err)
+	defer conn.Close()
+	require.Equal(t, "access_token", response.Header.Get("Sec-WebSocket-Protocol"))
+	waitConnections(t, hub, 42, 1)
+
+	_, response, err = dialer.Dial("ws"+strings.TrimPrefix(url, "http")+"/ws", http.Header{"Origin": {"https://evil.example"}})
+	require.Error(t, err)
+	require.Equal(t, http.StatusForbidden, response.StatusCode)
+}
+
+// TestWebSocketTicket
+func TestWebSocketTicket(t *testing.T) {
+	hub, url, token := newWebSocketServer(t)
+
+	req, err := http.NewRequest(http.MethodPost, url+"/ws/ticket", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+token)
+	response, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer response.Body.Close()
+	require.Equal(t, http.StatusCreated, response.StatusCode)
+
+	var body struct {
+		Ticket string `json:"ticket"`
+	}
+	require.NoError(t, json.NewDecoder(response.Body).Decode(&body))
+
+	wsURL := "ws" + strings.TrimPrefix(url, "http") + "/ws?ticket=" + body.Ticket
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	require.NoError(t, err)
+	defer conn.Close()
+	waitConnections(t, hub, 42, 1)
+
+	// Tickets can only be used once.
+	_, response, err = websocket.DefaultDialer.Dial(wsURL, nil)
+	require.Error(t, err)
+	require.Equal(t, http.StatusUnauthorized, response.StatusCode)
+}
+
+// TestWebSocketTicketOfDeletedUser
+func TestWebSocketTicketOfDeletedUser(t *testing.T) {
+	userStore := new(MockUserStore)
+	userStore.On("FindById", 7).Return(user.User{}, sql.ErrNoRows)
+
+	tickets := notification.NewTickets(time.Minute)
+	hub := notification.NewHub(notification.DefaultHubConfig())
+	t.Cleanup(hub.Close)
+	websocketHandler := handler.NewWebSocketHandler(hub, tickets, middleware.NewAuth(newKeys(t), userStore), []string{"http://localhost:5173"})
+	router := mux.NewRouter()
+	websocketHandler.RegisterRoutes(router)
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+
+	ticket, err := tickets.Issue(7)
+	require.NoError(t, err)
+
+	_, response, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/ws?ticket="+ticket, nil)
+	require.Error(t, err)
+	require.Equal(t, http.StatusUnauthorized, response.StatusCode)
+}
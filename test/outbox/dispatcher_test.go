@@ -0,0 +1,103 @@
+package outbox_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/cinema-booker/internal/outbox"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockOutboxStore struct {
+	mock.Mock
+}
+
+// Claim implements outbox.OutboxStore.
+func (m *MockOutboxStore) Claim(now time.Time, lease time.Duration, limit int) ([]outbox.Message, error) {
+	args := m.Called(now, lease, limit)
+	return args.Get(0).([]outbox.Message), args.Error(1)
+}
+
+// MarkDelivered implements outbox.OutboxStore.
+func (m *MockOutboxStore) MarkDelivered(id int, deliveredAt time.Time) error {
+	return m.Called(id, deliveredAt).Error(0)
+}
+
+// MarkFailed implements outbox.OutboxStore.
+func (m *MockOutboxStore) MarkFailed(id int, lastError string, retryAt *time.Time) error {
+	return m.Called(id, lastError, retryAt).Error(0)
+}
+
+func newDispatcher(store outbox.OutboxStore) *outbox.Dispatcher {
+	return outbox.NewDispatcher(store, outbox.DispatcherConfig{
+		BatchSize:   10,
+		MaxAttempts: 3,
+		Backoff:     time.Second,
+		MaxBackoff:  time.Minute,
+		Lease:       time.Minute,
+	})
+}
+
+// TestDispatchDelivers
+func TestDispatchDelivers(t *testing.T) {
+	store := new(MockOutboxStore)
+	dispatcher := newDispatcher(store)
+
+	var received map[string]int
+	dispatcher.Handle("test", func(message outbox.Message) error {
+		return message.Decode(&received)
+	})
+
+	now := time.Now()
+	store.On("Claim", now, time.Minute, 10).Return([]outbox.Message{
+		{Id: 1, Topic: "test", Payload: []byte(`{"order_id": 7}`), Attempts: 1},
+	}, nil)
+	store.On("MarkDelivered", 1, mock.Anything).Return(nil)
+
+	delivered, err := dispatcher.Dispatch(now)
+	require.NoError(t, err)
+	require.Equal(t, 1, delivered)
+	require.Equal(t, 7, received["order_id"])
+	store.AssertExpectations(t)
+}
+
+// TestDispatchRetriesWithBackoff
+func TestDispatchRetriesWithBackoff(t *testing.T) {
+	store := new(MockOutboxStore)
+	dispatcher := newDispatcher(store)
+	dispatcher.Handle("test", func(message outbox.Message) error {
+		return fmt.Errorf("unavailable")
+	})
+
+	now := time.Now()
+	store.On("Claim", now, time.Minute, 10).Return([]outbox.Message{
+		{Id: 1, Topic: "test", Attempts: 2},
+	}, nil)
+	store.On("MarkFailed", 1, "unavailable", mock.MatchedBy(func(retryAt *time.Time) bool {
+		// The second failed attempt waits twice the initial backoff.
+		return retryAt != nil && retryAt.Sub(now) >= 2*time.Second && retryAt.Sub(now) < 3*time.Second
+	})).Return(nil)
+
+	delivered, err := dispatcher.Dispatch(now)
+	require.NoError(t, err)
+	require.Equal(t, 0, delivered)
+	store.AssertExpectations(t)
+}
+
+// TestDispatchGivesUpAfterMaxAttempts
+func TestDispatchGivesUpAfterMaxAttempts(t *testing.T) {
+	store := new(MockOutboxStore)
+	dispatcher := newDispatcher(store)
+
+	now := time.Now()
+	store.On("Claim", now, time.Minute, 10).Return([]outbox.Message{
+		{Id: 1, Topic: "unknown", Attempts: 3},
+	}, nil)
+	store.On("MarkFailed", 1, "no handler for topic unknown", (*time.Time)(nil)).Return(nil)
+
+	_, err := dispatcher.Dispatch(now)
+	require.NoError(t, err)
+	store.AssertExpectations(t)
+}
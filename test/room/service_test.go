@@ -0,0 +1,75 @@
+package room
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/cinema-booker/internal/constants"
+	"github.com/cinema-booker/internal/room"
+	"github.com/cinema-booker/pkg/errors"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockRoomStore struct {
+	mock.Mock
+}
+
+// FindById implements room.RoomStore.
+func (m *MockRoomStore) FindById(cinemaId int, id int) (room.Room, error) {
+	args := m.Called(cinemaId, id)
+	return args.Get(0).(room.Room), args.Error(1)
+}
+
+// Create implements room.RoomStore.
+func (m *MockRoomStore) Create(input map[string]interface{}) error {
+	return m.Called(input).Error(0)
+}
+
+// Update implements room.RoomStore.
+func (m *MockRoomStore) Update(id int, input map[string]interface{}) error {
+	return m.Called(id, input).Error(0)
+}
+
+// TestCreateRequiresLayout
+func TestCreateRequiresLayout(t *testing.T) {
+	mockStore := new(MockRoomStore)
+	roomService := room.NewService(mockStore)
+	mockStore.On("Create", mock.Anything).Return(nil)
+
+	inputs := []map[string]interface{}{
+		{"number": "1", "type": constants.RoomTypeSmall},
+		{"number": "1", "type": constants.RoomTypeSmall, "layout": map[string]interface{}{"rows": []interface{}{}}},
+		{"number": "1", "type": constants.RoomTypeSmall, "layout": map[string]interface{}{"rows": []interface{}{
+			map[string]interface{}{"label": "A", "seats": []interface{}{
+				map[string]interface{}{"number": 1, "category": constants.SeatCategoryStandard, "blocked": true},
+			}},
+		}}},
+	}
+	for _, input := range inputs {
+		err := roomService.Create(context.Background(), 1, input)
+		require.Error(t, err)
+		require.Equal(t, http.StatusBadRequest, err.(errors.CustomError).StatusCode())
+	}
+	mockStore.AssertNotCalled(t, "Create", mock.Anything)
+
+	err := roomService.Create(context.Background(), 1, map[string]interface{}{"number": "1", "type": constants.RoomTypeSmall, "layout": map[string]interface{}{"rows": []interface{}{
+		map[string]interface{}{"label": "A", "seats": []interface{}{
+			map[string]interface{}{"number": 1, "category": constants.SeatCategoryStandard},
+		}},
+	}}})
+	require.NoError(t, err)
+}
+
+// TestUpdateLayoutRejectsEmptyLayout
+func TestUpdateLayoutRejectsEmptyLayout(t *testing.T) {
+	mockStore := new(MockRoomStore)
+	roomService := room.NewService(mockStore)
+	mockStore.On("FindById", 1, 2).Return(room.Room{Id: 2}, nil)
+
+	err := roomService.UpdateLayout(context.Background(), 1, 2, map[string]interface{}{"rows": []interface{}{}})
+	require.Error(t, err)
+	require.Equal(t, http.StatusBadRequest, err.(errors.CustomError).StatusCode())
+	mockStore.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,116 @@
+package session
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"testing"
+
+	"github.com/cinema-booker/internal/constants"
+	"github.com/cinema-booker/internal/session"
+	"github.com/cinema-booker/pkg/errors"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockSessionStore struct {
+	mock.Mock
+}
+
+// FindById implements session.SessionStore.
+func (m *MockSessionStore) FindById(id int) (session.Session, error) {
+	args := m.Called(id)
+	return args.Get(0).(session.Session), args.Error(1)
+}
+
+// FindEventId implements session.SessionStore.
+func (m *MockSessionStore) FindEventId(id int) (int, error) {
+	args := m.Called(id)
+	return args.Int(0), args.Error(1)
+}
+
+// HasRoom implements session.SessionStore.
+func (m *MockSessionStore) HasRoom(eventId int, roomId int) (bool, error) {
+	args := m.Called(eventId, roomId)
+	return args.Bool(0), args.Error(1)
+}
+
+// Create implements session.SessionStore.
+func (m *MockSessionStore) Create(input map[string]interface{}) error {
+	return m.Called(input).Error(0)
+}
+
+// Update implements session.SessionStore.
+func (m *MockSessionStore) Update(id int, input map[string]interface{}) error {
+	return m.Called(id, input).Error(0)
+}
+
+// GetDashboardData implements session.SessionStore.
+func (m *MockSessionStore) GetDashboardData(userId int) (session.FlatDashboardResponse, error) {
+	args := m.Called(userId)
+	return args.Get(0).(session.FlatDashboardResponse), args.Error(1)
+}
+
+type MockSessionCanceler struct {
+	mock.Mock
+}
+
+// CancelSession implements session.SessionCanceler.
+func (m *MockSessionCanceler) CancelSession(id int) error {
+	return m.Called(id).Error(0)
+}
+
+func as(userId int, role string) context.Context {
+	ctx := context.WithValue(context.Background(), constants.UserIDKey, userId)
+	return context.WithValue(ctx, constants.UserRoleKey, role)
+}
+
+// TestDashboardRequiresBookingsPermission
+func TestDashboardRequiresBookingsPermission(t *testing.T) {
+	mockStore := new(MockSessionStore)
+	sessionService := session.NewService(mockStore, new(MockSessionCanceler))
+
+	// Viewers invited with a role managing bookings, whatever their global role.
+	mockStore.On("GetDashboardData", 2).Return(session.FlatDashboardResponse{TotalCinemas: 1, TotalBookings: 3}, nil)
+	mockStore.On("GetDashboardData", 3).Return(session.FlatDashboardResponse{}, nil)
+	mockStore.On("GetDashboardData", 0).Return(session.FlatDashboardResponse{TotalCinemas: 4}, nil)
+
+	response, err := sessionService.GetDashboardData(as(2, constants.UserRoleViewer))
+	require.NoError(t, err)
+	require.Equal(t, 3, response.TotalBookings)
+
+	_, err = sessionService.GetDashboardData(as(3, constants.UserRoleManager))
+	require.Error(t, err)
+	require.Equal(t, http.StatusForbidden, err.(errors.CustomError).StatusCode())
+
+	response, err = sessionService.GetDashboardData(as(1, constants.UserRoleAdmin))
+	require.NoError(t, err)
+	require.Equal(t, 4, response.TotalCinemas)
+}
+
+// TestDeleteCancelsSession
+func TestDeleteCancelsSession(t *testing.T) {
+	mockStore := new(MockSessionStore)
+	bookings := new(MockSessionCanceler)
+	sessionService := session.NewService(mockStore, bookings)
+
+	mockStore.On("FindEventId", 1).Return(5, nil)
+	mockStore.On("FindEventId", 2).Return(0, sql.ErrNoRows)
+	bookings.On("CancelSession", 1).Return(nil).Once()
+	bookings.On("CancelSession", 1).Return(errors.CustomError{Key: errors.Conflict}).Once()
+
+	require.NoError(t, sessionService.Delete(as(1, constants.UserRoleAdmin), 5, 1))
+
+	err := sessionService.Delete(as(1, constants.UserRoleAdmin), 5, 1)
+	require.Error(t, err)
+	require.Equal(t, http.StatusConflict, err.(errors.CustomError).StatusCode())
+
+	err = sessionService.Delete(as(1, constants.UserRoleAdmin), 6, 1)
+	require.Error(t, err)
+	require.Equal(t, http.StatusNotFound, err.(errors.CustomError).StatusCode())
+
+	err = sessionService.Delete(as(1, constants.UserRoleAdmin), 5, 2)
+	require.Error(t, err)
+	require.Equal(t, http.StatusNotFound, err.(errors.CustomError).StatusCode())
+	bookings.AssertExpectations(t)
+}
@@ -0,0 +1,317 @@
+package staff
+
+import (
+	"context"
+	"database/sql"
+	goErrors "errors"
+	"net/http"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/cinema-booker/internal/constants"
+	"github.com/cinema-booker/internal/email"
+	"github.com/cinema-booker/internal/staff"
+	"github.com/cinema-booker/pkg/errors"
+	"github.com/cinema-booker/third_party/mailer"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockStaffStore struct {
+	mock.Mock
+}
+
+// FindRoles implements staff.StaffStore.
+func (m *MockStaffStore) FindRoles(cinemaId int) ([]staff.Role, error) {
+	args := m.Called(cinemaId)
+	return args.Get(0).([]staff.Role), args.Error(1)
+}
+
+// FindRoleById implements staff.StaffStore.
+func (m *MockStaffStore) FindRoleById(id int) (staff.Role, error) {
+	args := m.Called(id)
+	return args.Get(0).(staff.Role), args.Error(1)
+}
+
+// CreateRole implements staff.StaffStore.
+func (m *MockStaffStore) CreateRole(role staff.Role) (staff.Role, error) {
+	args := m.Called(role)
+	return args.Get(0).(staff.Role), args.Error(1)
+}
+
+// UpdateRole implements staff.StaffStore.
+func (m *MockStaffStore) UpdateRole(role staff.Role) error {
+	return m.Called(role).Error(0)
+}
+
+// DeleteRole implements staff.StaffStore.
+func (m *MockStaffStore) DeleteRole(id int) error {
+	return m.Called(id).Error(0)
+}
+
+// FindMemberPermissions implements staff.StaffStore.
+func (m *MockStaffStore) FindMemberPermissions(cinemaId int, userId int) ([]string, error) {
+	args := m.Called(cinemaId, userId)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+// FindMembers implements staff.StaffStore.
+func (m *MockStaffStore) FindMembers(cinemaId int) ([]staff.Member, error) {
+	args := m.Called(cinemaId)
+	return args.Get(0).([]staff.Member), args.Error(1)
+}
+
+// UpdateMember implements staff.StaffStore.
+func (m *MockStaffStore) UpdateMember(cinemaId int, userId int, roleId int) error {
+	return m.Called(cinemaId, userId, roleId).Error(0)
+}
+
+// RemoveMember implements staff.StaffStore.
+func (m *MockStaffStore) RemoveMember(cinemaId int, userId int) error {
+	return m.Called(cinemaId, userId).Error(0)
+}
+
+// FindInvitations implements staff.StaffStore.
+func (m *MockStaffStore) FindInvitations(cinemaId int, now time.Time) ([]staff.Invitation, error) {
+	args := m.Called(cinemaId, now)
+	return args.Get(0).([]staff.Invitation), args.Error(1)
+}
+
+// CreateInvitation implements staff.StaffStore.
+func (m *MockStaffStore) CreateInvitation(invitation staff.Invitation, tokenHash string) (staff.Invitation, error) {
+	args := m.Called(invitation, tokenHash)
+	return args.Get(0).(staff.Invitation), args.Error(1)
+}
+
+// DeleteInvitation implements staff.StaffStore.
+func (m *MockStaffStore) DeleteInvitation(cinemaId int, id int) (bool, error) {
+	args := m.Called(cinemaId, id)
+	return args.Bool(0), args.Error(1)
+}
+
+// AcceptInvitation implements staff.StaffStore.
+func (m *MockStaffStore) AcceptInvitation(tokenHash string, userId int, now time.Time) (staff.Invitation, error) {
+	args := m.Called(tokenHash, userId, now)
+	return args.Get(0).(staff.Invitation), args.Error(1)
+}
+
+func newService(t *testing.T, store staff.StaffStore, mails mailer.Mailer) *staff.Service {
+	emails, err := email.NewRegistry(constants.DefaultLocale)
+	require.NoError(t, err)
+
+	return staff.NewService(store, mails, emails)
+}
+
+func withUser(userId int) context.Context {
+	ctx := context.WithValue(context.Background(), constants.UserIDKey, userId)
+	return context.WithValue(ctx, constants.UserRoleKey, constants.UserRoleManager)
+}
+
+func withAdmin(userId int) context.Context {
+	ctx := context.WithValue(context.Background(), constants.UserIDKey, userId)
+	return context.WithValue(ctx, constants.UserRoleKey, constants.UserRoleAdmin)
+}
+
+func statusOf(t *testing.T, err error) int {
+	var customError errors.CustomError
+	require.True(t, goErrors.As(err, &customError))
+	return customError.StatusCode()
+}
+
+// TestCreateRole
+func TestCreateRole(t *testing.T) {
+	mockStore := new(MockStaffStore)
+	staffService := newService(t, mockStore, mailer.NewMemory())
+
+	role := staff.Role{Name: "Accountant", Permissions: pq.StringArray{constants.PermissionTicketsValidate}}
+	mockStore.On("CreateRole", role).Return(staff.Role{Id: 4, Name: role.Name, Permissions: role.Permissions}, nil)
+
+	created, err := staffService.CreateRole(context.Background(), map[string]interface{}{
+		"name":        " Accountant ",
+		"permissions": []interface{}{constants.PermissionTicketsValidate},
+	})
+	require.NoError(t, err)
+	require.Equal(t, 4, created.Id)
+
+	_, err = staffService.CreateRole(context.Background(), map[string]interface{}{
+		"name":        "Hacker",
+		"permissions": []interface{}{"everything"},
+	})
+	require.Equal(t, http.StatusBadRequest, statusOf(t, err))
+	mockStore.AssertNumberOfCalls(t, "CreateRole", 1)
+}
+
+// TestUpdateMember
+func TestUpdateMember(t *testing.T) {
+	mockStore := new(MockStaffStore)
+	staffService := newService(t, mockStore, mailer.NewMemory())
+
+	otherCinema := 2
+	mockStore.On("FindMemberPermissions", 1, 2).Return([]string{constants.PermissionStaffManage, constants.PermissionTicketsValidate}, nil)
+	mockStore.On("FindMemberPermissions", 1, mock.Anything).Return([]string{constants.PermissionTicketsValidate}, nil)
+	mockStore.On("FindRoleById", 1).Return(staff.Role{Id: 1, Name: "Ticket scanner", Permissions: pq.StringArray{constants.PermissionTicketsValidate}}, nil)
+	mockStore.On("FindRoleById", 5).Return(staff.Role{Id: 5, Name: "Projectionist", CinemaId: &otherCinema}, nil)
+	mockStore.On("UpdateMember", 1, 7, 1).Return(nil)
+	mockStore.On("UpdateMember", 1, 8, 1).Return(sql.ErrNoRows)
+	mockStore.On("UpdateMember", 1, 9, 1).Return(staff.ErrLastManager)
+
+	require.NoError(t, staffService.UpdateMember(withUser(2), 1, 7, map[string]interface{}{"role_id": float64(1)}))
+
+	err := staffService.UpdateMember(withUser(2), 1, 8, map[string]interface{}{"role_id": float64(1)})
+	require.Equal(t, http.StatusNotFound, statusOf(t, err))
+
+	err = staffService.UpdateMember(withUser(2), 1, 9, map[string]interface{}{"role_id": float64(1)})
+	require.Equal(t, http.StatusConflict, statusOf(t, err))
+
+	err = staffService.UpdateMember(withUser(2), 1, 7, map[string]interface{}{"role_id": float64(5)})
+	require.Equal(t, http.StatusBadRequest, statusOf(t, err))
+
+	err = staffService.UpdateMember(withUser(2), 1, 7, map[string]interface{}{})
+	require.Equal(t, http.StatusBadRequest, statusOf(t, err))
+	mockStore.AssertNumberOfCalls(t, "UpdateMember", 3)
+}
+
+// TestDeleteAssignedRole
+func TestDeleteAssignedRole(t *testing.T) {
+	mockStore := new(MockStaffStore)
+	staffService := newService(t, mockStore, mailer.NewMemory())
+
+	mockStore.On("FindRoleById", 1).Return(staff.Role{Id: 1}, nil)
+	mockStore.On("DeleteRole", 1).Return(&pq.Error{Code: "23503"})
+	mockStore.On("RemoveMember", 1, 7).Return(sql.ErrNoRows)
+	mockStore.On("RemoveMember", 1, 2).Return(staff.ErrLastManager)
+
+	err := staffService.DeleteRole(context.Background(), 1)
+	require.Equal(t, http.StatusConflict, statusOf(t, err))
+
+	err = staffService.RemoveMember(withAdmin(1), 1, 7)
+	require.Equal(t, http.StatusNotFound, statusOf(t, err))
+
+	err = staffService.RemoveMember(withAdmin(1), 1, 2)
+	require.Equal(t, http.StatusConflict, statusOf(t, err))
+}
+
+// TestInvite
+func TestInvite(t *testing.T) {
+	mockStore := new(MockStaffStore)
+	mails := mailer.NewMemory()
+	staffService := newService(t, mockStore, mails)
+
+	role := staff.Role{Id: 3, Name: "Ticket scanner"}
+	invitation := staff.Invitation{
+		Id: 1, CinemaId: 1, CinemaName: "Le Grand Rex", Email: "john@example.com", Role: role,
+		InvitedBy: staff.Inviter{Id: 2, Name: "Jane Doe"},
+	}
+
+	var tokenHash string
+	mockStore.On("FindRoleById", 3).Return(role, nil)
+	mockStore.On("FindMemberPermissions", 1, 2).Return([]string{constants.PermissionStaffManage}, nil)
+	mockStore.On("CreateInvitation", mock.MatchedBy(func(i staff.Invitation) bool {
+		return i.CinemaId == 1 && i.Email == "john@example.com" && i.Role.Id == 3 && i.InvitedBy.Id == 2
+	}), mock.Anything).Run(func(args mock.Arguments) {
+		tokenHash = args.String(1)
+	}).Return(invitation, nil)
+
+	created, err := staffService.Invite(withUser(2), 1, map[string]interface{}{"email": " john@example.com ", "role_id": float64(3)})
+	require.NoError(t, err)
+	require.Equal(t, 1, created.Id)
+
+	sent := mails.Sent()
+	require.Len(t, sent, 1)
+	require.Equal(t, []string{"john@example.com"}, sent[0].To)
+	require.Contains(t, sent[0].Text, "Jane Doe invites you to join the staff of Le Grand Rex as Ticket scanner")
+
+	token := regexp.MustCompile(`code: ([0-9a-f]{64})`).FindStringSubmatch(sent[0].Text)
+	require.Len(t, token, 2)
+	require.NotEqual(t, token[1], tokenHash)
+
+	mockStore.On("AcceptInvitation", tokenHash, 7, mock.Anything).Return(invitation, nil)
+	mockStore.On("AcceptInvitation", mock.Anything, 8, mock.Anything).Return(staff.Invitation{}, staff.ErrInvitationOtherEmail)
+
+	_, err = staffService.AcceptInvitation(withUser(7), map[string]interface{}{"token": token[1]})
+	require.NoError(t, err)
+
+	_, err = staffService.AcceptInvitation(withUser(8), map[string]interface{}{"token": token[1]})
+	require.Equal(t, http.StatusForbidden, statusOf(t, err))
+
+	_, err = staffService.Invite(withUser(2), 1, map[string]interface{}{"email": "john", "role_id": float64(3)})
+	require.Equal(t, http.StatusBadRequest, statusOf(t, err))
+}
+
+// TestGrantOnlyOwnPermissions
+func TestGrantOnlyOwnPermissions(t *testing.T) {
+	mockStore := new(MockStaffStore)
+	staffService := newService(t, mockStore, mailer.NewMemory())
+
+	manager := staff.Role{Id: 2, Name: "Manager", Permissions: pq.StringArray{constants.PermissionStaffManage, constants.PermissionCinemaManage}}
+	mockStore.On("FindRoleById", 2).Return(manager, nil)
+	mockStore.On("FindMemberPermissions", 1, 7).Return([]string{constants.PermissionStaffManage}, nil)
+	mockStore.On("UpdateMember", 1, 8, 2).Return(nil)
+
+	// A member managing the staff cannot give a role with more permissions than their own.
+	err := staffService.UpdateMember(withUser(7), 1, 8, map[string]interface{}{"role_id": float64(2)})
+	require.Equal(t, http.StatusForbidden, statusOf(t, err))
+
+	_, err = staffService.Invite(withUser(7), 1, map[string]interface{}{"email": "john@example.com", "role_id": float64(2)})
+	require.Equal(t, http.StatusForbidden, statusOf(t, err))
+	mockStore.AssertNotCalled(t, "UpdateMember", mock.Anything, mock.Anything, mock.Anything)
+	mockStore.AssertNotCalled(t, "CreateInvitation", mock.Anything, mock.Anything)
+
+	require.NoError(t, staffService.UpdateMember(withAdmin(1), 1, 8, map[string]interface{}{"role_id": float64(2)}))
+}
+
+// TestManageOnlyLesserMembers
+func TestManageOnlyLesserMembers(t *testing.T) {
+	mockStore := new(MockStaffStore)
+	staffService := newService(t, mockStore, mailer.NewMemory())
+
+	scanner := staff.Role{Id: 1, Name: "Ticket scanner", Permissions: pq.StringArray{constants.PermissionTicketsValidate}}
+	mockStore.On("FindRoleById", 1).Return(scanner, nil)
+	mockStore.On("FindMemberPermissions", 1, 7).Return([]string{constants.PermissionStaffManage, constants.PermissionTicketsValidate}, nil)
+	// Member 8 is a manager of the cinema, member 9 scans tickets.
+	mockStore.On("FindMemberPermissions", 1, 8).Return([]string{constants.PermissionStaffManage, constants.PermissionCinemaManage}, nil)
+	mockStore.On("FindMemberPermissions", 1, 9).Return([]string{constants.PermissionTicketsValidate}, nil)
+	mockStore.On("UpdateMember", 1, mock.Anything, 1).Return(nil)
+	mockStore.On("RemoveMember", 1, mock.Anything).Return(nil)
+
+	// A member managing the staff cannot demote or remove a member with more permissions than their own.
+	err := staffService.UpdateMember(withUser(7), 1, 8, map[string]interface{}{"role_id": float64(1)})
+	require.Equal(t, http.StatusForbidden, statusOf(t, err))
+
+	err = staffService.RemoveMember(withUser(7), 1, 8)
+	require.Equal(t, http.StatusForbidden, statusOf(t, err))
+	mockStore.AssertNotCalled(t, "UpdateMember", mock.Anything, mock.Anything, mock.Anything)
+	mockStore.AssertNotCalled(t, "RemoveMember", mock.Anything, mock.Anything)
+
+	require.NoError(t, staffService.UpdateMember(withUser(7), 1, 9, map[string]interface{}{"role_id": float64(1)}))
+	require.NoError(t, staffService.RemoveMember(withUser(7), 1, 9))
+
+	require.NoError(t, staffService.UpdateMember(withAdmin(1), 1, 8, map[string]interface{}{"role_id": float64(1)}))
+	require.NoError(t, staffService.RemoveMember(withAdmin(1), 1, 8))
+}
+
+// TestAcceptInvitationKeepsStaffManaged
+func TestAcceptInvitationKeepsStaffManaged(t *testing.T) {
+	mockStore := new(MockStaffStore)
+	staffService := newService(t, mockStore, mailer.NewMemory())
+
+	// The last manager of the cinema accepts an invitation to a role without staff.manage.
+	mockStore.On("AcceptInvitation", mock.Anything, 2, mock.Anything).Return(staff.Invitation{}, staff.ErrLastManager)
+
+	_, err := staffService.AcceptInvitation(withUser(2), map[string]interface{}{"token": "f3a1"})
+	require.Equal(t, http.StatusConflict, statusOf(t, err))
+}
+
+// TestUpdateRoleKeepsStaffManaged
+func TestUpdateRoleKeepsStaffManaged(t *testing.T) {
+	mockStore := new(MockStaffStore)
+	staffService := newService(t, mockStore, mailer.NewMemory())
+
+	mockStore.On("FindRoleById", 1).Return(staff.Role{Id: 1, Name: "Manager", Permissions: pq.StringArray{constants.PermissionStaffManage}}, nil)
+	mockStore.On("UpdateRole", mock.Anything).Return(staff.ErrLastManager)
+
+	err := staffService.UpdateRole(withAdmin(1), 1, map[string]interface{}{"permissions": []interface{}{constants.PermissionTicketsValidate}})
+	require.Equal(t, http.StatusConflict, statusOf(t, err))
+}
@@ -0,0 +1,159 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cinema-booker/api/handler"
+	"github.com/cinema-booker/internal/constants"
+	"github.com/cinema-booker/internal/email"
+	"github.com/cinema-booker/internal/user"
+	"github.com/cinema-booker/pkg/errors"
+	"github.com/cinema-booker/pkg/generator"
+	"github.com/cinema-booker/pkg/hasher"
+	"github.com/cinema-booker/pkg/jwt"
+	"github.com/cinema-booker/third_party/mailer"
+	"github.com/gorilla/mux"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newUserService(t *testing.T, store user.UserStore) (*user.Service, *jwt.KeySet) {
+	t.Setenv("JWT_EXPIRES_IN", "900")
+	t.Setenv("REFRESH_TOKEN_EXPIRES_IN", "3600")
+
+	emails, err := email.NewRegistry(constants.DefaultLocale)
+	require.NoError(t, err)
+
+	keys := newKeys(t)
+	return user.NewService(store, mailer.NewMemory(), emails, keys), keys
+}
+
+// TestSignInCreatesSession
+func TestSignInCreatesSession(t *testing.T) {
+	mockStore := new(MockUserStore)
+	userService, keys := newUserService(t, mockStore)
+
+	password, err := hasher.Hash("secret")
+	require.NoError(t, err)
+	mockStore.On("FindByEmail", "jane@example.com").Return(user.User{Id: 1, Email: "jane@example.com", Password: password, Role: constants.UserRoleViewer}, nil)
+	mockStore.On("CreateSession", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	response, err := userService.SignIn(context.Background(), map[string]interface{}{"email": "jane@example.com", "password": "secret"})
+	require.NoError(t, err)
+	require.Equal(t, 900, response["expires_in"])
+
+	claims, err := jwt.Parse(keys, response["token"].(string))
+	require.NoError(t, err)
+	require.Equal(t, "1", claims.Subject)
+	require.Equal(t, constants.UserRoleViewer, claims.Role)
+
+	// Only the hash of the refresh token is stored.
+	session := mockStore.Calls[1].Arguments.Get(0).(user.AuthSession)
+	require.Equal(t, claims.SessionId, session.Id)
+	require.Equal(t, generator.HashToken(response["refresh_token"].(string)), mockStore.Calls[1].Arguments.Get(1))
+}
+
+// TestRefreshRotatesToken
+func TestRefreshRotatesToken(t *testing.T) {
+	mockStore := new(MockUserStore)
+	userService, keys := newUserService(t, mockStore)
+
+	mockStore.On("RotateRefreshToken", generator.HashToken("fresh"), mock.Anything, mock.Anything, mock.Anything).Return(user.AuthSession{Id: "session", UserId: 1}, nil)
+	mockStore.On("FindById", 1).Return(user.User{Id: 1, Role: constants.UserRoleManager}, nil)
+	mockStore.On("RotateRefreshToken", generator.HashToken("used"), mock.Anything, mock.Anything, mock.Anything).Return(user.AuthSession{Id: "session", UserId: 1}, user.ErrRefreshTokenReused)
+
+	response, err := userService.Refresh(context.Background(), map[string]interface{}{"refresh_token": "fresh"})
+	require.NoError(t, err)
+	require.NotEqual(t, "fresh", response["refresh_token"])
+	require.Equal(t, generator.HashToken(response["refresh_token"].(string)), mockStore.Calls[0].Arguments.Get(1))
+
+	claims, err := jwt.Parse(keys, response["token"].(string))
+	require.NoError(t, err)
+	require.Equal(t, "1", claims.Subject)
+	require.Equal(t, constants.UserRoleManager, claims.Role)
+	require.Equal(t, "session", claims.SessionId)
+
+	_, err = userService.Refresh(context.Background(), map[string]interface{}{"refresh_token": "used"})
+	require.Error(t, err)
+	require.Equal(t, http.StatusUnauthorized, err.(errors.CustomError).StatusCode())
+
+	_, err = userService.Refresh(context.Background(), map[string]interface{}{})
+	require.Error(t, err)
+	require.Equal(t, http.StatusBadRequest, err.(errors.CustomError).StatusCode())
+}
+
+// TestRevokedTokenRejected
+func TestRevokedTokenRejected(t *testing.T) {
+	mockService := new(MockUserService)
+	mockStore := new(MockUserStore)
+	userHandler := handler.NewUserHandler(mockService, newAuth(t, mockStore), new(MockSessionService))
+
+	mockStore.On("FindById", 1).Return(user.User{Id: 1, Role: constants.UserRoleViewer}, nil)
+	mockStore.On("IsSessionActive", "session").Return(true, nil)
+	mockStore.On("IsSessionActive", "revoked").Return(false, nil)
+	mockService.On("SignOut", mock.Anything).Return(nil)
+
+	r := mux.NewRouter()
+	userHandler.RegisterRoutes(r)
+	signOut := func(sessionId string) int {
+		token := newToken(t, 1, constants.UserRoleViewer, sessionId)
+
+		req, err := http.NewRequest(http.MethodPost, "/sign-out", nil)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	require.Equal(t, http.StatusNoContent, signOut("session"))
+	require.Equal(t, http.StatusUnauthorized, signOut("revoked"))
+	mockService.AssertNumberOfCalls(t, "SignOut", 1)
+	require.Equal(t, "session", mockService.Calls[0].Arguments.Get(0).(context.Context).Value(constants.SessionIDKey))
+}
+
+// TestTokenOfDeletedUserRejected
+func TestTokenOfDeletedUserRejected(t *testing.T) {
+	mockService := new(MockUserService)
+	mockStore := new(MockUserStore)
+	userHandler := handler.NewUserHandler(mockService, newAuth(t, mockStore), new(MockSessionService))
+
+	mockStore.On("IsSessionActive", "session").Return(true, nil)
+	mockStore.On("FindById", 1).Return(user.User{}, sql.ErrNoRows)
+
+	r := mux.NewRouter()
+	userHandler.RegisterRoutes(r)
+
+	req, err := http.NewRequest(http.MethodPost, "/sign-out", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+newToken(t, 1, constants.UserRoleViewer, "session"))
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusUnauthorized, rr.Code)
+	mockService.AssertNotCalled(t, "SignOut", mock.Anything)
+}
+
+// TestGetMeKeepsCinemaId
+func TestGetMeKeepsCinemaId(t *testing.T) {
+	mockStore := new(MockUserStore)
+	userService, _ := newUserService(t, mockStore)
+
+	mockStore.On("FindMeById", 1).Return(user.UserBasic{Id: 1, CinemaIds: pq.Int64Array{3, 5}}, nil)
+	mockStore.On("FindMeById", 2).Return(user.UserBasic{Id: 2, CinemaIds: pq.Int64Array{}}, nil)
+
+	me, err := userService.GetMe(context.WithValue(context.Background(), constants.UserIDKey, 1))
+	require.NoError(t, err)
+	require.Equal(t, 3, *me["cinema_id"].(*int))
+	require.Equal(t, pq.Int64Array{3, 5}, me["cinema_ids"])
+
+	me, err = userService.GetMe(context.WithValue(context.Background(), constants.UserIDKey, 2))
+	require.NoError(t, err)
+	require.Nil(t, me["cinema_id"])
+}
@@ -0,0 +1,69 @@
+package handler
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cinema-booker/api/handler"
+	"github.com/cinema-booker/internal/constants"
+	"github.com/cinema-booker/internal/user"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// serveAs sends a request to the user routes on behalf of a user with the given role.
+func serveAs(t *testing.T, mockService *MockUserService, userId int, role string, method string, path string, body string) int {
+	mockStore := new(MockUserStore)
+	mockStore.On("FindById", userId).Return(user.User{Id: userId, Role: role}, nil)
+	mockStore.On("IsSessionActive", "session").Return(true, nil)
+	userHandler := handler.NewUserHandler(mockService, newAuth(t, mockStore), new(MockSessionService))
+
+	token := newToken(t, userId, role, "session")
+
+	req, err := http.NewRequest(method, path, bytes.NewBufferString(body))
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+
+	rr := httptest.NewRecorder()
+	r := mux.NewRouter()
+	userHandler.RegisterRoutes(r)
+	r.ServeHTTP(rr, req)
+
+	return rr.Code
+}
+
+// TestAdminRoutesForbidden
+func TestAdminRoutesForbidden(t *testing.T) {
+	mockService := new(MockUserService)
+
+	require.Equal(t, http.StatusForbidden, serveAs(t, mockService, 2, constants.UserRoleViewer, http.MethodGet, "/users", ""))
+	require.Equal(t, http.StatusForbidden, serveAs(t, mockService, 2, constants.UserRoleManager, http.MethodPatch, "/users/3/restore", ""))
+	mockService.AssertNotCalled(t, "GetAll", mock.Anything, mock.Anything)
+	mockService.AssertNotCalled(t, "Restore", mock.Anything, mock.Anything)
+}
+
+// TestOtherUserForbidden
+func TestOtherUserForbidden(t *testing.T) {
+	mockService := new(MockUserService)
+	mockService.On("Get", mock.Anything, 2).Return(user.UserBasic{Id: 2}, nil)
+
+	require.Equal(t, http.StatusOK, serveAs(t, mockService, 2, constants.UserRoleViewer, http.MethodGet, "/users/2", ""))
+	require.Equal(t, http.StatusForbidden, serveAs(t, mockService, 2, constants.UserRoleViewer, http.MethodGet, "/users/3", ""))
+	require.Equal(t, http.StatusForbidden, serveAs(t, mockService, 2, constants.UserRoleViewer, http.MethodPatch, "/users/3/password", `{"password":"secret"}`))
+	mockService.AssertNotCalled(t, "EditPassword", mock.Anything, mock.Anything, mock.Anything)
+}
+
+// TestUpdateOwnRoleForbidden
+func TestUpdateOwnRoleForbidden(t *testing.T) {
+	mockService := new(MockUserService)
+	mockService.On("Update", mock.Anything, 2, map[string]interface{}{"name": "Jane"}).Return(nil)
+	mockService.On("Update", mock.Anything, 2, map[string]interface{}{"role": constants.UserRoleAdmin}).Return(nil)
+
+	require.Equal(t, http.StatusAccepted, serveAs(t, mockService, 2, constants.UserRoleViewer, http.MethodPatch, "/users/2", `{"name":"Jane"}`))
+	require.Equal(t, http.StatusForbidden, serveAs(t, mockService, 2, constants.UserRoleViewer, http.MethodPatch, "/users/2", `{"role":"ADMIN"}`))
+	require.Equal(t, http.StatusAccepted, serveAs(t, mockService, 1, constants.UserRoleAdmin, http.MethodPatch, "/users/2", `{"role":"ADMIN"}`))
+}
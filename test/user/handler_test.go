@@ -2,17 +2,17 @@ package handler
 
 import (
 	"context"
-	"os"
 
 	// "encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/cinema-booker/api/handler"
-	"github.com/cinema-booker/pkg/jwt"
 
 	"github.com/cinema-booker/internal/constants"
+	"github.com/cinema-booker/internal/session"
 	"github.com/cinema-booker/internal/user"
 	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/mock"
@@ -39,6 +39,11 @@ func (m *MockUserService) Get(ctx context.Context, id int) (user.UserBasic, erro
 	return args.Get(0).(user.UserBasic), args.Error(1)
 }
 
+// EditPassword implements user.UserService.
+func (m *MockUserService) EditPassword(ctx context.Context, id int, input map[string]interface{}) error {
+	return m.Called(ctx, id, input).Error(0)
+}
+
 // GetAll implements user.UserService.
 func (m *MockUserService) GetAll(ctx context.Context, pagination map[string]int, search string) ([]user.User, error) {
 	args := m.Called(ctx, pagination)
@@ -72,6 +77,22 @@ func (m *MockUserService) SignIn(ctx context.Context, input map[string]interface
 	return args.Get(0).(map[string]interface{}), args.Error(1)
 }
 
+// Refresh implements user.UserService.
+func (m *MockUserService) Refresh(ctx context.Context, input map[string]interface{}) (map[string]interface{}, error) {
+	args := m.Called(ctx, input)
+	return args.Get(0).(map[string]interface{}), args.Error(1)
+}
+
+// SignOut implements user.UserService.
+func (m *MockUserService) SignOut(ctx context.Context) error {
+	return m.Called(ctx).Error(0)
+}
+
+// SignOutEverywhere implements user.UserService.
+func (m *MockUserService) SignOutEverywhere(ctx context.Context) error {
+	return m.Called(ctx).Error(0)
+}
+
 // SignUp implements user.UserService.
 func (m *MockUserService) SignUp(ctx context.Context, input map[string]interface{}) error {
 	return m.Called(ctx, input).Error(0)
@@ -82,6 +103,26 @@ func (m *MockUserService) Update(ctx context.Context, id int, input map[string]i
 	return m.Called(ctx, id, input).Error(0)
 }
 
+type MockSessionService struct {
+	mock.Mock
+}
+
+// Create implements session.SessionService.
+func (m *MockSessionService) Create(ctx context.Context, eventId int, input map[string]interface{}) error {
+	return m.Called(ctx, eventId, input).Error(0)
+}
+
+// Delete implements session.SessionService.
+func (m *MockSessionService) Delete(ctx context.Context, eventId int, id int) error {
+	return m.Called(ctx, eventId, id).Error(0)
+}
+
+// GetDashboardData implements session.SessionService.
+func (m *MockSessionService) GetDashboardData(ctx context.Context) (session.FlatDashboardResponse, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(session.FlatDashboardResponse), args.Error(1)
+}
+
 type MockUserStore struct {
 	mock.Mock
 }
@@ -120,18 +161,45 @@ func (m *MockUserStore) Update(id int, input map[string]interface{}) error {
 	return m.Called(id, input).Error(0)
 }
 
+// CreateSession implements user.UserStore.
+func (m *MockUserStore) CreateSession(session user.AuthSession, refreshTokenHash string, expiresAt time.Time) error {
+	return m.Called(session, refreshTokenHash, expiresAt).Error(0)
+}
+
+// RotateRefreshToken implements user.UserStore.
+func (m *MockUserStore) RotateRefreshToken(tokenHash string, newTokenHash string, expiresAt time.Time, now time.Time) (user.AuthSession, error) {
+	args := m.Called(tokenHash, newTokenHash, expiresAt, now)
+	return args.Get(0).(user.AuthSession), args.Error(1)
+}
+
+// IsSessionActive implements user.UserStore.
+func (m *MockUserStore) IsSessionActive(id string) (bool, error) {
+	args := m.Called(id)
+	return args.Bool(0), args.Error(1)
+}
+
+// RevokeSession implements user.UserStore.
+func (m *MockUserStore) RevokeSession(userId int, id string, now time.Time) error {
+	return m.Called(userId, id, now).Error(0)
+}
+
+// RevokeAllSessions implements user.UserStore.
+func (m *MockUserStore) RevokeAllSessions(userId int, now time.Time) error {
+	return m.Called(userId, now).Error(0)
+}
+
 // TestGetAll
 func TestGetAll(t *testing.T) {
 	mockService := new(MockUserService)
 	mockStore := new(MockUserStore)
-	userHandler := handler.NewUserHandler(mockService, mockStore)
+	userHandler := handler.NewUserHandler(mockService, newAuth(t, mockStore), new(MockSessionService))
 
 	mockService.On("GetAll", mock.Anything, mock.Anything).Return([]user.User{}, nil)
 	mockStore.On("FindById", mock.Anything).Return(user.User{Id: 1, Role: constants.UserRoleAdmin}, nil)
+	mockStore.On("IsSessionActive", "session").Return(true, nil)
 
 	// Mock token and context
-	token, err := jwt.Create(os.Getenv("JWT_SECRET"), 3600, 1)
-	require.NoError(t, err)
+	token := newToken(t, 1, constants.UserRoleAdmin, "session")
 
 	req, err := http.NewRequest(http.MethodGet, "/users", nil)
 	require.NoError(t, err)
@@ -162,19 +230,19 @@ func TestGetAll(t *testing.T) {
 func TestGetUser(t *testing.T) {
 	mockService := new(MockUserService)
 	mockStore := new(MockUserStore)
-	userHandler := handler.NewUserHandler(mockService, mockStore)
+	userHandler := handler.NewUserHandler(mockService, newAuth(t, mockStore), new(MockSessionService))
 
 	mockService.On("Get", mock.Anything, 1).Return(user.UserBasic{Id: 1, Name: "Test User"}, nil)
 
 	mockStore.On("FindById", 1).Return(user.User{Id: 1, Role: constants.UserRoleAdmin}, nil)
+	mockStore.On("IsSessionActive", "session").Return(true, nil)
 
 	req, err := http.NewRequest(http.MethodGet, "/users/1", nil)
 	require.NoError(t, err)
 
 	req = mux.SetURLVars(req, map[string]string{"id": "1"})
 
-	token, err := jwt.Create(os.Getenv("JWT_SECRET"), 3600, 1)
-	require.NoError(t, err)
+	token := newToken(t, 1, constants.UserRoleAdmin, "session")
 
 	req.Header.Set("Authorization", "Bearer "+token)
 
@@ -202,24 +270,25 @@ func TestGetUser(t *testing.T) {
 func TestGetMe(t *testing.T) {
 	mockService := new(MockUserService)
 	mockStore := new(MockUserStore)
-	userHandler := handler.NewUserHandler(mockService, mockStore)
+	userHandler := handler.NewUserHandler(mockService, newAuth(t, mockStore), new(MockSessionService))
 
 	expectedResponse := map[string]interface{}{
-		"id":        1,
-		"name":      "John Doe",
-		"email":     "johndoe@example.com",
-		"role":      constants.UserRoleAdmin,
-		"cinema_id": 1,
+		"id":         1,
+		"name":       "John Doe",
+		"email":      "johndoe@example.com",
+		"role":       constants.UserRoleAdmin,
+		"cinema_id":  1,
+		"cinema_ids": []int{1},
 	}
 
 	mockService.On("GetMe", mock.Anything).Return(expectedResponse, nil)
 	mockStore.On("FindById", 1).Return(user.User{Id: 1, Role: constants.UserRoleAdmin}, nil)
+	mockStore.On("IsSessionActive", "session").Return(true, nil)
 
 	req, err := http.NewRequest(http.MethodGet, "/me", nil)
 	require.NoError(t, err)
 
-	token, err := jwt.Create(os.Getenv("JWT_SECRET"), 3600, 1)
-	require.NoError(t, err)
+	token := newToken(t, 1, constants.UserRoleAdmin, "session")
 	req.Header.Set("Authorization", "Bearer "+token)
 
 	rr := httptest.NewRecorder()
@@ -0,0 +1,218 @@
+package handler
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cinema-booker/api/handler"
+	"github.com/cinema-booker/api/middleware"
+	"github.com/cinema-booker/internal/constants"
+	"github.com/cinema-booker/internal/user"
+	"github.com/cinema-booker/pkg/jwt"
+	goJwt "github.com/golang-jwt/jwt/v5"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	issuer   = "https://api.cinema-booker.test"
+	audience = "cinema-booker"
+)
+
+// newKeys returns the keys the access tokens of the tests are signed and verified with.
+// They share their secret, so that a token signed by one set is verified by another.
+func newKeys(t *testing.T) *jwt.KeySet {
+	keys, err := jwt.NewKeySet(issuer, audience, jwt.NewHMACKey("test", []byte("jwt_secret_key")))
+	require.NoError(t, err)
+
+	return keys
+}
+
+// newAuth returns the middleware verifying the tokens of newToken.
+func newAuth(t *testing.T, store user.UserStore) *middleware.Auth {
+	return middleware.NewAuth(newKeys(t), store)
+}
+
+// newToken returns an access token of a user, verified by the middleware of newAuth.
+func newToken(t *testing.T, userId int, role string, sessionId string) string {
+	token, err := jwt.Create(newKeys(t), 3600, userId, role, sessionId)
+	require.NoError(t, err)
+
+	return token
+}
+
+func parseKey(t *testing.T, id string, blockType string, der []byte) jwt.Key {
+	key, err := jwt.ParseKey(id, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}))
+	require.NoError(t, err)
+
+	return key
+}
+
+func newEd25519Key(t *testing.T, id string) jwt.Key {
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	require.NoError(t, err)
+
+	return parseKey(t, id, "PRIVATE KEY", der)
+}
+
+func newRSAKey(t *testing.T, id string) (jwt.Key, []byte) {
+	private, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	public, err := x509.MarshalPKIXPublicKey(&private.PublicKey)
+	require.NoError(t, err)
+
+	return parseKey(t, id, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(private)), public
+}
+
+// TestTokenClaims
+func TestTokenClaims(t *testing.T) {
+	key, _ := newRSAKey(t, "rsa")
+	keys, err := jwt.NewKeySet(issuer, audience, key)
+	require.NoError(t, err)
+
+	token, err := jwt.Create(keys, 900, 1, constants.UserRoleManager, "session")
+	require.NoError(t, err)
+
+	claims, err := jwt.Parse(keys, token)
+	require.NoError(t, err)
+	require.Equal(t, "1", claims.Subject)
+	require.Equal(t, issuer, claims.Issuer)
+	require.Equal(t, goJwt.ClaimStrings{audience}, claims.Audience)
+	require.Equal(t, constants.UserRoleManager, claims.Role)
+	require.Equal(t, "session", claims.SessionId)
+	require.NotEmpty(t, claims.ID)
+	require.Equal(t, int64(900), claims.ExpiresAt.Unix()-claims.IssuedAt.Unix())
+
+	parsed, _, err := goJwt.NewParser().ParseUnverified(token, &goJwt.MapClaims{})
+	require.NoError(t, err)
+	require.Equal(t, "RS256", parsed.Header["alg"])
+	require.Equal(t, "rsa", parsed.Header["kid"])
+
+	// Tokens issued for another audience, or expired, are rejected.
+	other, err := jwt.NewKeySet(issuer, "other", key)
+	require.NoError(t, err)
+	_, err = jwt.Parse(other, token)
+	require.Error(t, err)
+
+	expired, err := jwt.Create(keys, -1, 1, constants.UserRoleManager, "session")
+	require.NoError(t, err)
+	_, err = jwt.Parse(keys, expired)
+	require.ErrorIs(t, err, goJwt.ErrTokenExpired)
+}
+
+// TestKeyRotation
+func TestKeyRotation(t *testing.T) {
+	previous, previousPublic := newRSAKey(t, "2026-01")
+	previousKeys, err := jwt.NewKeySet(issuer, audience, previous)
+	require.NoError(t, err)
+	previousToken, err := jwt.Create(previousKeys, 900, 1, constants.UserRoleViewer, "session")
+	require.NoError(t, err)
+
+	// Once rotated, the previous key is only kept to verify the tokens it signed.
+	current := newEd25519Key(t, "2026-10")
+	keys, err := jwt.NewKeySet(issuer, audience, current, parseKey(t, "2026-01", "PUBLIC KEY", previousPublic))
+	require.NoError(t, err)
+
+	_, err = jwt.Parse(keys, previousToken)
+	require.NoError(t, err)
+
+	token, err := jwt.Create(keys, 900, 1, constants.UserRoleViewer, "session")
+	require.NoError(t, err)
+	_, err = jwt.Parse(keys, token)
+	require.NoError(t, err)
+	_, err = jwt.Parse(previousKeys, token)
+	require.Error(t, err)
+
+	// A public key cannot sign.
+	_, err = jwt.NewKeySet(issuer, audience, parseKey(t, "2026-01", "PUBLIC KEY", previousPublic))
+	require.Error(t, err)
+}
+
+// TestAlgorithmConfusionRejected
+func TestAlgorithmConfusionRejected(t *testing.T) {
+	key, public := newRSAKey(t, "rsa")
+	keys, err := jwt.NewKeySet(issuer, audience, key)
+	require.NoError(t, err)
+
+	// An HS256 token signed with the public key, which anyone can get from the JWKS.
+	forged := goJwt.NewWithClaims(goJwt.SigningMethodHS256, goJwt.MapClaims{
+		"sub": "1",
+		"iss": issuer,
+		"aud": audience,
+		"exp": 4102444800,
+		"sid": "session",
+	})
+	forged.Header["kid"] = "rsa"
+	token, err := forged.SignedString(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: public}))
+	require.NoError(t, err)
+
+	_, err = jwt.Parse(keys, token)
+	require.Error(t, err)
+}
+
+// TestLoadKeySet
+func TestLoadKeySet(t *testing.T) {
+	dir := t.TempDir()
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "2026-10.pem"), pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600))
+
+	t.Setenv("JWT_ISSUER", issuer)
+	t.Setenv("JWT_AUDIENCE", audience)
+	t.Setenv("JWT_KEYS_DIR", dir)
+	t.Setenv("JWT_SIGNING_KEY_ID", "2026-10")
+	keys, err := jwt.LoadKeySet()
+	require.NoError(t, err)
+	require.Equal(t, "2026-10", keys.JWKS()["keys"][0].Kid)
+
+	t.Setenv("JWT_SIGNING_KEY_ID", "2026-11")
+	_, err = jwt.LoadKeySet()
+	require.Error(t, err)
+
+	// Without keys, tokens are signed with the secret.
+	t.Setenv("JWT_KEYS_DIR", "")
+	t.Setenv("JWT_SECRET", "jwt_secret_key")
+	keys, err = jwt.LoadKeySet()
+	require.NoError(t, err)
+	require.Empty(t, keys.JWKS()["keys"])
+}
+
+// TestJWKS
+func TestJWKS(t *testing.T) {
+	rsaKey, _ := newRSAKey(t, "rsa")
+	keys, err := jwt.NewKeySet(issuer, audience, newEd25519Key(t, "ed25519"), rsaKey, jwt.NewHMACKey("hmac", []byte("secret")))
+	require.NoError(t, err)
+
+	r := mux.NewRouter()
+	handler.NewKeyHandler(keys).RegisterRoutes(r)
+	req, err := http.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+	require.NoError(t, err)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var jwks map[string][]jwt.JWK
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &jwks))
+	require.Len(t, jwks["keys"], 2)
+	require.Equal(t, "ed25519", jwks["keys"][0].Kid)
+	require.Equal(t, "OKP", jwks["keys"][0].Kty)
+	require.Equal(t, "EdDSA", jwks["keys"][0].Alg)
+	require.NotEmpty(t, jwks["keys"][0].X)
+	require.Equal(t, "rsa", jwks["keys"][1].Kid)
+	require.Equal(t, "RSA", jwks["keys"][1].Kty)
+	require.Equal(t, "RS256", jwks["keys"][1].Alg)
+	require.Equal(t, "AQAB", jwks["keys"][1].E)
+}
@@ -0,0 +1,15 @@
+package mailer
+
+type Email struct {
+	To      []string
+	Subject string
+	HTML    string
+	// Text is the plain text alternative shown by clients that do not render HTML.
+	Text string
+}
+
+// Mailer sends transactional emails. Resend sends them for real, Memory keeps them
+// in memory for tests and local development.
+type Mailer interface {
+	Send(email Email) error
+}
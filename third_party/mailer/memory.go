@@ -0,0 +1,29 @@
+package mailer
+
+import "sync"
+
+// Memory is a Mailer keeping every email it is asked to send, see Memory.Sent.
+type Memory struct {
+	mutex sync.Mutex
+	sent  []Email
+}
+
+func NewMemory() *Memory {
+	return &Memory{}
+}
+
+func (m *Memory) Send(email Email) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.sent = append(m.sent, email)
+	return nil
+}
+
+// Sent returns the emails sent so far, oldest first.
+func (m *Memory) Sent() []Email {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return append([]Email{}, m.sent...)
+}
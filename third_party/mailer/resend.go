@@ -0,0 +1,29 @@
+package mailer
+
+import (
+	resendGo "github.com/resend/resend-go/v2"
+)
+
+type Resend struct {
+	client    *resendGo.Client
+	fromEmail string
+}
+
+func NewResend(apiKey string, fromEmail string) *Resend {
+	return &Resend{
+		client:    resendGo.NewClient(apiKey),
+		fromEmail: fromEmail,
+	}
+}
+
+func (r *Resend) Send(email Email) error {
+	_, err := r.client.Emails.Send(&resendGo.SendEmailRequest{
+		From:    r.fromEmail,
+		To:      email.To,
+		Subject: email.Subject,
+		Html:    email.HTML,
+		Text:    email.Text,
+	})
+
+	return err
+}
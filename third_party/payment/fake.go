@@ -0,0 +1,153 @@
+package payment
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+type fakeCheckout struct {
+	request   CheckoutRequest
+	status    string
+	refunded  int64
+	refundIds []string
+}
+
+// Fake is an in-memory Provider used in tests and local development. Webhooks are
+// plain JSON events signed with the configured secret, see Fake.Webhook.
+type Fake struct {
+	mutex     sync.Mutex
+	secret    string
+	checkouts map[string]*fakeCheckout
+	refunds   map[string]Refund
+	events    int
+}
+
+func NewFake(secret string) *Fake {
+	return &Fake{
+		secret:    secret,
+		checkouts: make(map[string]*fakeCheckout),
+		refunds:   make(map[string]Refund),
+	}
+}
+
+func (f *Fake) CreateCheckout(request CheckoutRequest) (Checkout, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	id := fmt.Sprintf("cs_fake_%d", len(f.checkouts)+1)
+	f.checkouts[id] = &fakeCheckout{
+		request: request,
+		status:  StatusPending,
+	}
+
+	return Checkout{
+		Id:  id,
+		URL: fmt.Sprintf("https://payments.fake/checkout/%s", id),
+	}, nil
+}
+
+func (f *Fake) VerifyWebhook(payload []byte, signature string) (Event, error) {
+	if signature != f.secret {
+		return Event{}, fmt.Errorf("invalid webhook signature")
+	}
+
+	var event Event
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return Event{}, err
+	}
+
+	return event, nil
+}
+
+func (f *Fake) Refund(reference string, amount int64, key string) (Refund, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if refund, ok := f.refunds[key]; ok {
+		return refund, nil
+	}
+
+	checkout, ok := f.checkouts[reference]
+	if !ok || checkout.status != StatusPaid {
+		return Refund{}, fmt.Errorf("checkout %s was not paid", reference)
+	}
+	if checkout.refunded+amount > checkout.request.Amount() {
+		return Refund{}, fmt.Errorf("refund exceeds the amount paid for checkout %s", reference)
+	}
+	checkout.refunded += amount
+
+	refund := Refund{
+		Id:     fmt.Sprintf("re_fake_%d", len(f.refunds)+1),
+		Amount: amount,
+	}
+	f.refunds[key] = refund
+	checkout.refundIds = append(checkout.refundIds, refund.Id)
+	return refund, nil
+}
+
+func (f *Fake) PaymentStatus(reference string) (string, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	checkout, ok := f.checkouts[reference]
+	if !ok {
+		return "", fmt.Errorf("unknown checkout %s", reference)
+	}
+	if checkout.refunded > 0 {
+		return StatusRefunded, nil
+	}
+
+	return checkout.status, nil
+}
+
+// Checkout returns the request a checkout was created with.
+func (f *Fake) Checkout(reference string) (CheckoutRequest, bool) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	checkout, ok := f.checkouts[reference]
+	if !ok {
+		return CheckoutRequest{}, false
+	}
+
+	return checkout.request, true
+}
+
+// Webhook simulates the provider outcome of a checkout and returns the payload
+// and signature it would post to the webhook endpoint.
+func (f *Fake) Webhook(reference string, eventType string) ([]byte, string, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	checkout, ok := f.checkouts[reference]
+	if !ok {
+		return nil, "", fmt.Errorf("unknown checkout %s", reference)
+	}
+
+	event := Event{
+		Type:      eventType,
+		OrderId:   checkout.request.OrderId,
+		Reference: reference,
+		Amount:    checkout.request.Amount(),
+	}
+	switch eventType {
+	case EventCheckoutCompleted:
+		checkout.status = StatusPaid
+	case EventCheckoutExpired, EventPaymentFailed:
+		checkout.status = StatusExpired
+	case EventRefunded:
+		event.Amount = checkout.refunded
+		event.RefundIds = checkout.refundIds
+	}
+
+	f.events++
+	event.Id = fmt.Sprintf("evt_fake_%d", f.events)
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return payload, f.secret, nil
+}
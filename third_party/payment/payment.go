@@ -0,0 +1,73 @@
+package payment
+
+import "time"
+
+const (
+	EventCheckoutCompleted = "checkout.completed"
+	EventCheckoutExpired   = "checkout.expired"
+	EventPaymentFailed     = "payment.failed"
+	EventRefunded          = "refunded"
+)
+
+const (
+	StatusPending  = "PENDING"
+	StatusPaid     = "PAID"
+	StatusExpired  = "EXPIRED"
+	StatusRefunded = "REFUNDED"
+)
+
+type LineItem struct {
+	Name        string
+	Description string
+	UnitAmount  int64
+	Quantity    int64
+}
+
+type CheckoutRequest struct {
+	OrderId   int
+	Items     []LineItem
+	ExpiresAt time.Time
+}
+
+// Amount is the total charged for the checkout.
+func (r CheckoutRequest) Amount() int64 {
+	var amount int64
+	for _, item := range r.Items {
+		amount += item.UnitAmount * item.Quantity
+	}
+
+	return amount
+}
+
+type Checkout struct {
+	Id  string
+	URL string
+}
+
+type Refund struct {
+	Id     string
+	Amount int64
+}
+
+// Event is a verified webhook notification translated from the provider's own format.
+// Type is empty for notifications the API does not handle.
+type Event struct {
+	Id      string
+	Type    string
+	OrderId int
+	// Reference is the checkout id stored as the order payment reference.
+	Reference string
+	Amount    int64
+	// RefundIds are the ids of the refunds settled, for EventRefunded.
+	RefundIds []string
+}
+
+// Provider is implemented by every payment gateway the API can take payments with.
+type Provider interface {
+	CreateCheckout(request CheckoutRequest) (Checkout, error)
+	VerifyWebhook(payload []byte, signature string) (Event, error)
+	// Refund gives amount back from the payment made through the checkout reference.
+	// Calls with the same key refund only once.
+	Refund(reference string, amount int64, key string) (Refund, error)
+	PaymentStatus(reference string) (string, error)
+}
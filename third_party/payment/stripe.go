@@ -0,0 +1,214 @@
+package payment
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/stripe/stripe-go/v79"
+	"github.com/stripe/stripe-go/v79/client"
+	"github.com/stripe/stripe-go/v79/webhook"
+)
+
+// Stripe refuses checkout sessions expiring in less than 30 minutes.
+const stripeMinCheckoutDuration = 30 * time.Minute
+
+type StripeConfig struct {
+	APIKey        string
+	WebhookSecret string
+	Currency      string
+	SuccessURL    string
+	CancelURL     string
+}
+
+type Stripe struct {
+	Client *client.API
+	Config StripeConfig
+}
+
+func NewStripe(config StripeConfig) *Stripe {
+	return &Stripe{
+		Client: client.New(config.APIKey, nil),
+		Config: config,
+	}
+}
+
+func (s *Stripe) CreateCheckout(request CheckoutRequest) (Checkout, error) {
+	orderId := strconv.Itoa(request.OrderId)
+
+	lineItems := make([]*stripe.CheckoutSessionLineItemParams, 0, len(request.Items))
+	for _, item := range request.Items {
+		lineItems = append(lineItems, &stripe.CheckoutSessionLineItemParams{
+			PriceData: &stripe.CheckoutSessionLineItemPriceDataParams{
+				Currency: stripe.String(s.Config.Currency),
+				ProductData: &stripe.CheckoutSessionLineItemPriceDataProductDataParams{
+					Name:        stripe.String(item.Name),
+					Description: stripe.String(item.Description),
+				},
+				UnitAmount: stripe.Int64(item.UnitAmount),
+			},
+			Quantity: stripe.Int64(item.Quantity),
+		})
+	}
+
+	expiresAt := request.ExpiresAt
+	if min := time.Now().Add(stripeMinCheckoutDuration); expiresAt.Before(min) {
+		expiresAt = min
+	}
+
+	params := &stripe.CheckoutSessionParams{
+		Mode:              stripe.String(string(stripe.CheckoutSessionModePayment)),
+		LineItems:         lineItems,
+		SuccessURL:        stripe.String(s.Config.SuccessURL),
+		CancelURL:         stripe.String(s.Config.CancelURL),
+		ClientReferenceID: stripe.String(orderId),
+		ExpiresAt:         stripe.Int64(expiresAt.Unix()),
+		Metadata: map[string]string{
+			"order_id": orderId,
+		},
+		PaymentIntentData: &stripe.CheckoutSessionPaymentIntentDataParams{
+			Metadata: map[string]string{
+				"order_id": orderId,
+			},
+		},
+	}
+
+	session, err := s.Client.CheckoutSessions.New(params)
+	if err != nil {
+		return Checkout{}, err
+	}
+
+	return Checkout{
+		Id:  session.ID,
+		URL: session.URL,
+	}, nil
+}
+
+func (s *Stripe) VerifyWebhook(payload []byte, signature string) (Event, error) {
+	stripeEvent, err := webhook.ConstructEvent(payload, signature, s.Config.WebhookSecret)
+	if err != nil {
+		return Event{}, err
+	}
+
+	event := Event{
+		Id: stripeEvent.ID,
+	}
+
+	switch stripeEvent.Type {
+	case stripe.EventTypeCheckoutSessionCompleted, stripe.EventTypeCheckoutSessionExpired:
+		var session stripe.CheckoutSession
+		if err := json.Unmarshal(stripeEvent.Data.Raw, &session); err != nil {
+			return event, err
+		}
+
+		event.Type = EventCheckoutCompleted
+		if stripeEvent.Type == stripe.EventTypeCheckoutSessionExpired {
+			event.Type = EventCheckoutExpired
+		}
+		event.Reference = session.ID
+		event.Amount = session.AmountTotal
+		event.OrderId, err = orderIdFromMetadata(session.Metadata)
+
+	case stripe.EventTypePaymentIntentPaymentFailed:
+		var intent stripe.PaymentIntent
+		if err := json.Unmarshal(stripeEvent.Data.Raw, &intent); err != nil {
+			return event, err
+		}
+
+		event.Type = EventPaymentFailed
+		event.Amount = intent.Amount
+		event.OrderId, err = orderIdFromMetadata(intent.Metadata)
+
+	case stripe.EventTypeChargeRefunded:
+		var charge stripe.Charge
+		if err := json.Unmarshal(stripeEvent.Data.Raw, &charge); err != nil {
+			return event, err
+		}
+		if charge.PaymentIntent == nil {
+			return event, fmt.Errorf("charge %s has no payment intent", charge.ID)
+		}
+
+		// charges do not inherit the payment intent metadata holding our order id
+		intent, err := s.Client.PaymentIntents.Get(charge.PaymentIntent.ID, nil)
+		if err != nil {
+			return event, err
+		}
+
+		event.Type = EventRefunded
+		event.Amount = charge.AmountRefunded
+		event.OrderId, err = orderIdFromMetadata(intent.Metadata)
+		if err != nil {
+			return event, err
+		}
+
+		// the refunds of a charge are not part of the event payload anymore
+		refunds := s.Client.Refunds.List(&stripe.RefundListParams{Charge: stripe.String(charge.ID)})
+		for refunds.Next() {
+			if refund := refunds.Refund(); refund.Status == stripe.RefundStatusSucceeded {
+				event.RefundIds = append(event.RefundIds, refund.ID)
+			}
+		}
+		err = refunds.Err()
+	}
+
+	return event, err
+}
+
+// Refund refunds amount from the payment made through the checkout session reference.
+// key is sent as the idempotency key of the request.
+func (s *Stripe) Refund(reference string, amount int64, key string) (Refund, error) {
+	session, err := s.Client.CheckoutSessions.Get(reference, nil)
+	if err != nil {
+		return Refund{}, err
+	}
+	if session.PaymentIntent == nil {
+		return Refund{}, fmt.Errorf("checkout session %s has no payment", reference)
+	}
+
+	params := &stripe.RefundParams{
+		PaymentIntent: stripe.String(session.PaymentIntent.ID),
+		Amount:        stripe.Int64(amount),
+		Metadata:      session.Metadata,
+	}
+	params.SetIdempotencyKey(key)
+	refund, err := s.Client.Refunds.New(params)
+	if err != nil {
+		return Refund{}, err
+	}
+
+	return Refund{
+		Id:     refund.ID,
+		Amount: refund.Amount,
+	}, nil
+}
+
+func (s *Stripe) PaymentStatus(reference string) (string, error) {
+	params := &stripe.CheckoutSessionParams{}
+	params.AddExpand("payment_intent.latest_charge")
+
+	session, err := s.Client.CheckoutSessions.Get(reference, params)
+	if err != nil {
+		return "", err
+	}
+
+	switch {
+	case session.PaymentIntent != nil && session.PaymentIntent.LatestCharge != nil && session.PaymentIntent.LatestCharge.Refunded:
+		return StatusRefunded, nil
+	case session.PaymentStatus == stripe.CheckoutSessionPaymentStatusPaid:
+		return StatusPaid, nil
+	case session.Status == stripe.CheckoutSessionStatusExpired:
+		return StatusExpired, nil
+	default:
+		return StatusPending, nil
+	}
+}
+
+func orderIdFromMetadata(metadata map[string]string) (int, error) {
+	orderId, err := strconv.Atoi(metadata["order_id"])
+	if err != nil {
+		return 0, fmt.Errorf("invalid order id in metadata: %w", err)
+	}
+
+	return orderId, nil
+}